@@ -2,15 +2,17 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
 	"sync"
 
 	"github.com/bosley/beau"
 	"github.com/bosley/beau/mage"
 	"github.com/bosley/beau/toolkit"
+	"github.com/bosley/beau/tracing"
 )
 
 type Agent interface {
@@ -18,6 +20,20 @@ type Agent interface {
 	InterruptCurrentRequest() error
 	ResetConversation() error
 	SendMessage(message string) error
+
+	// Checkpoint marks the current point in the conversation for Rollback
+	Checkpoint() int
+
+	// Rollback returns the conversation to a checkpoint, keeping what came after
+	// it in the history tree
+	Rollback(checkpoint int) error
+
+	// EditMessage replaces the user message at index and runs the turn again
+	// from it, the original branch stays in the history tree
+	EditMessage(index int, content string) error
+
+	// History returns a copy of every branch the conversation has taken
+	History() *beau.HistoryNode
 }
 
 type Observer interface {
@@ -31,9 +47,37 @@ type Observer interface {
 type UsageStats struct {
 	MessageSizeBytes int    // Total size of the message data sent to LLM
 	Model            string // Model used for this request
-	TokensUsed       int    // Estimated tokens used (if available from API)
-	PromptTokens     int    // Input tokens
-	CompletionTokens int    // Output tokens
+	TokensUsed       int    // Total tokens for the turn (estimated if the API reported none)
+	PromptTokens     int    // Input tokens, summed across every request in the turn
+	CompletionTokens int    // Output tokens, summed across every request in the turn
+	Estimated        bool   // True when the provider did not report usage
+
+	Cost        float64 // USD spent by the turn, including the mages it tasked
+	MageCost    float64 // USD of Cost spent by mages
+	SessionCost float64 // USD spent since the agent was created
+
+	Redactions int // distinct secrets redacted since the agent was created
+}
+
+// redactingObserver removes secrets from everything the observer is shown. A
+// secret split across stream chunks can slip through OnChunk, OnComplete always
+// sees the whole message.
+type redactingObserver struct {
+	Observer
+	redactor *beau.Redactor
+}
+
+func (o *redactingObserver) OnChunk(chunk beau.StreamChunk) error {
+	chunk.Content = o.redactor.Redact(chunk.Content)
+	return o.Observer.OnChunk(chunk)
+}
+
+func (o *redactingObserver) OnError(err error) error {
+	return o.Observer.OnError(o.redactor.RedactError(err))
+}
+
+func (o *redactingObserver) OnComplete(message beau.Message) error {
+	return o.Observer.OnComplete(o.redactor.RedactMessage(message))
 }
 
 type Config struct {
@@ -43,11 +87,21 @@ type Config struct {
 	BaseURL       string
 	HTTPClient    *http.Client
 	RetryConfig   beau.RetryConfig
+	Provider      beau.Provider       // if nil, detected from BaseURL
+	RateLimiter   *beau.RateLimiter   // shared by the agent and every mage it summons
+	Fallback      beau.FallbackPolicy // tried in turn by the agent and its mages when the provider fails
+	Pricing       *beau.Pricing       // prices usage for cost reporting, beau.DefaultPricing if nil
+	Budget        *beau.Budget        // if set, requests from the agent and its mages fail once it is spent
+	Middleware    []beau.Middleware   // wraps every request of the agent and its mages
+	Tracer        *tracing.Tracer     // if set, turns, requests, mage executions and tool calls are traced
+	Redactor      *beau.Redactor      // if set, secrets are removed from logs, tool results, requests and observer output
 	Model         string
 	ImageModel    string // if empty will use the same as the model
 	ProjectBounds []beau.ProjectBounds
 	Temperature   float64
 	MaxTokens     int
+	SessionPath   string // if set, the conversation is logged here and resumed from it on start
+	ContextBudget int    // estimated tokens of history sent per request, 0 sends everything
 
 	PromptRefinements []string
 }
@@ -66,18 +120,27 @@ type agent struct {
 	ctx           context.Context
 	cancel        context.CancelFunc
 	running       bool
+
+	// Token usage of the current turn, summed across tool call round trips
+	turnUsage beau.Usage
+
+	// Spend of the session, the agent's own client and its mages roll up into it
+	meter           *beau.CostMeter
+	turnStart       beau.Spend
+	turnClientStart beau.Spend
+
+	// Span of the current turn, the parent of everything the turn does
+	turnSpan *tracing.Span
 }
 
 func (a *agent) calculateMessageSize() int {
 	messages := a.conv.GetMessages()
 	totalSize := 0
 	for _, msg := range messages {
-		switch content := msg.Content.(type) {
-		case string:
-			totalSize += len(content)
-		case []interface{}:
-			if jsonBytes, err := json.Marshal(content); err == nil {
-				totalSize += len(jsonBytes)
+		for _, item := range msg.Content {
+			totalSize += len(item.Text)
+			if item.ImageURL != nil {
+				totalSize += len(item.ImageURL.URL)
 			}
 		}
 
@@ -98,6 +161,67 @@ func (a *agent) calculateMessageSize() int {
 	return totalSize
 }
 
+// recordUsage adds the usage of the most recent request to the current turn
+func (a *agent) recordUsage() {
+	response := a.conv.LastResponse()
+	if response == nil {
+		return
+	}
+	a.mu.Lock()
+	a.turnUsage = a.turnUsage.Add(response.Usage)
+	a.mu.Unlock()
+}
+
+// reportUsage sends the usage of the completed turn to the observer
+func (a *agent) reportUsage() {
+	if a.config.Observer == nil {
+		return
+	}
+
+	a.mu.Lock()
+	turn := a.turnUsage
+	turnSpend := a.meter.Spend().Sub(a.turnStart)
+	clientSpend := a.client.Spend().Sub(a.turnClientStart)
+	a.mu.Unlock()
+
+	usage := UsageStats{
+		MessageSizeBytes: a.calculateMessageSize(),
+		Model:            a.config.Model,
+		TokensUsed:       turn.TotalTokens,
+		PromptTokens:     turn.PromptTokens,
+		CompletionTokens: turn.CompletionTokens,
+		Cost:             turnSpend.Cost,
+		MageCost:         turnSpend.Cost - clientSpend.Cost,
+		SessionCost:      a.meter.Spend().Cost,
+		Redactions:       a.config.Redactor.Total(),
+	}
+	if usage.TokensUsed == 0 {
+		usage.TokensUsed = usage.PromptTokens + usage.CompletionTokens
+	}
+	if usage.TokensUsed == 0 {
+		usage.TokensUsed = a.estimateTokens(usage.MessageSizeBytes)
+		usage.Estimated = true
+	}
+	a.config.Observer.OnUsage(usage)
+}
+
+// endTurn ends the span of the current turn with what the turn used
+func (a *agent) endTurn(err error) {
+	a.mu.Lock()
+	span := a.turnSpan
+	a.turnSpan = nil
+	turn := a.turnUsage
+	turnSpend := a.meter.Spend().Sub(a.turnStart)
+	a.mu.Unlock()
+
+	span.SetAttribute("prompt_tokens", turn.PromptTokens)
+	span.SetAttribute("completion_tokens", turn.CompletionTokens)
+	span.SetAttribute("requests", turnSpend.Requests)
+	span.SetAttribute("cost_usd", turnSpend.Cost)
+	span.RecordError(err)
+	span.End()
+}
+
 // estimateTokens provides a rough estimate of tokens based on character count
 // This is a simple heuristic: ~4 characters per token on average
 func (a *agent) estimateTokens(size int) int {
@@ -110,6 +234,12 @@ func NewAgent(config Config) (Agent, error) {
 			Level: slog.LevelInfo,
 		}))
 	}
+	if config.Redactor != nil {
+		config.Logger = beau.RedactLogger(config.Logger, config.Redactor)
+		if config.Observer != nil {
+			config.Observer = &redactingObserver{Observer: config.Observer, redactor: config.Redactor}
+		}
+	}
 
 	if config.ImageModel == "" {
 		config.ImageModel = config.Model
@@ -132,6 +262,15 @@ func NewAgent(config Config) (Agent, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create beau client: %w", err)
 	}
+	meter := beau.NewCostMeter(nil)
+	client.WithProvider(config.Provider).
+		WithRateLimiter(config.RateLimiter).
+		WithFallback(config.Fallback).
+		WithPricing(config.Pricing).
+		WithBudget(config.Budget).
+		WithCostMeter(beau.NewCostMeter(meter)).
+		WithRedactor(config.Redactor).
+		Use(config.Middleware...)
 
 	portal := mage.NewPortal(mage.PortalConfig{
 		Logger:        config.Logger.WithGroup("mage_portal"),
@@ -139,10 +278,19 @@ func NewAgent(config Config) (Agent, error) {
 		BaseURL:       config.BaseURL,
 		HTTPClient:    config.HTTPClient,
 		RetryConfig:   config.RetryConfig,
+		Provider:      config.Provider,
+		RateLimiter:   config.RateLimiter,
+		Fallback:      config.Fallback,
+		Pricing:       config.Pricing,
+		Meter:         meter,
+		Budget:        config.Budget,
+		Middleware:    config.Middleware,
+		Redactor:      config.Redactor,
 		PrimaryModel:  config.Model,
 		ImageModel:    config.ImageModel,
 		MiniModel:     config.Model, // Use same model for mini tasks
 		ProjectBounds: config.ProjectBounds,
+		ContextBudget: config.ContextBudget,
 		MaxTokens:     8192,
 		Temperature:   0.7,
 	})
@@ -152,16 +300,19 @@ func NewAgent(config Config) (Agent, error) {
 		logger: config.Logger.WithGroup("agent"),
 		client: client,
 		portal: portal,
+		meter:  meter,
 	}
 
 	a.toolkit = mage.GetUnifiedMageKit(
 		portal,
 		config.Logger.WithGroup("mage_kit"),
 		a.handleToolCallback,
-	)
+	).WithRedactor(config.Redactor)
 
-	// Initialize conversation
-	a.resetConversation()
+	// Initialize conversation, picking up a previous session when there is one
+	if err := a.initConversation(); err != nil {
+		return nil, err
+	}
 
 	return a, nil
 }
@@ -175,6 +326,9 @@ func (a *agent) Start(ctx context.Context) error {
 	}
 
 	a.ctx, a.cancel = context.WithCancel(ctx)
+	if a.config.Tracer != nil {
+		a.ctx = tracing.ContextWithTracer(a.ctx, a.config.Tracer)
+	}
 	a.running = true
 
 	// No longer need to start stream handler since we create streams per request
@@ -203,6 +357,29 @@ func (a *agent) ResetConversation() error {
 	return a.resetConversation()
 }
 
+func (a *agent) initConversation() error {
+	if a.config.SessionPath != "" {
+		if _, err := os.Stat(a.config.SessionPath); err == nil {
+			conv, err := a.client.ResumeConversation(a.config.SessionPath, a.conversationOptions()...)
+			if err != nil {
+				return fmt.Errorf("failed to resume session: %w", err)
+			}
+			a.conv = conv.WithContextPolicy(a.portal.NewContextPolicy(a.client))
+			a.logger.Info("Resumed session", "path", a.config.SessionPath, "messages", len(conv.GetMessages()))
+			return nil
+		}
+	}
+	return a.resetConversation()
+}
+
+// conversationOptions are the request options of every agent conversation
+func (a *agent) conversationOptions() []beau.RequestOption {
+	return []beau.RequestOption{
+		beau.WithTools(a.toolkit.GetTools()),
+		beau.WithToolChoice("auto"),
+	}
+}
+
 func (a *agent) resetConversation() error {
 	// Cancel any active request
 	if a.activeRequest != nil {
@@ -211,10 +388,8 @@ func (a *agent) resetConversation() error {
 	}
 
 	// Create new conversation with tools
-	a.conv = a.client.NewConversation(a.config.Model,
-		beau.WithTools(a.toolkit.GetTools()),
-		beau.WithToolChoice("auto"),
-	)
+	a.conv = a.client.NewConversation(a.config.Model, a.conversationOptions()...).
+		WithContextPolicy(a.portal.NewContextPolicy(a.client))
 
 	// Debug log the tools being registered
 	tools := a.toolkit.GetTools()
@@ -294,11 +469,55 @@ Remember: You cannot perform these operations without calling the tool. If a use
 
 	a.conv.AddSystemMessage(proompt)
 
+	// a reset starts the session log over
+	if a.config.SessionPath != "" {
+		if err := a.conv.PersistTo(a.config.SessionPath); err != nil {
+			return fmt.Errorf("failed to persist session: %w", err)
+		}
+	}
+
 	a.logger.Info("Conversation reset")
 	return nil
 }
 
 func (a *agent) SendMessage(message string) error {
+	return a.beginTurn(message, func() error {
+		a.conv.AddUserMessage(message)
+		return nil
+	})
+}
+
+func (a *agent) EditMessage(index int, content string) error {
+	return a.beginTurn(content, func() error {
+		return a.conv.EditMessage(index, content)
+	})
+}
+
+func (a *agent) Checkpoint() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.conv.Checkpoint()
+}
+
+func (a *agent) Rollback(checkpoint int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.activeRequest != nil {
+		return fmt.Errorf("request already in progress")
+	}
+	return a.conv.Rollback(checkpoint)
+}
+
+func (a *agent) History() *beau.HistoryNode {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.conv.History()
+}
+
+// beginTurn runs prepare to set up the conversation and then sends it, handling
+// the reply in the background
+func (a *agent) beginTurn(message string, prepare func() error) error {
 	a.mu.Lock()
 
 	if !a.running {
@@ -311,12 +530,23 @@ func (a *agent) SendMessage(message string) error {
 		return fmt.Errorf("request already in progress")
 	}
 
-	reqCtx, reqCancel := context.WithCancel(a.ctx)
+	if err := prepare(); err != nil {
+		a.mu.Unlock()
+		return err
+	}
+
+	turnCtx, span := tracing.Start(a.ctx, "agent.turn")
+	span.SetAttribute("model", a.config.Model)
+	span.SetAttribute("message_bytes", len(message))
+	a.turnSpan = span
+
+	reqCtx, reqCancel := context.WithCancel(turnCtx)
 	a.activeRequest = reqCancel
+	a.turnUsage = beau.Usage{}
+	a.turnStart = a.meter.Spend()
+	a.turnClientStart = a.client.Spend()
 	a.mu.Unlock()
 
-	a.conv.AddUserMessage(message)
-
 	go func() {
 		defer func() {
 			a.mu.Lock()
@@ -339,35 +569,39 @@ func (a *agent) SendMessage(message string) error {
 		if err != nil {
 			if reqCtx.Err() != nil {
 				a.logger.Info("Request cancelled")
+				a.endTurn(reqCtx.Err())
 				return
 			}
 			a.logger.Error("Failed to send message", "error", err)
 			if a.config.Observer != nil {
 				a.config.Observer.OnError(err)
 			}
+			if errors.Is(err, beau.ErrBudgetExceeded) {
+				// the run stops here, the observer still hears what it cost
+				a.reportUsage()
+			}
+			a.endTurn(err)
 			return
 		}
+		a.recordUsage()
 
 		if len(response.ToolCalls) > 0 {
 			a.logger.Info("Handling tool calls", "count", len(response.ToolCalls))
-			if err := a.toolkit.HandleResponseCalls(response); err != nil {
+			if err := a.toolkit.HandleResponseCallsContext(reqCtx, response); err != nil {
 				a.logger.Error("Failed to handle tool calls", "error", err)
 				if a.config.Observer != nil {
 					a.config.Observer.OnError(err)
 				}
+				a.endTurn(err)
 			}
 		} else {
 			if a.config.Observer != nil {
 				a.config.Observer.OnComplete(*response)
 
 				// Send usage stats
-				usage := UsageStats{
-					MessageSizeBytes: a.calculateMessageSize(),
-					Model:            a.config.Model,
-					TokensUsed:       a.estimateTokens(a.calculateMessageSize()),
-				}
-				a.config.Observer.OnUsage(usage)
+				a.reportUsage()
 			}
+			a.endTurn(nil)
 		}
 	}()
 
@@ -389,18 +623,16 @@ func (a *agent) handleStreamForRequest(streamChan chan beau.StreamChunk) {
 func (a *agent) handleToolCallback(isError bool, id string, result interface{}) {
 	a.logger.Info("Tool callback", "id", id, "isError", isError)
 
-	var resultStr string
 	if isError {
-		resultStr = fmt.Sprintf("Error: %v", result)
+		a.conv.AddToolError(id, fmt.Sprintf("Error: %v", result))
 	} else {
-		resultStr = fmt.Sprintf("%v", result)
+		a.conv.AddToolResult(id, fmt.Sprintf("%v", result))
 	}
 
-	a.conv.AddToolResult(id, resultStr)
-
 	go func() {
-		reqCtx, reqCancel := context.WithCancel(a.ctx)
 		a.mu.Lock()
+		// continue the turn's trace, the tool call that got here has ended
+		reqCtx, reqCancel := context.WithCancel(tracing.ContextWithSpan(a.ctx, a.turnSpan))
 		a.activeRequest = reqCancel
 		a.mu.Unlock()
 
@@ -423,23 +655,31 @@ func (a *agent) handleToolCallback(isError bool, id string, result interface{})
 		if err != nil {
 			if reqCtx.Err() != nil {
 				a.logger.Info("Request cancelled during tool response")
+				a.endTurn(reqCtx.Err())
 				return
 			}
 			a.logger.Error("Failed to send tool response", "error", err)
 			if a.config.Observer != nil {
 				a.config.Observer.OnError(err)
 			}
+			if errors.Is(err, beau.ErrBudgetExceeded) {
+				// the run stops here, the observer still hears what it cost
+				a.reportUsage()
+			}
+			a.endTurn(err)
 			return
 		}
+		a.recordUsage()
 
 		// Check for more tool calls
 		if len(response.ToolCalls) > 0 {
 			a.logger.Info("More tool calls needed", "count", len(response.ToolCalls))
-			if err := a.toolkit.HandleResponseCalls(response); err != nil {
+			if err := a.toolkit.HandleResponseCallsContext(reqCtx, response); err != nil {
 				a.logger.Error("Failed to handle additional tool calls", "error", err)
 				if a.config.Observer != nil {
 					a.config.Observer.OnError(err)
 				}
+				a.endTurn(err)
 			}
 		} else {
 			// Final response complete
@@ -447,13 +687,9 @@ func (a *agent) handleToolCallback(isError bool, id string, result interface{})
 				a.config.Observer.OnComplete(*response)
 
 				// Send usage stats
-				usage := UsageStats{
-					MessageSizeBytes: a.calculateMessageSize(),
-					Model:            a.config.Model,
-					TokensUsed:       a.estimateTokens(a.calculateMessageSize()),
-				}
-				a.config.Observer.OnUsage(usage)
+				a.reportUsage()
 			}
+			a.endTurn(nil)
 		}
 	}()
 }
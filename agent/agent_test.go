@@ -0,0 +1,411 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bosley/beau"
+	"github.com/bosley/beau/beautest"
+	"github.com/bosley/beau/tracing"
+)
+
+// testObserver records everything the agent reports
+type testObserver struct {
+	mu       sync.Mutex
+	chunks   strings.Builder
+	errors   []error
+	complete []beau.Message
+	usage    []UsageStats
+	done     chan struct{}
+}
+
+func newTestObserver() *testObserver {
+	return &testObserver{done: make(chan struct{}, 10)}
+}
+
+func (o *testObserver) OnChunk(chunk beau.StreamChunk) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.chunks.WriteString(chunk.Content)
+	return nil
+}
+
+func (o *testObserver) OnError(err error) error {
+	o.mu.Lock()
+	o.errors = append(o.errors, err)
+	o.mu.Unlock()
+	o.done <- struct{}{}
+	return nil
+}
+
+func (o *testObserver) OnComplete(message beau.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.complete = append(o.complete, message)
+	return nil
+}
+
+func (o *testObserver) OnUsage(usage UsageStats) error {
+	o.mu.Lock()
+	o.usage = append(o.usage, usage)
+	o.mu.Unlock()
+	o.done <- struct{}{}
+	return nil
+}
+
+// Helper function to wait until the turn has ended or failed
+func (o *testObserver) wait(t *testing.T) {
+	select {
+	case <-o.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for the agent")
+	}
+}
+
+// Helper function to wait for streamed content, chunks may trail the completion
+func (o *testObserver) waitForChunks(t *testing.T, expected string) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		o.mu.Lock()
+		got := o.chunks.String()
+		o.mu.Unlock()
+		if got == expected {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Errorf("Expected streamed content %q, got %q", expected, o.chunks.String())
+}
+
+// Helper function to retry fn while the previous turn is still winding down,
+// observers hear of the reply just before the request is released
+func untilIdle(fn func() error) error {
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := fn()
+		if err == nil || !strings.Contains(err.Error(), "in progress") || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAgentToolCallbackChain(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	server := beautest.NewServer(
+		// the agent delegates to the filesystem mage
+		beautest.ToolCall("task_mage", map[string]string{
+			"mage_type": "filesystem",
+			"command":   "list " + dir,
+		}).WithUsage(20, 3),
+		// the mage runs its own tool loop against the same server
+		beautest.ToolCall("list_directory", map[string]string{"directory_path": dir}).WithUsage(100, 10),
+		beautest.Text("Found notes.txt").WithUsage(50, 5),
+		// the agent answers with the mage result
+		beautest.Chunks("The project ", "has notes.txt").WithUsage(10, 5),
+	)
+	defer server.Close()
+
+	observer := newTestObserver()
+	ag, err := NewAgent(Config{
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Observer: observer,
+		APIKey:   "test-key",
+		BaseURL:  server.URL,
+		Model:    "test-model",
+		// a dollar per prompt token and two per completion token
+		Pricing: beau.NewPricing(map[string]beau.ModelPrice{"test-model": {Input: 1_000_000, Output: 2_000_000}}),
+		ProjectBounds: []beau.ProjectBounds{
+			{Name: "test", Description: "Test project", ABSPath: dir},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	if err := ag.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+
+	if err := ag.SendMessage("what is in my project?"); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	observer.wait(t)
+
+	observer.mu.Lock()
+	errs, complete, usage := observer.errors, observer.complete, observer.usage
+	observer.mu.Unlock()
+
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if len(complete) != 1 {
+		t.Fatalf("Expected 1 completion, got %d", len(complete))
+	}
+	if content := complete[0].Text(); content != "The project has notes.txt" {
+		t.Errorf("Unexpected completion: %q", content)
+	}
+	observer.waitForChunks(t, "The project has notes.txt")
+
+	// usage covers both agent requests, the mage's requests are its own
+	if len(usage) != 1 || usage[0].PromptTokens != 30 || usage[0].CompletionTokens != 8 || usage[0].TokensUsed != 38 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+
+	// the cost does include the mage: 30*1 + 8*2 for the agent, 150*1 + 15*2 for the mage
+	if len(usage) == 1 && (usage[0].Cost != 226 || usage[0].MageCost != 180 || usage[0].SessionCost != 226) {
+		t.Errorf("Unexpected cost: %+v", usage[0])
+	}
+
+	server.AssertRequestCount(t, 4)
+	server.AssertMessage(t, 0, beau.RoleUser, "what is in my project?")
+	server.AssertMessage(t, 1, beau.RoleUser, "list "+dir)
+	server.AssertMessage(t, 2, beau.RoleTool, "notes.txt")
+	server.AssertMessage(t, 3, beau.RoleTool, "Found notes.txt")
+}
+
+func TestAgentTracesTurn(t *testing.T) {
+	dir := t.TempDir()
+	server := beautest.NewServer(
+		beautest.ToolCall("task_mage", map[string]string{
+			"mage_type": "filesystem",
+			"command":   "list " + dir,
+		}),
+		beautest.ToolCall("list_directory", map[string]string{"directory_path": dir}),
+		beautest.Text("Nothing there"),
+		beautest.Text("The project is empty"),
+	)
+	defer server.Close()
+
+	exporter := tracing.NewMemoryExporter()
+	observer := newTestObserver()
+	ag, err := NewAgent(Config{
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Observer: observer,
+		APIKey:   "test-key",
+		BaseURL:  server.URL,
+		Model:    "test-model",
+		Tracer:   tracing.NewTracer(exporter),
+		ProjectBounds: []beau.ProjectBounds{
+			{Name: "test", Description: "Test project", ABSPath: dir},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	if err := ag.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+	if err := ag.SendMessage("what is in my project?"); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	observer.wait(t)
+
+	// the turn span ends just after the observer hears of the usage
+	var spans []tracing.SpanData
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		spans = exporter.Spans()
+		if len(spans) > 0 && spans[len(spans)-1].Name == "agent.turn" {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// name each span by its ancestry
+	byID := map[string]tracing.SpanData{}
+	for _, span := range spans {
+		byID[span.SpanID] = span
+	}
+	path := func(span tracing.SpanData) string {
+		names := []string{span.Name}
+		for parent, ok := byID[span.ParentID]; ok; parent, ok = byID[parent.ParentID] {
+			names = append([]string{parent.Name}, names...)
+		}
+		return strings.Join(names, " > ")
+	}
+
+	var paths []string
+	for _, span := range spans {
+		if span.TraceID != spans[0].TraceID {
+			t.Errorf("Expected a single trace, %s is in %s", span.Name, span.TraceID)
+		}
+		paths = append(paths, path(span))
+	}
+
+	expected := []string{
+		"agent.turn > conversation.send",
+		"agent.turn > tool.call > mage.execute > conversation.send",
+		"agent.turn > tool.call > mage.execute > tool.call",
+		"agent.turn > tool.call > mage.execute > conversation.send",
+		"agent.turn > tool.call > mage.execute",
+		"agent.turn > tool.call",
+		"agent.turn > conversation.send",
+		"agent.turn",
+	}
+	if strings.Join(paths, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected spans:\n%s", strings.Join(paths, "\n"))
+	}
+	if len(spans) == len(expected) {
+		if tool := spans[5].Attributes["tool"]; tool != "task_mage" {
+			t.Errorf("Expected the task_mage call, got %v", tool)
+		}
+		if mage := spans[4].Attributes["mage"]; mage != "mage_fs" {
+			t.Errorf("Expected the filesystem mage, got %v", mage)
+		}
+	}
+}
+
+func TestAgentReportsErrors(t *testing.T) {
+	server := beautest.NewServer(beautest.ServerError(http.StatusBadGateway, "upstream down"))
+	defer server.Close()
+
+	observer := newTestObserver()
+	ag, err := NewAgent(Config{
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Observer: observer,
+		APIKey:   "test-key",
+		BaseURL:  server.URL,
+		Model:    "test-model",
+		ProjectBounds: []beau.ProjectBounds{
+			{Name: "test", Description: "Test project", ABSPath: t.TempDir()},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	ag.Start(context.Background())
+
+	if err := ag.SendMessage("hello"); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	observer.wait(t)
+
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	if len(observer.errors) == 0 || len(observer.complete) != 0 {
+		t.Errorf("Expected an error and no completion, got errors=%v complete=%d", observer.errors, len(observer.complete))
+	}
+}
+
+func TestAgentResumesSession(t *testing.T) {
+	server := beautest.NewServer(
+		beautest.Text("Noted."),
+		beautest.Text("It was 42."),
+	)
+	defer server.Close()
+
+	sessionPath := filepath.Join(t.TempDir(), "session.jsonl")
+	config := Config{
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		APIKey:      "test-key",
+		BaseURL:     server.URL,
+		Model:       "test-model",
+		SessionPath: sessionPath,
+		ProjectBounds: []beau.ProjectBounds{
+			{Name: "test", Description: "Test project", ABSPath: t.TempDir()},
+		},
+	}
+
+	// Helper function to run one turn on a fresh agent
+	runTurn := func(message string) {
+		observer := newTestObserver()
+		config.Observer = observer
+		ag, err := NewAgent(config)
+		if err != nil {
+			t.Fatalf("Failed to create agent: %v", err)
+		}
+		ag.Start(context.Background())
+		if err := ag.SendMessage(message); err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+		observer.wait(t)
+	}
+
+	runTurn("remember the number 42")
+	runTurn("what was the number?")
+
+	server.AssertRequestCount(t, 2)
+	server.AssertMessage(t, 1, beau.RoleUser, "remember the number 42")
+	server.AssertMessage(t, 1, beau.RoleAssistant, "Noted.")
+	server.AssertMessage(t, 1, beau.RoleUser, "what was the number?")
+
+	// the system prompt is carried over, not duplicated
+	systemMessages := 0
+	for _, msg := range server.Requests()[1].Messages {
+		if msg.Role == beau.RoleSystem {
+			systemMessages++
+		}
+	}
+	if systemMessages != 1 {
+		t.Errorf("Expected 1 system message, got %d", systemMessages)
+	}
+}
+
+func TestAgentEditMessage(t *testing.T) {
+	server := beautest.NewServer(
+		beautest.Text("Paris."),
+		beautest.Text("Rome."),
+	)
+	defer server.Close()
+
+	observer := newTestObserver()
+	ag, err := NewAgent(Config{
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Observer: observer,
+		APIKey:   "test-key",
+		BaseURL:  server.URL,
+		Model:    "test-model",
+		ProjectBounds: []beau.ProjectBounds{
+			{Name: "test", Description: "Test project", ABSPath: t.TempDir()},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	ag.Start(context.Background())
+
+	start := ag.Checkpoint()
+	if err := ag.SendMessage("capital of France?"); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	observer.wait(t)
+
+	// the system prompt is message 0, the question message 1
+	if err := untilIdle(func() error { return ag.EditMessage(1, "capital of Italy?") }); err != nil {
+		t.Fatalf("Failed to edit message: %v", err)
+	}
+	observer.wait(t)
+
+	server.AssertRequestCount(t, 2)
+	server.AssertMessage(t, 1, beau.RoleUser, "capital of Italy?")
+	for _, msg := range server.Requests()[1].Messages {
+		if msg.Role == beau.RoleAssistant {
+			t.Errorf("Expected the original reply to be dropped, got %+v", msg)
+		}
+	}
+
+	// both questions branch off the system prompt
+	system := ag.History().Children[0]
+	if len(system.Children) != 2 {
+		t.Errorf("Expected 2 branches, got %d", len(system.Children))
+	}
+
+	if err := untilIdle(func() error { return ag.Rollback(start) }); err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if err := ag.EditMessage(1, "x"); err == nil {
+		t.Errorf("Expected an error editing a message that no longer exists")
+	}
+}
@@ -1,19 +1,22 @@
 package beau
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/bosley/beau/tracing"
 )
 
 var (
@@ -31,6 +34,9 @@ var (
 	ErrReadStream           = fmt.Errorf("error reading stream")
 )
 
+// NewClient creates a client for baseURL. The provider is detected from the base url
+// and can be overridden with WithProvider. apiKey may be empty for providers that do
+// not need one, such as a local Ollama server.
 func NewClient(
 	apiKey string,
 	baseURL string,
@@ -39,10 +45,6 @@ func NewClient(
 	retryConfig RetryConfig,
 ) (*Client, error) {
 
-	if apiKey == "" {
-		return nil, ErrMissingAPIKey
-	}
-
 	if baseURL == "" {
 		return nil, ErrMissingBaseURL
 	}
@@ -65,6 +67,8 @@ func NewClient(
 		HTTPClient:  httpClient,
 		Logger:      logger,
 		RetryConfig: retryConfig,
+		Provider:    DetectProvider(baseURL),
+		Meter:       NewCostMeter(nil),
 	}, nil
 }
 
@@ -83,22 +87,42 @@ func (x *Client) WithHTTPClient(client *http.Client) *Client {
 	return x
 }
 
+// WithProvider overrides the provider detected from the base url
+func (x *Client) WithProvider(provider Provider) *Client {
+	if provider != nil {
+		x.Provider = provider
+	}
+	return x
+}
+
 func (x *Client) WithRetryConfig(config RetryConfig) *Client {
 	x.RetryConfig = config
 	return x
 }
 
+// WithRateLimiter makes every request wait for room in the limiter's budgets
+func (x *Client) WithRateLimiter(limiter *RateLimiter) *Client {
+	x.RateLimiter = limiter
+	return x
+}
+
+// WithCache serves repeated requests from cache instead of the provider
+func (x *Client) WithCache(cache Cache) *Client {
+	x.Cache = cache
+	return x
+}
+
 func CreateTextMessage(role MessageRole, content string) Message {
 	return Message{
 		Role:    role,
-		Content: content,
+		Content: TextContent(content),
 	}
 }
 
 func CreateComplexMessage(role MessageRole, items []ContentItem) Message {
 	return Message{
 		Role:    role,
-		Content: items,
+		Content: MessageContent(items),
 	}
 }
 
@@ -193,85 +217,167 @@ func parseRetryAfter(retryAfterHeader string) time.Duration {
 }
 
 func (x *Client) doRequestWithRetry(ctx context.Context, req *http.Request) (*http.Response, error) {
+	classifier := x.RetryConfig.Classifier
+	if classifier == nil {
+		classifier = DefaultRetryClassifier
+	}
+
+	var bodyBytes []byte
+	if req.Body != nil {
+		var err error
+		bodyBytes, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrReadRequestBody, err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	}
+
 	var lastErr error
 	delay := x.RetryConfig.InitialDelay
 
+	// the estimated tokens are charged once per request, a retry only takes
+	// another request slot
+	tokens := estimateRequestTokens(bodyBytes)
+
 	for attempt := 0; attempt <= x.RetryConfig.MaxRetries; attempt++ {
 		reqCopy := req.Clone(ctx)
-		if req.Body != nil {
-			bodyBytes, err := io.ReadAll(req.Body)
-			if err != nil {
-				return nil, fmt.Errorf("%w: %v", ErrReadRequestBody, err)
-			}
-			req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		if bodyBytes != nil {
 			reqCopy.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 		}
+		for key, values := range requestHeaders(ctx) {
+			reqCopy.Header[key] = values
+		}
 
+		finalAttempt := attempt >= x.RetryConfig.MaxRetries
+
+		if x.RateLimiter != nil {
+			if err := x.RateLimiter.Wait(ctx, tokens); err != nil {
+				return nil, err
+			}
+			tokens = 0
+		}
+
+		var wait time.Duration
 		resp, err := x.HTTPClient.Do(reqCopy)
 		if err != nil {
+			// transport failures are retried even when status retries are disabled
+			if finalAttempt || !classifier(0, err) {
+				return nil, err
+			}
 			lastErr = err
-			if attempt < x.RetryConfig.MaxRetries {
-				x.Logger.Warn("Request failed, retrying",
-					"attempt", attempt+1,
-					"error", err,
-					"delay", delay)
-
-				select {
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				case <-time.After(delay):
-					delay = time.Duration(float64(delay) * x.RetryConfig.BackoffFactor)
-					if delay > x.RetryConfig.MaxDelay {
-						delay = x.RetryConfig.MaxDelay
-					}
-					continue
-				}
+			wait = x.retryDelay(delay, 0)
+
+			x.Logger.Warn("Request failed, retrying",
+				"attempt", attempt+1,
+				"error", err,
+				"delay", wait)
+		} else {
+			if finalAttempt || !x.RetryConfig.Enabled || !classifier(resp.StatusCode, nil) {
+				x.observeRateLimits(resp)
+				return resp, nil
 			}
-			return nil, err
-		}
 
-		if resp.StatusCode == http.StatusTooManyRequests && x.RetryConfig.Enabled && attempt < x.RetryConfig.MaxRetries {
 			body, _ := io.ReadAll(resp.Body)
 			resp.Body.Close()
 
-			retryAfter := delay
-			if retryAfterHeader := resp.Header.Get("Retry-After"); retryAfterHeader != "" {
-				retryAfter = parseRetryAfter(retryAfterHeader)
+			apiErr := x.classifyError(resp, body)
+			// a spent quota does not come back by waiting, hand it to the caller
+			if errors.Is(apiErr, ErrQuotaExceeded) {
+				resp.Body = io.NopCloser(bytes.NewReader(body))
+				x.observeRateLimits(resp)
+				return resp, nil
 			}
+			lastErr = apiErr
+			wait = x.retryDelay(delay, retryAfterFromHeader(resp.Header))
 
-			if retryAfter > delay {
-				delay = retryAfter
-			}
-
-			if delay > x.RetryConfig.MaxDelay {
-				delay = x.RetryConfig.MaxDelay
-			}
-
-			x.Logger.Warn("Rate limited, retrying",
+			x.Logger.Warn("Request rejected, retrying",
 				"attempt", attempt+1,
 				"statusCode", resp.StatusCode,
-				"delay", delay,
-				"responseBody", string(body))
+				"error", apiErr,
+				"delay", wait)
+		}
 
-			lastErr = &RateLimitError{
-				StatusCode:   resp.StatusCode,
-				RetryAfter:   delay,
-				ResponseBody: string(body),
-			}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
 
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(delay):
-				delay = time.Duration(float64(delay) * x.RetryConfig.BackoffFactor)
-				continue
-			}
+		delay = time.Duration(float64(delay) * x.RetryConfig.BackoffFactor)
+		if x.RetryConfig.MaxDelay > 0 && delay > x.RetryConfig.MaxDelay {
+			delay = x.RetryConfig.MaxDelay
 		}
+	}
+
+	return nil, fmt.Errorf("%w: %w", ErrMaxRetriesExceeded, lastErr)
+}
 
-		return resp, nil
+// estimateRequestTokens guesses the prompt tokens of a request body at roughly
+// four bytes per token, completion tokens are recorded once the response arrives
+func estimateRequestTokens(body []byte) int {
+	return len(body) / 4
+}
+
+// storeResponse caches a successful response, failures only cost a future miss
+func (x *Client) storeResponse(key string, result *ChatCompletionResponse) {
+	if x.Cache == nil || key == "" || result == nil || len(result.Choices) == 0 {
+		return
 	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	if err := x.Cache.Set(key, data); err != nil {
+		x.Logger.Warn("Failed to cache response", "error", err)
+	}
+}
 
-	return nil, fmt.Errorf("%w: %v", ErrMaxRetriesExceeded, lastErr)
+// observeRateLimits reconciles the rate limiter with the headers of the response
+// a request ended with, responses that were retried are not counted
+func (x *Client) observeRateLimits(resp *http.Response) {
+	if x.RateLimiter != nil {
+		x.RateLimiter.Observe(resp.Header)
+	}
+}
+
+// recordUsage charges the completion tokens of a response to the rate limiter
+func (x *Client) recordUsage(result *ChatCompletionResponse) {
+	if x.RateLimiter != nil && result != nil {
+		x.RateLimiter.Record(result.Usage.CompletionTokens)
+	}
+}
+
+// retryDelay jitters the backoff delay so clients that failed together do not
+// retry together, and never waits less than the server asked for
+func (x *Client) retryDelay(delay time.Duration, retryAfter time.Duration) time.Duration {
+	if x.RetryConfig.Jitter > 0 {
+		delay -= time.Duration(float64(delay) * x.RetryConfig.Jitter * rand.Float64())
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	if x.RetryConfig.MaxDelay > 0 && delay > x.RetryConfig.MaxDelay {
+		delay = x.RetryConfig.MaxDelay
+	}
+	return delay
+}
+
+// classifyError turns an error response into an error, adding the request id
+// and retry hint from the headers when the provider returned an APIError
+func (x *Client) classifyError(resp *http.Response, body []byte) error {
+	err := x.Provider.ClassifyError(resp.StatusCode, body)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		apiErr.Provider = x.Provider.Name()
+		if apiErr.RequestID == "" {
+			apiErr.RequestID = requestIDFromHeader(resp.Header)
+		}
+		if apiErr.RetryAfter == 0 {
+			apiErr.RetryAfter = retryAfterFromHeader(resp.Header)
+		}
+	}
+	return err
 }
 
 func (x *Client) Send(ctx context.Context, temperature float64, maxTokens int, messages []Message, model string, opts ...RequestOption) (*ChatCompletionResponse, error) {
@@ -286,206 +392,184 @@ func (x *Client) Send(ctx context.Context, temperature float64, maxTokens int, m
 		opt(&req)
 	}
 
-	jsonData, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrMarshalRequest, err)
-	}
+	x.Logger.Debug("Sending request to Client", "provider", x.Provider.Name(), "model", model, "messageCount", len(messages))
+	return x.serve(ctx, &req)
+}
 
-	x.Logger.Debug("Sending request to Client", "model", model, "messageCount", len(messages))
+// dispatch answers a request from the cache or the fallback chain, it is the
+// innermost handler of the middleware chain
+func (x *Client) dispatch(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	model := req.Model
+	streaming := req.streamChannel() != nil
 
-	cleanBaseURL := strings.TrimSuffix(x.BaseURL, "/")
-	fullURL := fmt.Sprintf("%s/v1/chat/completions", cleanBaseURL)
+	var cacheKey string
+	if x.Cache != nil {
+		cacheKey = CacheKey(x.Provider.Name(), x.BaseURL, req)
+		if data, ok := x.Cache.Get(cacheKey); ok {
+			if cached := cachedResponse(data); cached != nil {
+				x.Logger.Debug("Serving response from cache", "model", model, "key", cacheKey)
+				cached.Target = &ResponseTarget{Provider: x.Provider.Name(), BaseURL: x.BaseURL, Model: model, Cached: true}
+				if streaming {
+					replayStream(cached, req.streamConfig.Channel)
+				}
+				return cached, nil
+			}
+		}
+	}
 
-	httpReq, err := http.NewRequestWithContext(
-		ctx,
-		"POST",
-		fullURL,
-		bytes.NewBuffer(jsonData),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrCreateRequest, err)
+	var lastErr error
+	chain := x.fallbackChain(model)
+	if err := x.checkBudget(); err != nil {
+		// nothing is sent once the budget is spent
+		lastErr, chain = err, nil
+	}
+	for i, next := range chain {
+		req.Model = next.target.Model
+		result, streamed, err := next.client.send(ctx, req, streaming)
+		if err == nil {
+			target := next.target
+			result.Target = &target
+			x.recordCost(target.Model, result.Usage)
+			x.storeResponse(cacheKey, result)
+			return result, nil
+		}
+		lastErr = err
+
+		// content already streamed cannot be taken back
+		if streamed || i == len(chain)-1 || !x.shouldFallback(ctx, err) {
+			break
+		}
+		x.Logger.Warn("Request failed, falling back",
+			"provider", next.target.Provider,
+			"model", next.target.Model,
+			"error", err,
+			"next", chain[i+1].target.Model)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", x.APIKey))
+	if streaming {
+		req.streamConfig.Channel <- StreamChunk{Error: lastErr}
+		close(req.streamConfig.Channel)
+	}
+	return nil, lastErr
+}
 
-	// Add Anthropic-specific headers if using Claude
-	if strings.Contains(x.BaseURL, "anthropic.com") {
-		httpReq.Header.Set("anthropic-version", "2023-06-01")
-		// For Anthropic, use x-api-key instead of Bearer token
-		httpReq.Header.Set("x-api-key", x.APIKey)
-		httpReq.Header.Del("Authorization")
+// send performs a request against the client's own provider. Streaming
+// responses are forwarded to the request's channel, which is closed on success
+// and left open on failure. streamed reports whether any content was forwarded.
+func (x *Client) send(ctx context.Context, req ChatCompletionRequest, streaming bool) (*ChatCompletionResponse, bool, error) {
+	httpReq, err := x.Provider.NewRequest(ctx, x.BaseURL, x.APIKey, req)
+	if err != nil {
+		return nil, false, err
 	}
 
 	// If streaming is enabled and we have a channel, handle streaming
-	if req.Stream && req.streamConfig != nil && req.streamConfig.Channel != nil {
+	if streaming {
 		x.Logger.Debug("Using streaming response with channel")
 		return x.handleStreamingResponse(ctx, httpReq, req.streamConfig.Channel)
 	}
 
 	resp, err := x.doRequestWithRetry(ctx, httpReq)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrReadResponseBody, err)
+		return nil, false, fmt.Errorf("%w: %v", ErrReadResponseBody, err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: %d: %s", ErrUnexpectedStatusCode, resp.StatusCode, string(body))
+		return nil, false, x.classifyError(resp, body)
 	}
 
-	var result ChatCompletionResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrUnmarshalResponse, err)
+	result, err := x.Provider.ParseResponse(body)
+	if err != nil {
+		return nil, false, err
 	}
+	x.recordUsage(result)
 
 	if len(result.Choices) > 0 && result.Choices[0].FinishReason == "length" {
 		x.Logger.Warn("Response was truncated due to length limits",
 			"finishReason", result.Choices[0].FinishReason,
-			"model", model)
+			"model", req.Model)
 	}
-
-	return &result, nil
+	return result, false, nil
 }
 
-func (x *Client) handleStreamingResponse(ctx context.Context, req *http.Request, stream chan StreamChunk) (*ChatCompletionResponse, error) {
+func (x *Client) handleStreamingResponse(ctx context.Context, req *http.Request, stream chan StreamChunk) (*ChatCompletionResponse, bool, error) {
 	resp, err := x.doRequestWithRetry(ctx, req)
 	if err != nil {
-		stream <- StreamChunk{Error: err}
-		close(stream)
-		return nil, err
+		return nil, false, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		err := fmt.Errorf("%w: %d: %s", ErrUnexpectedStatusCode, resp.StatusCode, string(body))
-		stream <- StreamChunk{Error: err}
-		close(stream)
-		return nil, err
-	}
-
-	scanner := bufio.NewScanner(resp.Body)
-	var fullMessage string
-	var toolCalls []ToolCall
-
-	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			stream <- StreamChunk{Error: ctx.Err()}
-			close(stream)
-			return nil, ctx.Err()
-		default:
-			line := scanner.Text()
-			if line == "" {
-				continue
-			}
-
-			line = strings.TrimPrefix(line, "data: ")
-
-			if line == "[DONE]" {
-				stream <- StreamChunk{Done: true}
-				close(stream)
-				return &ChatCompletionResponse{
-					Choices: []Choice{
-						{
-							Message: Message{
-								Role:      RoleAssistant,
-								Content:   fullMessage,
-								ToolCalls: toolCalls,
-							},
-						},
-					},
-				}, nil
-			}
-
-			var chunk struct {
-				Choices []struct {
-					Delta struct {
-						Content   string     `json:"content"`
-						ToolCalls []ToolCall `json:"tool_calls"`
-					} `json:"delta"`
-				} `json:"choices"`
-			}
-
-			if err := json.Unmarshal([]byte(line), &chunk); err != nil {
-				x.Logger.Debug("Failed to parse chunk", "error", err, "line", line)
-				continue
-			}
-
-			if len(chunk.Choices) > 0 {
-				delta := chunk.Choices[0].Delta
-
-				// Handle content streaming
-				if delta.Content != "" {
-					stream <- StreamChunk{Content: delta.Content}
-					fullMessage += delta.Content
-				}
-
-				// Handle tool calls
-				if len(delta.ToolCalls) > 0 {
-					// Merge tool calls - streaming may send partial tool calls
-					for _, tc := range delta.ToolCalls {
-						if tc.ID != "" {
-							// New tool call
-							toolCalls = append(toolCalls, tc)
-						} else if len(toolCalls) > 0 {
-							// Update last tool call with additional data
-							lastIdx := len(toolCalls) - 1
-							if tc.Function.Name != "" {
-								toolCalls[lastIdx].Function.Name = tc.Function.Name
-							}
-							if tc.Function.Arguments != "" {
-								toolCalls[lastIdx].Function.Arguments += tc.Function.Arguments
-							}
-						}
-					}
-				}
-			}
+		return nil, false, x.classifyError(resp, body)
+	}
+
+	// chunks pass through a relay to learn whether any reached the caller, a
+	// stream failing on its first event can still fall back
+	relay := make(chan StreamChunk)
+	forwarded := make(chan bool)
+	go func() {
+		streamed := false
+		for chunk := range relay {
+			stream <- chunk
+			streamed = true
 		}
+		forwarded <- streamed
+	}()
+	result, err := x.Provider.DecodeStream(ctx, resp.Body, relay)
+	close(relay)
+	streamed := <-forwarded
+	if err != nil {
+		return nil, streamed, err
 	}
+	x.recordUsage(result)
 
-	if err := scanner.Err(); err != nil {
-		stream <- StreamChunk{Error: fmt.Errorf("%w: %v", ErrReadStream, err)}
-		close(stream)
-		return nil, err
-	}
-
-	return &ChatCompletionResponse{
-		Choices: []Choice{
-			{
-				Message: Message{
-					Role:      RoleAssistant,
-					Content:   fullMessage,
-					ToolCalls: toolCalls,
-				},
-			},
-		},
-	}, nil
+	stream <- StreamChunk{Done: true}
+	close(stream)
+	return result, streamed, nil
 }
 
 type Conversation struct {
-	client   *Client
-	messages []Message
-	model    string
-	options  []RequestOption
+	client       *Client
+	messages     []Message
+	model        string
+	options      []RequestOption
+	lastResponse *ChatCompletionResponse
+	storePath    string // conversation log appended to, see PersistTo
+	policy       ContextPolicy
+	tree         *historyTree
+	head         *HistoryNode
 }
 
 func (x *Client) NewConversation(model string, opts ...RequestOption) *Conversation {
-	return &Conversation{
+	conv := &Conversation{
 		client:   x,
 		messages: []Message{},
 		model:    model,
 		options:  opts,
 	}
+	conv.initHistory()
+	return conv
+}
+
+// WithContextPolicy sets the policy deciding what part of the history is sent
+// with each request
+func (c *Conversation) WithContextPolicy(policy ContextPolicy) *Conversation {
+	c.policy = policy
+	return c
 }
 
 func (c *Conversation) AddMessage(message Message) *Conversation {
 	c.messages = append(c.messages, message)
+	c.head = c.tree.add(c.head, message)
+	if err := c.appendToStore(message); err != nil {
+		c.client.Logger.Error("Failed to persist conversation message", "path", c.storePath, "error", err)
+	}
 	return c
 }
 
@@ -508,38 +592,95 @@ func (c *Conversation) AddComplexUserMessage(items []ContentItem) *Conversation
 func (c *Conversation) AddToolResult(toolCallID string, result string) *Conversation {
 	return c.AddMessage(Message{
 		Role:       RoleTool,
-		Content:    result,
+		Content:    TextContent(result),
 		ToolCallID: toolCallID,
 	})
 }
 
-func (c *Conversation) Send(ctx context.Context, temperature float64, maxTokens int, opts ...RequestOption) (*Message, error) {
+// AddToolError adds the result of a tool call that failed
+func (c *Conversation) AddToolError(toolCallID string, result string) *Conversation {
+	return c.AddMessage(Message{
+		Role:       RoleTool,
+		Content:    TextContent(result),
+		ToolCallID: toolCallID,
+		IsError:    true,
+	})
+}
+
+func (c *Conversation) Send(ctx context.Context, temperature float64, maxTokens int, opts ...RequestOption) (_ *Message, err error) {
+	ctx, span := tracing.Start(ctx, "conversation.send")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	span.SetAttribute("model", c.model)
+
 	finalOptions := append(c.options, opts...)
-	response, err := c.client.Send(ctx, temperature, maxTokens, c.messages, c.model, finalOptions...)
+
+	messages := c.messages
+	if c.policy != nil {
+		if messages, err = c.policy.Apply(ctx, c.messages); err != nil {
+			return nil, err
+		}
+		if len(messages) != len(c.messages) {
+			c.client.Logger.Debug("Context policy trimmed history", "messages", len(c.messages), "sent", len(messages))
+		}
+	}
+	span.SetAttribute("messages", len(messages))
+
+	response, err := c.client.Send(ctx, temperature, maxTokens, messages, c.model, finalOptions...)
 	if err != nil {
 		return nil, err
 	}
+	if span != nil {
+		c.traceResponse(span, response)
+	}
 
 	if len(response.Choices) == 0 {
 		return nil, ErrNoResponseChoices
 	}
 
+	c.lastResponse = response
 	message := response.Choices[0].Message
 
 	// Check if the response was truncated
 	if response.Choices[0].FinishReason == "length" || response.Choices[0].FinishReason == "max_tokens" {
 		c.client.Logger.Warn("Response truncated",
 			"finishReason", response.Choices[0].FinishReason,
-			"contentLength", len(fmt.Sprintf("%v", message.Content)))
+			"contentLength", len(message.Text()))
 	}
-	c.messages = append(c.messages, message)
+	c.AddMessage(message)
 	return &message, nil
 }
 
+// traceResponse records what answered a request and what it cost on span
+func (c *Conversation) traceResponse(span *tracing.Span, response *ChatCompletionResponse) {
+	model := c.model
+	if response.Target != nil {
+		model = response.Target.Model
+		span.SetAttribute("provider", response.Target.Provider)
+		span.SetAttribute("target_model", response.Target.Model)
+		span.SetAttribute("fallback", response.Target.Fallback)
+	}
+	span.SetAttribute("prompt_tokens", response.Usage.PromptTokens)
+	span.SetAttribute("completion_tokens", response.Usage.CompletionTokens)
+	span.SetAttribute("cost_usd", c.client.costOf(model, response.Usage))
+	if len(response.Choices) > 0 {
+		span.SetAttribute("finish_reason", response.Choices[0].FinishReason)
+		span.SetAttribute("tool_calls", len(response.Choices[0].Message.ToolCalls))
+	}
+}
+
 func (c *Conversation) GetMessages() []Message {
 	return c.messages
 }
 
+// LastResponse returns the full response to the most recent Send, including its
+// token usage. It is nil until a Send has succeeded.
+func (c *Conversation) LastResponse() *ChatCompletionResponse {
+	return c.lastResponse
+}
+
 type RequestOption func(*ChatCompletionRequest)
 
 func WithTemperature(temperature float64) RequestOption {
@@ -563,6 +704,7 @@ func WithTools(tools []Tool) RequestOption {
 func WithStream(channel chan StreamChunk) RequestOption {
 	return func(req *ChatCompletionRequest) {
 		req.Stream = true
+		req.StreamOptions = &StreamOptions{IncludeUsage: true}
 		req.streamConfig = &StreamConfig{
 			Enabled: true,
 			Channel: channel,
@@ -570,6 +712,65 @@ func WithStream(channel chan StreamChunk) RequestOption {
 	}
 }
 
+// WithTopP samples from the smallest set of tokens whose probability adds up to p
+func WithTopP(p float64) RequestOption {
+	return func(req *ChatCompletionRequest) {
+		req.TopP = &p
+	}
+}
+
+// WithStop ends generation at any of the stop sequences
+func WithStop(stop ...string) RequestOption {
+	return func(req *ChatCompletionRequest) {
+		req.Stop = stop
+	}
+}
+
+// WithSeed asks for deterministic sampling where the backend supports it
+func WithSeed(seed int64) RequestOption {
+	return func(req *ChatCompletionRequest) {
+		req.Seed = &seed
+	}
+}
+
+// WithN asks for n choices, returned in ChatCompletionResponse.Choices. Only the
+// first choice is streamed.
+func WithN(n int) RequestOption {
+	return func(req *ChatCompletionRequest) {
+		req.N = n
+	}
+}
+
+// WithPresencePenalty penalizes tokens that have appeared at all
+func WithPresencePenalty(penalty float64) RequestOption {
+	return func(req *ChatCompletionRequest) {
+		req.PresencePenalty = &penalty
+	}
+}
+
+// WithFrequencyPenalty penalizes tokens by how often they have appeared
+func WithFrequencyPenalty(penalty float64) RequestOption {
+	return func(req *ChatCompletionRequest) {
+		req.FrequencyPenalty = &penalty
+	}
+}
+
+// WithLogprobs returns the log probability of every generated token in
+// Choice.Logprobs, along with the top most likely alternatives when top > 0
+func WithLogprobs(top int) RequestOption {
+	return func(req *ChatCompletionRequest) {
+		req.Logprobs = true
+		req.TopLogprobs = top
+	}
+}
+
+// WithParallelToolCalls allows or forbids several tool calls in one reply
+func WithParallelToolCalls(enabled bool) RequestOption {
+	return func(req *ChatCompletionRequest) {
+		req.ParallelToolCalls = &enabled
+	}
+}
+
 func WithToolChoice(toolChoice interface{}) RequestOption {
 	return func(req *ChatCompletionRequest) {
 		req.ToolChoice = toolChoice
@@ -0,0 +1,518 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const anthropicVersion = "2023-06-01"
+
+// anthropicRequest is the body of a request to the Anthropic Messages API
+type anthropicRequest struct {
+	Model         string               `json:"model"`
+	System        string               `json:"system,omitempty"`
+	Messages      []anthropicMessage   `json:"messages"`
+	MaxTokens     int                  `json:"max_tokens"`
+	Temperature   float64              `json:"temperature,omitempty"`
+	TopP          *float64             `json:"top_p,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    MessageRole      `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock is a single content block. Which fields are set depends on Type
+type anthropicBlock struct {
+	Type string `json:"type"`
+
+	// text
+	Text string `json:"text,omitempty"`
+
+	// image
+	Source *anthropicImageSource `json:"source,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"` // base64 or url
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type                   string `json:"type"` // auto, any, tool, none
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// usage converts to the OpenAI shape, where prompt tokens include cached ones
+func (u anthropicUsage) usage() Usage {
+	prompt := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	usage := Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      prompt + u.OutputTokens,
+	}
+	if u.CacheReadInputTokens > 0 {
+		usage.PromptTokensDetails = &PromptTokensDetails{CachedTokens: u.CacheReadInputTokens}
+	}
+	return usage
+}
+
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Type       string           `json:"type"`
+	Role       string           `json:"role"`
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+// anthropicStreamEvent covers every event type sent on a Messages stream
+type anthropicStreamEvent struct {
+	Type         string             `json:"type"`
+	Index        int                `json:"index"`
+	Message      *anthropicResponse `json:"message,omitempty"`
+	ContentBlock *anthropicBlock    `json:"content_block,omitempty"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// AnthropicProvider speaks the native Anthropic Messages API, translating messages,
+// tool calls and content items to and from its block based format
+type AnthropicProvider struct {
+	// Version is sent as the anthropic-version header
+	Version string
+}
+
+var _ Provider = &AnthropicProvider{}
+
+func NewAnthropicProvider() *AnthropicProvider {
+	return &AnthropicProvider{
+		Version: anthropicVersion,
+	}
+}
+
+func (p *AnthropicProvider) Name() string {
+	return "anthropic"
+}
+
+// toAnthropicRequest translates a chat completions request into the Messages API shape.
+// System messages are hoisted into the top level system prompt, tool results become
+// tool_result blocks on a user turn, and consecutive turns of the same role are merged
+// since the API requires user and assistant turns to alternate. The API has no seed,
+// n, penalties or logprobs, so those are dropped.
+func toAnthropicRequest(req ChatCompletionRequest) anthropicRequest {
+	out := anthropicRequest{
+		Model:         req.Model,
+		MaxTokens:     req.MaxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		StopSequences: req.Stop,
+		Stream:        req.Stream,
+		Messages:      []anthropicMessage{},
+	}
+
+	if out.MaxTokens == 0 {
+		out.MaxTokens = DefaultMaxTokens
+	}
+
+	var system []string
+	for _, msg := range req.Messages {
+		var role MessageRole
+		var blocks []anthropicBlock
+
+		switch msg.Role {
+		case RoleSystem:
+			for _, item := range msg.Content {
+				if item.Type == ContentTypeText && item.Text != "" {
+					system = append(system, item.Text)
+				}
+			}
+			continue
+		case RoleTool:
+			role = RoleUser
+			blocks = []anthropicBlock{{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   msg.Content.Text(),
+				IsError:   msg.IsError,
+			}}
+		default:
+			role = msg.Role
+			for _, item := range msg.Content {
+				if block, ok := toAnthropicBlock(item); ok {
+					blocks = append(blocks, block)
+				}
+			}
+			for _, tc := range msg.ToolCalls {
+				blocks = append(blocks, toolCallToAnthropicBlock(tc))
+			}
+		}
+
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if n := len(out.Messages); n > 0 && out.Messages[n-1].Role == role {
+			out.Messages[n-1].Content = append(out.Messages[n-1].Content, blocks...)
+			continue
+		}
+		out.Messages = append(out.Messages, anthropicMessage{Role: role, Content: blocks})
+	}
+	// The Messages API has no response format, so it is requested in the system prompt
+	if instruction := responseFormatInstruction(req.ResponseFormat); instruction != "" {
+		system = append(system, instruction)
+	}
+	out.System = strings.Join(system, "\n\n")
+
+	for _, tool := range req.Tools {
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		out.Tools = append(out.Tools, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+
+	if len(out.Tools) > 0 {
+		out.ToolChoice = toAnthropicToolChoice(req.ToolChoice)
+		if req.ParallelToolCalls != nil && !*req.ParallelToolCalls {
+			if out.ToolChoice == nil {
+				out.ToolChoice = &anthropicToolChoice{Type: "auto"}
+			}
+			if out.ToolChoice.Type != "none" {
+				out.ToolChoice.DisableParallelToolUse = true
+			}
+		}
+	}
+
+	return out
+}
+
+func toAnthropicBlock(item ContentItem) (anthropicBlock, bool) {
+	switch item.Type {
+	case ContentTypeText:
+		if item.Text == "" {
+			return anthropicBlock{}, false
+		}
+		return anthropicBlock{Type: "text", Text: item.Text}, true
+	case ContentTypeImageURL:
+		if item.ImageURL == nil {
+			return anthropicBlock{}, false
+		}
+		return anthropicBlock{Type: "image", Source: toAnthropicImageSource(item.ImageURL.URL)}, true
+	case ContentTypeTool:
+		if item.ToolCall == nil {
+			return anthropicBlock{}, false
+		}
+		return toolCallToAnthropicBlock(*item.ToolCall), true
+	case ContentTypeToolResult:
+		if item.ToolResult == nil {
+			return anthropicBlock{}, false
+		}
+		return anthropicBlock{
+			Type:      "tool_result",
+			ToolUseID: item.ToolResult.ToolCallID,
+			Content:   item.ToolResult.Content,
+			IsError:   item.ToolResult.IsError,
+		}, true
+	}
+	return anthropicBlock{}, false
+}
+
+func toolCallToAnthropicBlock(tc ToolCall) anthropicBlock {
+	input := json.RawMessage(tc.Function.Arguments)
+	if len(strings.TrimSpace(tc.Function.Arguments)) == 0 || !json.Valid(input) {
+		input = json.RawMessage("{}")
+	}
+	return anthropicBlock{
+		Type:  "tool_use",
+		ID:    tc.ID,
+		Name:  tc.Function.Name,
+		Input: input,
+	}
+}
+
+// toAnthropicImageSource converts a data URL into a base64 source, anything else is
+// passed along as a url source
+func toAnthropicImageSource(url string) *anthropicImageSource {
+	if strings.HasPrefix(url, "data:") {
+		header, data, found := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+		if found {
+			return &anthropicImageSource{
+				Type:      "base64",
+				MediaType: strings.TrimSuffix(header, ";base64"),
+				Data:      data,
+			}
+		}
+	}
+	return &anthropicImageSource{Type: "url", URL: url}
+}
+
+func toAnthropicToolChoice(choice interface{}) *anthropicToolChoice {
+	switch v := choice.(type) {
+	case nil:
+		return nil
+	case string:
+		switch v {
+		case "none":
+			return &anthropicToolChoice{Type: "none"}
+		case "required", "any":
+			return &anthropicToolChoice{Type: "any"}
+		default:
+			return &anthropicToolChoice{Type: "auto"}
+		}
+	case map[string]interface{}:
+		if fn, ok := v["function"].(map[string]interface{}); ok {
+			if name, ok := fn["name"].(string); ok {
+				return &anthropicToolChoice{Type: "tool", Name: name}
+			}
+		}
+	}
+	return &anthropicToolChoice{Type: "auto"}
+}
+
+// fromAnthropicStopReason maps Anthropic stop reasons onto the chat completions finish reasons
+func fromAnthropicStopReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	}
+	return reason
+}
+
+func fromAnthropicResponse(resp anthropicResponse) *ChatCompletionResponse {
+	var text strings.Builder
+	var toolCalls []ToolCall
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			args := string(block.Input)
+			if args == "" {
+				args = "{}"
+			}
+			toolCalls = append(toolCalls, ToolCall{
+				ID:   block.ID,
+				Type: "function",
+				Function: ToolFunction{
+					Name:      block.Name,
+					Arguments: args,
+				},
+			})
+		}
+	}
+
+	return &ChatCompletionResponse{
+		ID:     resp.ID,
+		Object: "chat.completion",
+		Model:  resp.Model,
+		Choices: []Choice{
+			{
+				Message: Message{
+					Role:      RoleAssistant,
+					Content:   TextContent(text.String()),
+					ToolCalls: toolCalls,
+				},
+				FinishReason: fromAnthropicStopReason(resp.StopReason),
+			},
+		},
+		Usage: resp.Usage.usage(),
+	}
+}
+
+func (p *AnthropicProvider) NewRequest(ctx context.Context, baseURL string, apiKey string, req ChatCompletionRequest) (*http.Request, error) {
+	if apiKey == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	jsonData, err := json.Marshal(toAnthropicRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMarshalRequest, err)
+	}
+
+	httpReq, err := newJSONRequest(ctx, baseURL, "/v1/messages", jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	version := p.Version
+	if version == "" {
+		version = anthropicVersion
+	}
+	httpReq.Header.Set("x-api-key", apiKey)
+	httpReq.Header.Set("anthropic-version", version)
+	return httpReq, nil
+}
+
+func (p *AnthropicProvider) ParseResponse(body []byte) (*ChatCompletionResponse, error) {
+	var result anthropicResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnmarshalResponse, err)
+	}
+	return fromAnthropicResponse(result), nil
+}
+
+func (p *AnthropicProvider) DecodeStream(ctx context.Context, body io.Reader, stream chan<- StreamChunk) (*ChatCompletionResponse, error) {
+	// The final message is rebuilt from the stream the same way the API would have
+	// returned it without streaming, then converted in one place.
+	var message anthropicResponse
+	var partialInputs = map[int]*strings.Builder{}
+
+	decoder := NewSSEDecoder(body)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		sse, err := decoder.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrReadStream, err)
+		}
+
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(sse.Data), &event); err != nil {
+			continue
+		}
+
+		// The event name is authoritative, the payload type is only a fallback
+		if sse.Event != "message" {
+			event.Type = sse.Event
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				message = *event.Message
+				message.Content = nil
+			}
+		case "content_block_start":
+			if event.ContentBlock == nil {
+				continue
+			}
+			for len(message.Content) <= event.Index {
+				message.Content = append(message.Content, anthropicBlock{})
+			}
+			message.Content[event.Index] = *event.ContentBlock
+			if event.ContentBlock.Type == "tool_use" {
+				message.Content[event.Index].Input = nil
+				partialInputs[event.Index] = &strings.Builder{}
+			}
+			if event.ContentBlock.Text != "" {
+				stream <- StreamChunk{Content: event.ContentBlock.Text}
+			}
+		case "content_block_delta":
+			if event.Index >= len(message.Content) {
+				continue
+			}
+			switch event.Delta.Type {
+			case "text_delta":
+				message.Content[event.Index].Text += event.Delta.Text
+				stream <- StreamChunk{Content: event.Delta.Text}
+			case "input_json_delta":
+				if b, ok := partialInputs[event.Index]; ok {
+					b.WriteString(event.Delta.PartialJSON)
+				}
+			}
+		case "content_block_stop":
+			if b, ok := partialInputs[event.Index]; ok && event.Index < len(message.Content) {
+				message.Content[event.Index].Input = json.RawMessage(b.String())
+				delete(partialInputs, event.Index)
+			}
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				message.StopReason = event.Delta.StopReason
+			}
+			if event.Usage != nil {
+				message.Usage.OutputTokens = event.Usage.OutputTokens
+				if event.Usage.InputTokens > 0 {
+					message.Usage.InputTokens = event.Usage.InputTokens
+				}
+				if event.Usage.CacheReadInputTokens > 0 {
+					message.Usage.CacheReadInputTokens = event.Usage.CacheReadInputTokens
+				}
+				if event.Usage.CacheCreationInputTokens > 0 {
+					message.Usage.CacheCreationInputTokens = event.Usage.CacheCreationInputTokens
+				}
+			}
+		case "message_stop":
+			return fromAnthropicResponse(message), nil
+		case "error":
+			if event.Error != nil {
+				return nil, fmt.Errorf("%w: %w", ErrReadStream, newAPIError(0, nil, "", event.Error.Type, event.Error.Message))
+			}
+			return nil, fmt.Errorf("%w: %s", ErrReadStream, sse.Data)
+		}
+	}
+
+	return fromAnthropicResponse(message), nil
+}
+
+func (p *AnthropicProvider) ClassifyError(statusCode int, body []byte) error {
+	var result struct {
+		Error *struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil && result.Error != nil {
+		return newAPIError(statusCode, body, "", result.Error.Type, result.Error.Message)
+	}
+	return unexpectedStatusError(statusCode, body)
+}
@@ -0,0 +1,246 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Helper function to replay a recorded stream transcript through a provider's decoder
+func decodeProviderTranscript(t *testing.T, provider Provider, name string) (*ChatCompletionResponse, []StreamChunk, error) {
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to open transcript: %v", err)
+	}
+	defer file.Close()
+
+	stream := make(chan StreamChunk, 100)
+	response, err := provider.DecodeStream(context.Background(), file, stream)
+	close(stream)
+
+	var chunks []StreamChunk
+	for chunk := range stream {
+		chunks = append(chunks, chunk)
+	}
+	return response, chunks, err
+}
+
+func TestToAnthropicRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		messages       []Message
+		expectSystem   string
+		expectMessages []anthropicMessage
+	}{
+		{
+			name: "System prompts are hoisted",
+			messages: []Message{
+				CreateTextMessage(RoleSystem, "You are terse."),
+				CreateTextMessage(RoleUser, "hi"),
+				CreateTextMessage(RoleSystem, "Answer in French."),
+			},
+			expectSystem: "You are terse.\n\nAnswer in French.",
+			expectMessages: []anthropicMessage{
+				{Role: RoleUser, Content: []anthropicBlock{{Type: "text", Text: "hi"}}},
+			},
+		},
+		{
+			name: "Same role turns are merged",
+			messages: []Message{
+				CreateTextMessage(RoleUser, "first"),
+				CreateTextMessage(RoleUser, "second"),
+				CreateTextMessage(RoleAssistant, "reply"),
+			},
+			expectMessages: []anthropicMessage{
+				{Role: RoleUser, Content: []anthropicBlock{{Type: "text", Text: "first"}, {Type: "text", Text: "second"}}},
+				{Role: RoleAssistant, Content: []anthropicBlock{{Type: "text", Text: "reply"}}},
+			},
+		},
+		{
+			name: "Tool results go on a user turn",
+			messages: []Message{
+				CreateTextMessage(RoleUser, "list /tmp"),
+				{Role: RoleAssistant, ToolCalls: []ToolCall{
+					{ID: "toolu_1", Type: "function", Function: ToolFunction{Name: "list_directory", Arguments: `{"path":"/tmp"}`}},
+					{ID: "toolu_2", Type: "function", Function: ToolFunction{Name: "read_file", Arguments: ""}},
+				}},
+				{Role: RoleTool, ToolCallID: "toolu_1", Content: TextContent("a.txt")},
+				{Role: RoleTool, ToolCallID: "toolu_2", Content: TextContent("Error: denied"), IsError: true},
+				CreateTextMessage(RoleUser, "thanks"),
+			},
+			expectMessages: []anthropicMessage{
+				{Role: RoleUser, Content: []anthropicBlock{{Type: "text", Text: "list /tmp"}}},
+				{Role: RoleAssistant, Content: []anthropicBlock{
+					{Type: "tool_use", ID: "toolu_1", Name: "list_directory", Input: json.RawMessage(`{"path":"/tmp"}`)},
+					{Type: "tool_use", ID: "toolu_2", Name: "read_file", Input: json.RawMessage(`{}`)},
+				}},
+				{Role: RoleUser, Content: []anthropicBlock{
+					{Type: "tool_result", ToolUseID: "toolu_1", Content: "a.txt"},
+					{Type: "tool_result", ToolUseID: "toolu_2", Content: "Error: denied", IsError: true},
+					{Type: "text", Text: "thanks"},
+				}},
+			},
+		},
+		{
+			name: "Images become sources",
+			messages: []Message{
+				CreateComplexMessage(RoleUser, []ContentItem{
+					{Type: ContentTypeText, Text: "compare"},
+					{Type: ContentTypeImageURL, ImageURL: &ImageURL{URL: "data:image/png;base64,iVBORw0KGgo="}},
+					{Type: ContentTypeImageURL, ImageURL: &ImageURL{URL: "https://example.com/cat.jpg"}},
+				}),
+			},
+			expectMessages: []anthropicMessage{
+				{Role: RoleUser, Content: []anthropicBlock{
+					{Type: "text", Text: "compare"},
+					{Type: "image", Source: &anthropicImageSource{Type: "base64", MediaType: "image/png", Data: "iVBORw0KGgo="}},
+					{Type: "image", Source: &anthropicImageSource{Type: "url", URL: "https://example.com/cat.jpg"}},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := toAnthropicRequest(ChatCompletionRequest{Model: "claude", Messages: tt.messages})
+			if out.System != tt.expectSystem {
+				t.Errorf("Expected system %q, got %q", tt.expectSystem, out.System)
+			}
+			if out.MaxTokens != DefaultMaxTokens {
+				t.Errorf("Expected the default max tokens, got %d", out.MaxTokens)
+			}
+			if !reflect.DeepEqual(out.Messages, tt.expectMessages) {
+				got, _ := json.Marshal(out.Messages)
+				want, _ := json.Marshal(tt.expectMessages)
+				t.Errorf("Expected messages\n%s\ngot\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestToAnthropicToolChoice(t *testing.T) {
+	tools := []Tool{{Type: "function", Function: ToolSchema{Name: "lookup", Description: "Looks things up"}}}
+	parallel := false
+
+	tests := []struct {
+		name     string
+		choice   interface{}
+		parallel *bool
+		expected *anthropicToolChoice
+	}{
+		{name: "Unset", choice: nil, expected: nil},
+		{name: "Auto", choice: "auto", expected: &anthropicToolChoice{Type: "auto"}},
+		{name: "None", choice: "none", expected: &anthropicToolChoice{Type: "none"}},
+		{name: "Required", choice: "required", expected: &anthropicToolChoice{Type: "any"}},
+		{
+			name:     "Named function",
+			choice:   map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "lookup"}},
+			expected: &anthropicToolChoice{Type: "tool", Name: "lookup"},
+		},
+		{name: "Parallel calls disabled", choice: nil, parallel: &parallel, expected: &anthropicToolChoice{Type: "auto", DisableParallelToolUse: true}},
+		{name: "None ignores parallel", choice: "none", parallel: &parallel, expected: &anthropicToolChoice{Type: "none"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := ChatCompletionRequest{
+				Model:      "claude",
+				Messages:   []Message{CreateTextMessage(RoleUser, "hi")},
+				Tools:      tools,
+				ToolChoice: tt.choice,
+			}
+			req.ParallelToolCalls = tt.parallel
+			out := toAnthropicRequest(req)
+			if !reflect.DeepEqual(out.ToolChoice, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, out.ToolChoice)
+			}
+			if len(out.Tools) != 1 || out.Tools[0].Name != "lookup" || out.Tools[0].InputSchema["type"] != "object" {
+				t.Errorf("Expected the tool with an empty object schema, got %+v", out.Tools)
+			}
+		})
+	}
+
+	// without tools there is nothing to choose
+	if out := toAnthropicRequest(ChatCompletionRequest{ToolChoice: "required"}); out.ToolChoice != nil {
+		t.Errorf("Expected no tool choice without tools, got %+v", out.ToolChoice)
+	}
+}
+
+func TestAnthropicStreamTranscripts(t *testing.T) {
+	tests := []struct {
+		name          string
+		transcript    string
+		expectKind    APIErrorKind
+		expectChunks  string
+		expectContent string
+		expectFinish  string
+		expectedCalls []ToolCall
+		expectUsage   Usage
+	}{
+		{
+			name:          "Text then tool use",
+			transcript:    "anthropic_tool_use.sse",
+			expectChunks:  "Let me check.",
+			expectContent: "Let me check.",
+			expectFinish:  "tool_calls",
+			expectedCalls: []ToolCall{
+				{ID: "toolu_01", Type: "function", Function: ToolFunction{Name: "list_directory", Arguments: `{"directory_path": "/tmp"}`}},
+			},
+			expectUsage: Usage{
+				PromptTokens:        220,
+				CompletionTokens:    35,
+				TotalTokens:         255,
+				PromptTokensDetails: &PromptTokensDetails{CachedTokens: 100},
+			},
+		},
+		{
+			name:         "Error event mid stream",
+			transcript:   "anthropic_overloaded.sse",
+			expectKind:   APIErrorOverloaded,
+			expectChunks: "Partial",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, chunks, err := decodeProviderTranscript(t, NewAnthropicProvider(), tt.transcript)
+
+			var streamed string
+			for _, chunk := range chunks {
+				streamed += chunk.Content
+			}
+			if streamed != tt.expectChunks {
+				t.Errorf("Expected streamed %q, got %q", tt.expectChunks, streamed)
+			}
+
+			if tt.expectKind != "" {
+				var apiErr *APIError
+				if !errors.Is(err, ErrReadStream) || !errors.As(err, &apiErr) || apiErr.Kind != tt.expectKind {
+					t.Fatalf("Expected a %s stream error, got %v", tt.expectKind, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			choice := response.Choices[0]
+			if response.ID != "msg_01" || response.Model != "claude-sonnet-4-20250514" {
+				t.Errorf("Expected metadata from message_start, got id=%q model=%q", response.ID, response.Model)
+			}
+			if choice.Message.Text() != tt.expectContent || choice.FinishReason != tt.expectFinish {
+				t.Errorf("Unexpected choice: %+v", choice)
+			}
+			if !reflect.DeepEqual(choice.Message.ToolCalls, tt.expectedCalls) {
+				t.Errorf("Expected tool calls %+v, got %+v", tt.expectedCalls, choice.Message.ToolCalls)
+			}
+			if !reflect.DeepEqual(response.Usage, tt.expectUsage) {
+				t.Errorf("Expected usage %+v, got %+v", tt.expectUsage, response.Usage)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+// Package beautest provides a fake chat completions server for testing code
+// built on beau without a real provider. Replies are scripted in order and are
+// rendered as plain JSON or as an SSE stream depending on the request.
+package beautest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bosley/beau"
+)
+
+// Call is a tool call the fake model makes
+type Call struct {
+	Name      string
+	Arguments interface{} // marshaled to JSON, a string is used as is
+}
+
+// Reply is one scripted response, build them with Text, Chunks, ToolCall,
+// ToolCalls, RateLimited and ServerError
+type Reply struct {
+	status     int
+	retryAfter time.Duration
+	message    string
+	chunks     []string
+	calls      []Call
+	usage      *beau.Usage
+}
+
+// Text replies with content
+func Text(content string) Reply {
+	return Reply{status: http.StatusOK, chunks: []string{content}}
+}
+
+// Chunks replies with content split into the given stream chunks. Non streaming
+// requests receive the chunks joined.
+func Chunks(chunks ...string) Reply {
+	return Reply{status: http.StatusOK, chunks: chunks}
+}
+
+// ToolCall replies with a single tool call
+func ToolCall(name string, arguments interface{}) Reply {
+	return ToolCalls(Call{Name: name, Arguments: arguments})
+}
+
+// ToolCalls replies with parallel tool calls
+func ToolCalls(calls ...Call) Reply {
+	return Reply{status: http.StatusOK, calls: calls}
+}
+
+// RateLimited replies 429 with a Retry-After header
+func RateLimited(retryAfter time.Duration) Reply {
+	return Reply{status: http.StatusTooManyRequests, retryAfter: retryAfter, message: "rate limit exceeded"}
+}
+
+// ServerError replies with the given status code and error message
+func ServerError(status int, message string) Reply {
+	return Reply{status: status, message: message}
+}
+
+// WithUsage attaches token usage to a reply
+func (r Reply) WithUsage(promptTokens int, completionTokens int) Reply {
+	r.usage = &beau.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+	return r
+}
+
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	replies  []Reply
+	requests []beau.ChatCompletionRequest
+	nextCall int
+}
+
+// NewServer starts a server answering on /v1/chat/completions. Close it when done.
+func NewServer(replies ...Reply) *Server {
+	s := &Server{replies: replies}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Enqueue adds replies to the end of the script
+func (s *Server) Enqueue(replies ...Reply) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, replies...)
+	return s
+}
+
+// Requests returns every request received so far, in order
+func (s *Server) Requests() []beau.ChatCompletionRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]beau.ChatCompletionRequest{}, s.requests...)
+}
+
+// Pending returns the number of scripted replies not yet served
+func (s *Server) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.replies)
+}
+
+// AssertRequestCount fails the test unless exactly n requests were received
+func (s *Server) AssertRequestCount(t testing.TB, n int) {
+	t.Helper()
+	if got := len(s.Requests()); got != n {
+		t.Errorf("Expected %d requests, got %d", n, got)
+	}
+}
+
+// AssertMessage fails the test unless request number index (0 based) contains
+// a message with the given role whose text contains substr
+func (s *Server) AssertMessage(t testing.TB, index int, role beau.MessageRole, substr string) {
+	t.Helper()
+	requests := s.Requests()
+	if index >= len(requests) {
+		t.Errorf("Expected request %d, only %d received", index, len(requests))
+		return
+	}
+	for _, msg := range requests[index].Messages {
+		if msg.Role == role && strings.Contains(msg.Text(), substr) {
+			return
+		}
+	}
+	t.Errorf("Request %d has no %s message containing %q", index, role, substr)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	var req beau.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	if len(s.replies) == 0 {
+		s.mu.Unlock()
+		writeError(w, http.StatusInternalServerError, "beautest: no scripted reply left")
+		return
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	toolCalls := s.toolCalls(reply.calls)
+	s.mu.Unlock()
+
+	if reply.status != http.StatusOK {
+		if reply.retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(reply.retryAfter.Seconds()))))
+		}
+		writeError(w, reply.status, reply.message)
+		return
+	}
+
+	if req.Stream {
+		writeStream(w, req, reply, toolCalls)
+		return
+	}
+
+	finishReason := "stop"
+	if len(toolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+	response := beau.ChatCompletionResponse{
+		ID:     "chatcmpl-beautest",
+		Object: "chat.completion",
+		Model:  req.Model,
+		Choices: []beau.Choice{
+			{
+				Message: beau.Message{
+					Role:      beau.RoleAssistant,
+					Content:   beau.TextContent(strings.Join(reply.chunks, "")),
+					ToolCalls: toolCalls,
+				},
+				FinishReason: finishReason,
+			},
+		},
+	}
+	if reply.usage != nil {
+		response.Usage = *reply.usage
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// toolCalls assigns ids unique to the server, the caller must hold the lock
+func (s *Server) toolCalls(calls []Call) []beau.ToolCall {
+	var out []beau.ToolCall
+	for _, call := range calls {
+		s.nextCall++
+		arguments, ok := call.Arguments.(string)
+		if !ok {
+			data, _ := json.Marshal(call.Arguments)
+			arguments = string(data)
+		}
+		out = append(out, beau.ToolCall{
+			ID:   fmt.Sprintf("call_%d", s.nextCall),
+			Type: "function",
+			Function: beau.ToolFunction{
+				Name:      call.Name,
+				Arguments: arguments,
+			},
+		})
+	}
+	return out
+}
+
+func writeStream(w http.ResponseWriter, req beau.ChatCompletionRequest, reply Reply, toolCalls []beau.ToolCall) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(chunk map[string]interface{}) {
+		chunk["id"] = "chatcmpl-beautest"
+		chunk["object"] = "chat.completion.chunk"
+		chunk["model"] = req.Model
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	delta := func(d map[string]interface{}, finishReason interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"choices": []interface{}{
+				map[string]interface{}{"index": 0, "delta": d, "finish_reason": finishReason},
+			},
+		}
+	}
+
+	send(delta(map[string]interface{}{"role": "assistant"}, nil))
+	for _, content := range reply.chunks {
+		send(delta(map[string]interface{}{"content": content}, nil))
+	}
+
+	// arguments are split across two deltas the way real providers stream them
+	for i, call := range toolCalls {
+		half := len(call.Function.Arguments) / 2
+		send(delta(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+			"index": i,
+			"id":    call.ID,
+			"type":  call.Type,
+			"function": map[string]interface{}{
+				"name":      call.Function.Name,
+				"arguments": call.Function.Arguments[:half],
+			},
+		}}}, nil))
+		send(delta(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+			"index":    i,
+			"function": map[string]interface{}{"arguments": call.Function.Arguments[half:]},
+		}}}, nil))
+	}
+
+	finishReason := "stop"
+	if len(toolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+	send(delta(map[string]interface{}{}, finishReason))
+
+	if reply.usage != nil && req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		send(map[string]interface{}{"choices": []interface{}{}, "usage": reply.usage})
+	}
+
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    "beautest_error",
+		},
+	})
+}
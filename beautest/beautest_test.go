@@ -0,0 +1,138 @@
+package beautest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bosley/beau"
+)
+
+// Helper function to create a client pointed at the fake server
+func newTestClient(t *testing.T, server *Server) *beau.Client {
+	client, err := beau.NewClient("test-key", server.URL, nil, nil, beau.RetryConfig{
+		MaxRetries:    2,
+		InitialDelay:  time.Millisecond,
+		MaxDelay:      10 * time.Millisecond,
+		BackoffFactor: 2,
+		Enabled:       true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client
+}
+
+func TestServerReplies(t *testing.T) {
+	messages := []beau.Message{beau.CreateTextMessage(beau.RoleUser, "hello")}
+
+	tests := []struct {
+		name             string
+		replies          []Reply
+		stream           bool
+		expectError      error
+		expectedContent  string
+		expectedTool     string
+		expectedArgs     string
+		expectedTokens   int
+		expectedRequests int
+	}{
+		{
+			name:             "Plain text",
+			replies:          []Reply{Text("hi there")},
+			expectedContent:  "hi there",
+			expectedRequests: 1,
+		},
+		{
+			name:             "Streamed chunks with usage",
+			replies:          []Reply{Chunks("hi ", "there").WithUsage(5, 2)},
+			stream:           true,
+			expectedContent:  "hi there",
+			expectedTokens:   7,
+			expectedRequests: 1,
+		},
+		{
+			name:             "Streamed tool call",
+			replies:          []Reply{ToolCall("read_file", map[string]string{"file_path": "/tmp/a"})},
+			stream:           true,
+			expectedTool:     "read_file",
+			expectedArgs:     `{"file_path":"/tmp/a"}`,
+			expectedRequests: 1,
+		},
+		{
+			name:             "Rate limited then text",
+			replies:          []Reply{RateLimited(time.Second), Text("after wait")},
+			expectedContent:  "after wait",
+			expectedRequests: 2,
+		},
+		{
+			name:             "Server error then text",
+			replies:          []Reply{ServerError(http.StatusBadGateway, "bad gateway"), Text("recovered")},
+			expectedContent:  "recovered",
+			expectedRequests: 2,
+		},
+		{
+			name:             "Server error until retries run out",
+			replies:          []Reply{ServerError(529, "overloaded"), ServerError(529, "overloaded"), ServerError(529, "overloaded")},
+			expectError:      beau.ErrOverloaded,
+			expectedRequests: 3,
+		},
+		{
+			name:             "Client error is not retried",
+			replies:          []Reply{ServerError(http.StatusUnauthorized, "invalid api key")},
+			expectError:      beau.ErrAuthentication,
+			expectedRequests: 1,
+		},
+		{
+			name:             "Script exhausted",
+			expectError:      beau.ErrUnexpectedStatusCode,
+			expectedRequests: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(tt.replies...)
+			defer server.Close()
+			client := newTestClient(t, server)
+
+			var opts []beau.RequestOption
+			if tt.stream {
+				opts = append(opts, beau.WithStream(make(chan beau.StreamChunk, 32)))
+			}
+
+			response, err := client.Send(context.Background(), 0.7, 100, messages, "test-model", opts...)
+			server.AssertRequestCount(t, tt.expectedRequests)
+			if tt.expectError != nil {
+				if !errors.Is(err, tt.expectError) {
+					t.Errorf("Expected error %v, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			server.AssertMessage(t, 0, beau.RoleUser, "hello")
+
+			message := response.Choices[0].Message
+			if content := message.Text(); content != tt.expectedContent {
+				t.Errorf("Expected content %q, got %q", tt.expectedContent, content)
+			}
+			if tt.expectedTool != "" {
+				if len(message.ToolCalls) != 1 {
+					t.Fatalf("Expected 1 tool call, got %d", len(message.ToolCalls))
+				}
+				call := message.ToolCalls[0]
+				if call.Function.Name != tt.expectedTool || call.Function.Arguments != tt.expectedArgs {
+					t.Errorf("Unexpected tool call: %+v", call)
+				}
+			}
+			if response.Usage.TotalTokens != tt.expectedTokens {
+				t.Errorf("Expected %d tokens, got %d", tt.expectedTokens, response.Usage.TotalTokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package beau
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCacheWrite = fmt.Errorf("failed to write cache entry")
+)
+
+// Cache stores encoded chat completion responses by request key. Values are
+// opaque bytes so backends never share memory with callers.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte) error
+}
+
+// CacheKey hashes the parts of a request that decide its response. Streaming
+// settings are left out so streamed and plain requests share entries.
+func CacheKey(provider string, baseURL string, req ChatCompletionRequest) string {
+	normalized := struct {
+		Provider       string          `json:"provider"`
+		BaseURL        string          `json:"base_url"`
+		Model          string          `json:"model"`
+		Messages       []Message       `json:"messages"`
+		MaxTokens      int             `json:"max_tokens"`
+		Temperature    float64         `json:"temperature"`
+		Tools          []Tool          `json:"tools"`
+		ToolChoice     interface{}     `json:"tool_choice"`
+		ResponseFormat *ResponseFormat `json:"response_format"`
+		Sampling       SamplingParams  `json:"sampling"`
+	}{
+		Provider:       provider,
+		BaseURL:        strings.TrimRight(baseURL, "/"),
+		Model:          req.Model,
+		Messages:       req.Messages,
+		MaxTokens:      req.MaxTokens,
+		Temperature:    req.Temperature,
+		Tools:          req.Tools,
+		ToolChoice:     req.ToolChoice,
+		ResponseFormat: req.ResponseFormat,
+		Sampling:       req.SamplingParams,
+	}
+
+	// maps marshal with sorted keys, so equal requests hash equally
+	data, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// MemoryCache keeps entries in process memory
+type MemoryCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]memoryEntry
+}
+
+var _ Cache = &MemoryCache{}
+
+// NewMemoryCache creates an in-memory cache, a ttl of zero never expires entries
+func NewMemoryCache(ttl time.Duration) *MemoryCache {
+	return &MemoryCache{
+		ttl:     ttl,
+		entries: map[string]memoryEntry{},
+	}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return append([]byte(nil), entry.value...), true
+}
+
+func (c *MemoryCache) Set(key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := memoryEntry{value: append([]byte{}, value...)}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	c.entries[key] = entry
+	return nil
+}
+
+// DiskCache keeps one file per entry under a directory, so entries survive
+// restarts. Expiry is judged from the file's modification time. Files are named
+// after a hash of the key, so any key is safe to use.
+type DiskCache struct {
+	dir string
+	ttl time.Duration
+}
+
+var _ Cache = &DiskCache{}
+
+// NewDiskCache creates a cache in dir, a ttl of zero never expires entries
+func NewDiskCache(dir string, ttl time.Duration) *DiskCache {
+	return &DiskCache{dir: dir, ttl: ttl}
+}
+
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name+".json")
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if c.ttl > 0 && time.Since(info.ModTime()) > c.ttl {
+		os.Remove(path)
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func (c *DiskCache) Set(key string, value []byte) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("%w: %v", ErrCacheWrite, err)
+	}
+
+	// write then rename so readers never see a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCacheWrite, err)
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("%w: %v", ErrCacheWrite, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("%w: %v", ErrCacheWrite, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("%w: %v", ErrCacheWrite, err)
+	}
+	return nil
+}
+
+// cachedResponse decodes a cache hit, nil when the entry is unusable. A hit
+// costs nothing, so its usage is zeroed and meters and budgets are not charged
+// again for the original request.
+func cachedResponse(data []byte) *ChatCompletionResponse {
+	var response ChatCompletionResponse
+	if err := json.Unmarshal(data, &response); err != nil || len(response.Choices) == 0 {
+		return nil
+	}
+	response.Usage = Usage{}
+	return &response
+}
+
+// replayStream feeds a cached response to a stream consumer in word sized
+// chunks, ending it the way a live stream ends
+func replayStream(response *ChatCompletionResponse, stream chan StreamChunk) {
+	content := response.Choices[0].Message.Content.Text()
+	for _, word := range strings.SplitAfter(content, " ") {
+		if word != "" {
+			stream <- StreamChunk{Content: word}
+		}
+	}
+	stream <- StreamChunk{Done: true}
+	close(stream)
+}
@@ -0,0 +1,181 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper function to create a client whose server counts the requests it answers
+func newCountingClient(t *testing.T, reply string) (*Client, *int) {
+	t.Helper()
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			data, _ := json.Marshal(map[string]interface{}{
+				"choices": []map[string]interface{}{{"delta": map[string]string{"content": reply}}},
+			})
+			w.Write([]byte("data: " + string(data) + "\n\ndata: [DONE]\n\n"))
+			return
+		}
+		json.NewEncoder(w).Encode(ChatCompletionResponse{
+			Choices: []Choice{{Message: CreateTextMessage(RoleAssistant, reply)}},
+			Usage:   Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient("test-key", server.URL, nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client, &count
+}
+
+func TestCacheBackends(t *testing.T) {
+	tests := []struct {
+		name  string
+		cache func(t *testing.T, ttl time.Duration) Cache
+	}{
+		{name: "Memory", cache: func(t *testing.T, ttl time.Duration) Cache { return NewMemoryCache(ttl) }},
+		{name: "Disk", cache: func(t *testing.T, ttl time.Duration) Cache { return NewDiskCache(t.TempDir(), ttl) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := tt.cache(t, time.Hour)
+			key := CacheKey("openai", "", ChatCompletionRequest{Model: "m"})
+
+			if _, ok := cache.Get(key); ok {
+				t.Fatal("Expected a miss on an empty cache")
+			}
+			if err := cache.Set(key, []byte("value")); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if data, ok := cache.Get(key); !ok || string(data) != "value" {
+				t.Errorf("Expected stored value, got %q (hit=%v)", data, ok)
+			}
+
+			// callers get their own copy
+			data, _ := cache.Get(key)
+			data[0] = 'X'
+			if data, _ := cache.Get(key); string(data) != "value" {
+				t.Errorf("Expected the entry unchanged by the caller, got %q", data)
+			}
+
+			// any string is a usable key
+			for _, short := range []string{"", "a", "../escape"} {
+				if err := cache.Set(short, []byte(short+"!")); err != nil {
+					t.Fatalf("Unexpected error for key %q: %v", short, err)
+				}
+				if data, ok := cache.Get(short); !ok || string(data) != short+"!" {
+					t.Errorf("Expected the value for key %q, got %q (hit=%v)", short, data, ok)
+				}
+			}
+
+			expiring := tt.cache(t, time.Millisecond)
+			expiring.Set(key, []byte("value"))
+			time.Sleep(10 * time.Millisecond)
+			if _, ok := expiring.Get(key); ok {
+				t.Error("Expected the entry to expire")
+			}
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	base := ChatCompletionRequest{
+		Model:       "m",
+		Messages:    []Message{CreateTextMessage(RoleUser, "hi")},
+		Temperature: 0.5,
+	}
+
+	streamed := base
+	streamed.Stream = true
+	if CacheKey("openai", "", base) != CacheKey("openai", "", streamed) {
+		t.Error("Expected streaming to share the cache key")
+	}
+
+	warmer := base
+	warmer.Temperature = 0.9
+	if CacheKey("openai", "", base) == CacheKey("openai", "", warmer) {
+		t.Error("Expected temperature to change the cache key")
+	}
+
+	if CacheKey("openai", "", base) == CacheKey("anthropic", "", base) {
+		t.Error("Expected the provider to change the cache key")
+	}
+
+	failed := base
+	failed.Messages = []Message{{Role: RoleTool, ToolCallID: "call_1", Content: TextContent("boom"), IsError: true}}
+	succeeded := base
+	succeeded.Messages = []Message{{Role: RoleTool, ToolCallID: "call_1", Content: TextContent("boom")}}
+	if CacheKey("anthropic", "", failed) == CacheKey("anthropic", "", succeeded) {
+		t.Error("Expected a failed tool result to change the cache key")
+	}
+}
+
+func TestClientCache(t *testing.T) {
+	client, count := newCountingClient(t, "cached reply text")
+	client.WithCache(NewMemoryCache(0))
+
+	messages := []Message{CreateTextMessage(RoleUser, "hello")}
+	for i := 0; i < 2; i++ {
+		response, err := client.Send(context.Background(), 0, 100, messages, "test-model")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Choices[0].Message.Content.Text() != "cached reply text" {
+			t.Errorf("Unexpected content: %q", response.Choices[0].Message.Text())
+		}
+		// only the request that reached the server used tokens
+		hit := i == 1
+		if response.Target.Cached != hit || (response.Usage.TotalTokens == 0) != hit {
+			t.Errorf("Request %d: expected cached=%v, got %+v with usage %+v", i, hit, response.Target, response.Usage)
+		}
+	}
+	if *count != 1 {
+		t.Errorf("Expected 1 request, got %d", *count)
+	}
+	if spent := client.Spend().Usage.TotalTokens; spent != 15 {
+		t.Errorf("Expected the hit to cost nothing, got %d tokens spent", spent)
+	}
+
+	// a streaming consumer gets the cached reply as chunks ending with Done
+	stream := make(chan StreamChunk, 16)
+	if _, err := client.Send(context.Background(), 0, 100, messages, "test-model", WithStream(stream)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var text strings.Builder
+	var chunks int
+	done := false
+	for chunk := range stream {
+		if chunk.Done {
+			done = true
+			continue
+		}
+		chunks++
+		text.WriteString(chunk.Content)
+	}
+	if *count != 1 {
+		t.Errorf("Expected the streamed request to hit the cache, got %d requests", *count)
+	}
+	if !done || text.String() != "cached reply text" || chunks < 2 {
+		t.Errorf("Unexpected replay: %q in %d chunks (done=%v)", text.String(), chunks, done)
+	}
+
+	if _, err := client.Send(context.Background(), 0.7, 100, messages, "test-model"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if *count != 2 {
+		t.Errorf("Expected a changed temperature to miss, got %d requests", *count)
+	}
+}
@@ -3,17 +3,20 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 
 	"github.com/bosley/beau"
 	"github.com/bosley/beau/agent"
+	"github.com/bosley/beau/tracing"
 	"github.com/fatih/color"
 )
 
@@ -57,6 +60,14 @@ func (o *CliObserver) OnError(err error) error {
 	defer o.mu.Unlock()
 
 	color.Red("\n❌ Error: %v\n", err)
+	switch {
+	case errors.Is(err, beau.ErrAuthentication):
+		color.Yellow("Check that the API key is valid for this provider")
+	case errors.Is(err, beau.ErrQuotaExceeded):
+		color.Yellow("The account has run out of quota or credit")
+	case errors.Is(err, beau.ErrContextLength):
+		color.Yellow("The conversation is too long for the model, type 'reset' to start over")
+	}
 	// Don't block on channel if it's already been used
 	select {
 	case o.complete <- true:
@@ -82,7 +93,7 @@ func (o *CliObserver) OnComplete(message beau.Message) error {
 		}
 	} else {
 		// Check if there's content to display
-		if content, ok := message.Content.(string); ok && content != "" {
+		if message.Text() != "" {
 			// Content was already streamed, just add newline
 			fmt.Println()
 		} else {
@@ -104,8 +115,18 @@ func (o *CliObserver) OnUsage(usage agent.UsageStats) error {
 	defer o.mu.Unlock()
 
 	// Display usage statistics
-	color.HiBlack("\n📊 Usage: %d bytes sent | ~%d tokens | Model: %s\n",
-		usage.MessageSizeBytes, usage.TokensUsed, usage.Model)
+	if usage.Estimated {
+		color.HiBlack("\n📊 Usage: %d bytes sent | ~%d tokens (estimated) | Model: %s\n",
+			usage.MessageSizeBytes, usage.TokensUsed, usage.Model)
+		return nil
+	}
+	color.HiBlack("\n📊 Usage: %d prompt + %d completion = %d tokens | Model: %s\n",
+		usage.PromptTokens, usage.CompletionTokens, usage.TokensUsed, usage.Model)
+	color.HiBlack("💰 Cost: $%.4f this turn ($%.4f in mages) | $%.4f this session\n",
+		usage.Cost, usage.MageCost, usage.SessionCost)
+	if usage.Redactions > 0 {
+		color.HiBlack("🔒 %d secrets redacted this session\n", usage.Redactions)
+	}
 	return nil
 }
 
@@ -113,6 +134,87 @@ func (o *CliObserver) WaitForComplete() {
 	<-o.complete
 }
 
+// printHistory prints the conversation tree, marking the current checkpoint and
+// indenting where it branches
+func printHistory(node *beau.HistoryNode, current int, depth int) {
+	if node.Parent != nil {
+		text := strings.ReplaceAll(node.Message.Text(), "\n", " ")
+		if len(node.Message.ToolCalls) > 0 {
+			text = fmt.Sprintf("[%d tool calls]", len(node.Message.ToolCalls))
+		}
+		if runes := []rune(text); len(runes) > 60 {
+			text = string(runes[:60]) + "..."
+		}
+		marker := " "
+		if node.ID == current {
+			marker = "*"
+		}
+		color.HiBlack("%s%s %d %s: %s", strings.Repeat("  ", depth), marker, node.ID, node.Message.Role, text)
+	}
+	if len(node.Children) > 1 {
+		depth++
+	}
+	for _, child := range node.Children {
+		printHistory(child, current, depth)
+	}
+}
+
+// providerDefaults returns the base url, default model, key and provider for a
+// provider name given on the command line
+func providerDefaults(provider string) (string, string, string, beau.Provider, bool) {
+	switch provider {
+	case "openai":
+		return beau.DefaultBaseURL_OpenAI, beau.DefaultModel_OpenAI, os.Getenv("OPENAI_API_KEY"), nil, true
+	case "anthropic":
+		return beau.DefaultBaseURL_Claude, beau.DefaultModel_Claude, os.Getenv("ANTHROPIC_API_KEY"), nil, true
+	case "xai":
+		return beau.DefaultBaseURL_XAI, beau.DefaultModel_XAI, os.Getenv("XAI_API_KEY"), nil, true
+	case "local":
+		baseURL := beau.DefaultBaseURL_Local
+		if host := os.Getenv("OLLAMA_HOST"); host != "" {
+			baseURL = host
+			if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+				baseURL = "http://" + baseURL
+			}
+		}
+		return baseURL, beau.DefaultModel_Local, "", beau.NewOllamaProvider(), true
+	}
+	return "", "", "", nil, false
+}
+
+// parseFallback builds a fallback policy from entries like "grok-3" (same
+// provider) or "openai:gpt-4o" (another provider with its key from the environment)
+func parseFallback(value string) (beau.FallbackPolicy, error) {
+	var policy beau.FallbackPolicy
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		// ollama tags also use a colon, only known provider names are split off
+		name, model, found := strings.Cut(entry, ":")
+		baseURL, defaultModel, apiKey, provider, ok := providerDefaults(name)
+		if !found || !ok {
+			policy.Targets = append(policy.Targets, beau.FallbackTarget{Model: entry})
+			continue
+		}
+		if apiKey == "" && name != "local" {
+			return policy, fmt.Errorf("no API key found for fallback provider %s", name)
+		}
+		if model == "" {
+			model = defaultModel
+		}
+		policy.Targets = append(policy.Targets, beau.FallbackTarget{
+			Provider: provider,
+			BaseURL:  baseURL,
+			APIKey:   apiKey,
+			Model:    model,
+		})
+	}
+	return policy, nil
+}
+
 func main() {
 
 	var dir string
@@ -120,12 +222,31 @@ func main() {
 	var debug bool
 	var temperature float64
 	var maxTokens int
-
-	flag.StringVar(&provider, "provider", "xai", "The provider to use")
+	var modelOverride string
+	var baseURLOverride string
+	var requestsPerMinute int
+	var tokensPerMinute int
+	var sessionPath string
+	var contextBudget int
+	var fallback string
+	var budget float64
+	var tracePath string
+
+	flag.StringVar(&provider, "provider", "xai", "The provider to use (xai, openai, anthropic, local)")
+	flag.StringVar(&modelOverride, "model", "", "The model to use (defaults to the provider's default)")
+	flag.StringVar(&baseURLOverride, "base-url", "", "The base URL to use (defaults to the provider's default)")
 	flag.StringVar(&dir, "dir", "", "The directory to use")
 	flag.BoolVar(&debug, "debug", false, "Enable debug logging")
 	flag.Float64Var(&temperature, "temp", 0.7, "Temperature for generation (0.0-2.0)")
 	flag.IntVar(&maxTokens, "max-tokens", 8192, "Maximum tokens for generation")
+	flag.IntVar(&requestsPerMinute, "rpm", 0, "Requests per minute budget shared by the agent and its mages (0 for unlimited)")
+	flag.IntVar(&tokensPerMinute, "tpm", 0, "Tokens per minute budget shared by the agent and its mages (0 for unlimited)")
+	flag.StringVar(&sessionPath, "session", "", "JSONL file to log the conversation to and resume it from")
+	flag.IntVar(&contextBudget, "context-budget", 0, "Estimated tokens of history sent per request, older turns are summarized past it (0 sends everything)")
+
+	flag.StringVar(&fallback, "fallback", "", "Comma separated models to fall back to when the provider fails, as model or provider:model")
+	flag.Float64Var(&budget, "budget", 0, "Stop once the session has spent this many USD, including its mages (0 for unlimited)")
+	flag.StringVar(&tracePath, "trace", "", "JSONL file to append spans of turns, requests, mage executions and tool calls to")
 
 	flag.Parse()
 
@@ -139,28 +260,30 @@ func main() {
 		Level: logLevel,
 	}))
 
-	var model string
-	var baseURL string
-	var apiKey string
-
-	switch provider {
-	case "openai":
-		baseURL = beau.DefaultBaseURL_OpenAI
-		model = beau.DefaultModel_OpenAI
-		apiKey = os.Getenv("OPENAI_API_KEY")
-	case "anthropic":
-		baseURL = beau.DefaultBaseURL_Claude
-		model = beau.DefaultModel_Claude
-		apiKey = os.Getenv("ANTHROPIC_API_KEY")
-	case "xai":
-		baseURL = beau.DefaultBaseURL_XAI
-		model = beau.DefaultModel_XAI
-		apiKey = os.Getenv("XAI_API_KEY")
-	default:
+	baseURL, model, apiKey, llmProvider, ok := providerDefaults(provider)
+	if !ok {
 		color.Red("❌ Invalid provider: %s", provider)
 		os.Exit(1)
 	}
 
+	if modelOverride != "" {
+		model = modelOverride
+	}
+	if baseURLOverride != "" {
+		baseURL = baseURLOverride
+	}
+
+	if apiKey == "" && provider != "local" {
+		color.Red("❌ No API key found. Please set OPENAI_API_KEY, ANTHROPIC_API_KEY, or XAI_API_KEY")
+		os.Exit(1)
+	}
+
+	// never print any part of the key, only whether there is one
+	keyStatus := "(none)"
+	if apiKey != "" {
+		keyStatus = "set"
+	}
+
 	fmt.Printf(`
 	Provider: %s
 	Model: %s
@@ -171,14 +294,9 @@ func main() {
 		color.HiCyanString(provider),
 		color.HiYellowString(model),
 		color.HiGreenString(baseURL),
-		color.HiRedString(apiKey[:10]+"..."),
+		color.HiRedString(keyStatus),
 	)
 
-	if apiKey == "" {
-		color.Red("❌ No API key found. Please set OPENAI_API_KEY, ANTHROPIC_API_KEY, or XAI_API_KEY")
-		os.Exit(1)
-	}
-
 	var err error
 	if dir == "" {
 		dir, err = os.Getwd()
@@ -191,16 +309,72 @@ func main() {
 	// Create observer
 	observer := NewCliObserver()
 
+	var rateLimiter *beau.RateLimiter
+	if requestsPerMinute > 0 || tokensPerMinute > 0 {
+		rateLimiter = beau.NewRateLimiter(requestsPerMinute, tokensPerMinute)
+	}
+
+	var spendBudget *beau.Budget
+	if budget > 0 {
+		spendBudget = beau.NewBudget(budget)
+	}
+
+	fallbackPolicy, err := parseFallback(fallback)
+	if err != nil {
+		color.Red("❌ %v", err)
+		os.Exit(1)
+	}
+
+	var tracer *tracing.Tracer
+	if tracePath != "" {
+		exporter, err := tracing.NewJSONLExporter(tracePath)
+		if err != nil {
+			color.Red("❌ Failed to open trace file: %v", err)
+			os.Exit(1)
+		}
+		tracer = tracing.NewTracer(exporter)
+		tracer.OnError = func(err error) {
+			logger.Warn("Failed to record span", "error", err)
+		}
+	}
+
+	// Exporters may hold spans until they are closed, so every exit from here
+	// on closes the tracer first. os.Exit skips deferred calls.
+	var closeOnce sync.Once
+	closeTracer := func() {
+		closeOnce.Do(func() {
+			if tracer == nil {
+				return
+			}
+			if err := tracer.Close(); err != nil {
+				color.Red("❌ Failed to write traces: %v", err)
+			}
+		})
+	}
+	defer closeTracer()
+
+	// Keep the key and anything else that looks like a credential out of logs,
+	// tool results and requests
+	redactor := beau.NewRedactor().AddSecret("api_key", apiKey).AddSecretsFromEnv()
+
 	// Configure the agent
 	config := agent.Config{
-		Logger:      logger,
-		Observer:    observer,
-		APIKey:      apiKey,
-		BaseURL:     baseURL,
-		Model:       model,
-		RetryConfig: beau.DefaultRetryConfig(),
-		Temperature: temperature,
-		MaxTokens:   maxTokens,
+		Logger:        logger,
+		Observer:      observer,
+		APIKey:        apiKey,
+		BaseURL:       baseURL,
+		Model:         model,
+		RetryConfig:   beau.DefaultRetryConfig(),
+		Provider:      llmProvider,
+		RateLimiter:   rateLimiter,
+		Fallback:      fallbackPolicy,
+		Budget:        spendBudget,
+		Tracer:        tracer,
+		Redactor:      redactor,
+		Temperature:   temperature,
+		MaxTokens:     maxTokens,
+		SessionPath:   sessionPath,
+		ContextBudget: contextBudget,
 
 		// Restrict file operations to current directory
 		ProjectBounds: []beau.ProjectBounds{
@@ -216,12 +390,14 @@ func main() {
 	ag, err := agent.NewAgent(config)
 	if err != nil {
 		color.Red("❌ Failed to create agent: %v", err)
+		closeTracer()
 		os.Exit(1)
 	}
 
 	ctx := context.Background()
 	if err := ag.Start(ctx); err != nil {
 		color.Red("❌ Failed to start agent: %v", err)
+		closeTracer()
 		os.Exit(1)
 	}
 
@@ -240,6 +416,7 @@ func main() {
 	color.HiWhite("Commands:")
 	color.White("  • Type your message and press Enter")
 	color.White("  • Type 'reset' to start a new conversation")
+	color.White("  • Type 'checkpoint', 'rollback <id>' or 'history' to rewind and branch the conversation")
 	color.White("  • Type 'exit' or 'quit' to leave")
 	color.White("  • Press Ctrl+C during generation to interrupt")
 	fmt.Println()
@@ -267,6 +444,7 @@ func main() {
 				} else {
 					// No active request, exit program
 					color.HiGreen("\n👋 Goodbye!\n")
+					closeTracer()
 					os.Exit(0)
 				}
 			}
@@ -287,7 +465,7 @@ func main() {
 		switch strings.ToLower(input) {
 		case "exit", "quit":
 			color.HiGreen("\n👋 Goodbye!")
-			os.Exit(0)
+			return
 		case "reset":
 			if err := ag.ResetConversation(); err != nil {
 				color.Red("❌ Failed to reset: %v", err)
@@ -295,10 +473,29 @@ func main() {
 				color.Green("✅ Conversation reset")
 			}
 			continue
+		case "checkpoint":
+			color.Green("📍 Checkpoint %d", ag.Checkpoint())
+			continue
+		case "history":
+			printHistory(ag.History(), ag.Checkpoint(), 0)
+			continue
 		case "":
 			continue
 		}
 
+		if fields := strings.Fields(input); len(fields) == 2 && strings.EqualFold(fields[0], "rollback") {
+			checkpoint, err := strconv.Atoi(fields[1])
+			if err == nil {
+				err = ag.Rollback(checkpoint)
+			}
+			if err != nil {
+				color.Red("❌ Failed to roll back: %v", err)
+			} else {
+				color.Green("✅ Rolled back to checkpoint %d", checkpoint)
+			}
+			continue
+		}
+
 		// Reset observer for new message
 		observer.Reset()
 
@@ -0,0 +1,63 @@
+package beau
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TextContent creates content holding a single text part
+func TextContent(text string) MessageContent {
+	return MessageContent{CreateTextItem(text)}
+}
+
+// Text returns the concatenated text parts of the content
+func (c MessageContent) Text() string {
+	var b strings.Builder
+	for _, item := range c {
+		if item.Type == ContentTypeText {
+			b.WriteString(item.Text)
+		}
+	}
+	return b.String()
+}
+
+// MarshalJSON encodes a single text part as a plain string, which every provider
+// accepts, and anything else as an array of parts
+func (c MessageContent) MarshalJSON() ([]byte, error) {
+	if c == nil {
+		return []byte("null"), nil
+	}
+	if len(c) == 1 && c[0].Type == ContentTypeText {
+		return json.Marshal(c[0].Text)
+	}
+	return json.Marshal([]ContentItem(c))
+}
+
+// UnmarshalJSON decodes a plain string or an array of parts
+func (c *MessageContent) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*c = nil
+	case len(data) > 0 && data[0] == '"':
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*c = TextContent(text)
+	default:
+		var items []ContentItem
+		if err := json.Unmarshal(data, &items); err != nil {
+			return fmt.Errorf("message content must be a string or an array of parts: %w", err)
+		}
+		*c = items
+	}
+	return nil
+}
+
+// Text returns the concatenated text parts of the message
+func (m Message) Text() string {
+	return m.Content.Text()
+}
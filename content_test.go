@@ -0,0 +1,63 @@
+package beau
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMessageContentJSON(t *testing.T) {
+	image := CreateImageURLItem("https://example.com/cat.png", "low")
+
+	tests := []struct {
+		name     string
+		content  MessageContent
+		expected string
+	}{
+		{name: "Single text part", content: TextContent("hello"), expected: `"hello"`},
+		{name: "Empty text", content: TextContent(""), expected: `""`},
+		{name: "No content", content: nil, expected: `null`},
+		{name: "Text and image", content: MessageContent{CreateTextItem("look"), image}, expected: `[{"type":"text","text":"look"},{"type":"image_url","image_url":{"url":"https://example.com/cat.png","detail":"low"}}]`},
+		{name: "Single image", content: MessageContent{image}, expected: `[{"type":"image_url","image_url":{"url":"https://example.com/cat.png","detail":"low"}}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.content)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, data)
+			}
+
+			var decoded MessageContent
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.content) {
+				t.Errorf("Expected %+v after round trip, got %+v", tt.content, decoded)
+			}
+		})
+	}
+
+	var invalid MessageContent
+	if err := json.Unmarshal([]byte(`42`), &invalid); err == nil {
+		t.Errorf("Expected an error decoding a number")
+	}
+}
+
+func TestMessageText(t *testing.T) {
+	var message Message
+	data := `{"role":"user","content":[{"type":"text","text":"what is "},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}},{"type":"text","text":"this?"}]}`
+	if err := json.Unmarshal([]byte(data), &message); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if message.Text() != "what is this?" {
+		t.Errorf("Expected the text parts joined, got %q", message.Text())
+	}
+	if len(message.Content) != 3 || message.Content[1].ImageURL == nil {
+		t.Errorf("Expected typed parts, got %+v", message.Content)
+	}
+}
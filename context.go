@@ -0,0 +1,385 @@
+package beau
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var (
+	ErrContextSummary = fmt.Errorf("failed to summarize conversation")
+)
+
+const (
+	// imageTokenEstimate is a rough cost of one image part, providers differ widely
+	imageTokenEstimate = 765
+
+	// messageTokenOverhead covers the role and framing of every message
+	messageTokenOverhead = 4
+
+	// elidedToolResult replaces tool results dropped to save context
+	elidedToolResult = "[tool result removed to save context]"
+
+	// summaryTranscriptLimit caps how much of one message is fed to the summarizer
+	summaryTranscriptLimit = 2000
+)
+
+// ContextPolicy decides what part of a conversation's history is sent with each
+// request. Policies always keep system messages and never separate a tool call
+// from its tool result. The history itself is left untouched.
+type ContextPolicy interface {
+	Apply(ctx context.Context, messages []Message) ([]Message, error)
+}
+
+// ClonablePolicy is a ContextPolicy that keeps state between requests, such as
+// a cached summary. Conversation.Fork gives the fork its own copy, so branches
+// never overwrite each other's state. Stateful policies must implement it.
+type ClonablePolicy interface {
+	ContextPolicy
+	Clone() ContextPolicy
+}
+
+// clonePolicy copies a stateful policy, stateless ones are shared as is
+func clonePolicy(policy ContextPolicy) ContextPolicy {
+	if clonable, ok := policy.(ClonablePolicy); ok {
+		return clonable.Clone()
+	}
+	return policy
+}
+
+// EstimateTokens roughly estimates the prompt tokens of messages at four
+// characters per token
+func EstimateTokens(messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += estimateMessageTokens(msg)
+	}
+	return total
+}
+
+func estimateMessageTokens(msg Message) int {
+	chars := len(msg.Name) + len(msg.ToolCallID)
+	images := 0
+	for _, item := range msg.Content {
+		switch item.Type {
+		case ContentTypeImageURL:
+			images++
+		default:
+			chars += len(item.Text)
+		}
+	}
+	for _, tc := range msg.ToolCalls {
+		chars += len(tc.ID) + len(tc.Function.Name) + len(tc.Function.Arguments)
+	}
+	return messageTokenOverhead + chars/4 + images*imageTokenEstimate
+}
+
+// contextTurns groups the indices of non system messages into turns, each
+// starting at a user message. Tool results follow their call within the same
+// turn, so dropping whole turns keeps them paired.
+func contextTurns(messages []Message) [][]int {
+	var turns [][]int
+	for i, msg := range messages {
+		if msg.Role == RoleSystem {
+			continue
+		}
+		if msg.Role == RoleUser || len(turns) == 0 {
+			turns = append(turns, []int{})
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], i)
+	}
+	return turns
+}
+
+// turnTokens sums the estimated tokens of the messages in a turn
+func turnTokens(messages []Message, turn []int) int {
+	total := 0
+	for _, i := range turn {
+		total += estimateMessageTokens(messages[i])
+	}
+	return total
+}
+
+// withoutTurns copies messages leaving out the given turns, inserting extra
+// where the first of them began
+func withoutTurns(messages []Message, turns [][]int, extra ...Message) []Message {
+	dropped := map[int]bool{}
+	first := -1
+	for _, turn := range turns {
+		for _, i := range turn {
+			dropped[i] = true
+			if first == -1 || i < first {
+				first = i
+			}
+		}
+	}
+
+	out := make([]Message, 0, len(messages)-len(dropped)+len(extra))
+	for i, msg := range messages {
+		if i == first {
+			out = append(out, extra...)
+		}
+		if !dropped[i] {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// oldestTurnsOver returns how many of the oldest turns must go to bring the
+// messages within maxTokens. The latest turn is always kept.
+func oldestTurnsOver(messages []Message, turns [][]int, maxTokens int) int {
+	total := EstimateTokens(messages)
+	count := 0
+	for count < len(turns)-1 && total > maxTokens {
+		total -= turnTokens(messages, turns[count])
+		count++
+	}
+	return count
+}
+
+type slidingWindowPolicy struct {
+	maxTokens int
+}
+
+// NewSlidingWindowPolicy drops the oldest turns until the history fits in
+// maxTokens. The latest turn is kept even when it alone is over budget.
+func NewSlidingWindowPolicy(maxTokens int) ContextPolicy {
+	return &slidingWindowPolicy{maxTokens: maxTokens}
+}
+
+func (p *slidingWindowPolicy) Apply(ctx context.Context, messages []Message) ([]Message, error) {
+	if EstimateTokens(messages) <= p.maxTokens {
+		return messages, nil
+	}
+	turns := contextTurns(messages)
+	return withoutTurns(messages, turns[:oldestTurnsOver(messages, turns, p.maxTokens)]), nil
+}
+
+type dropToolResultsPolicy struct {
+	maxTokens  int
+	keepRecent int
+}
+
+// NewDropToolResultsPolicy replaces the content of the oldest tool results with
+// a short note until the history fits in maxTokens. The keepRecent latest tool
+// results are never touched. The tool messages themselves stay, so every call
+// keeps its result.
+func NewDropToolResultsPolicy(maxTokens int, keepRecent int) ContextPolicy {
+	return &dropToolResultsPolicy{maxTokens: maxTokens, keepRecent: keepRecent}
+}
+
+func (p *dropToolResultsPolicy) Apply(ctx context.Context, messages []Message) ([]Message, error) {
+	total := EstimateTokens(messages)
+	if total <= p.maxTokens {
+		return messages, nil
+	}
+
+	var toolResults []int
+	for i, msg := range messages {
+		if msg.Role == RoleTool {
+			toolResults = append(toolResults, i)
+		}
+	}
+	if len(toolResults) <= p.keepRecent {
+		return messages, nil
+	}
+
+	out := append([]Message{}, messages...)
+	for _, i := range toolResults[:len(toolResults)-p.keepRecent] {
+		if total <= p.maxTokens {
+			break
+		}
+		before := estimateMessageTokens(out[i])
+		out[i].Content = TextContent(elidedToolResult)
+		total -= before - estimateMessageTokens(out[i])
+	}
+	return out, nil
+}
+
+type summarizePolicy struct {
+	client    *Client
+	model     string
+	maxTokens int
+
+	// the summary of the oldest turns is reused while those turns are unchanged
+	mu          sync.Mutex
+	summarized  int
+	fingerprint string
+	summary     string
+}
+
+// NewSummarizePolicy replaces the oldest turns with a summary written by model
+// once the history no longer fits in maxTokens, keeping the latest turns that
+// fit in half the budget. Earlier summaries are extended rather than redone. If
+// summarizing fails the oldest turns are dropped instead.
+func NewSummarizePolicy(client *Client, model string, maxTokens int) ContextPolicy {
+	return &summarizePolicy{client: client, model: model, maxTokens: maxTokens}
+}
+
+func (p *summarizePolicy) Apply(ctx context.Context, messages []Message) ([]Message, error) {
+	if EstimateTokens(messages) <= p.maxTokens {
+		return messages, nil
+	}
+
+	// the system prompt alone can be over budget, then there is nothing to summarize
+	turns := contextTurns(messages)
+	if len(turns) == 0 {
+		return messages, nil
+	}
+
+	// keep the latest turns that fit in half the budget, and at least one
+	keep := 1
+	kept := turnTokens(messages, turns[len(turns)-1])
+	for keep < len(turns) {
+		next := turnTokens(messages, turns[len(turns)-keep-1])
+		if kept+next > p.maxTokens/2 {
+			break
+		}
+		kept += next
+		keep++
+	}
+	cut := len(turns) - keep
+	if cut == 0 {
+		return messages, nil
+	}
+
+	summary, err := p.summarize(ctx, messages, turns[:cut])
+	if err != nil {
+		p.client.Logger.Warn("Dropping old turns instead of summarizing them", "error", err)
+		return withoutTurns(messages, turns[:oldestTurnsOver(messages, turns, p.maxTokens)]), nil
+	}
+
+	return withoutTurns(messages, turns[:cut],
+		CreateTextMessage(RoleSystem, "Summary of the earlier conversation:\n"+summary)), nil
+}
+
+// Clone copies the policy along with its cached summary, which still applies to
+// the history a fork shares with the original
+func (p *summarizePolicy) Clone() ContextPolicy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &summarizePolicy{
+		client:      p.client,
+		model:       p.model,
+		maxTokens:   p.maxTokens,
+		summarized:  p.summarized,
+		fingerprint: p.fingerprint,
+		summary:     p.summary,
+	}
+}
+
+// summarize returns a summary of the given turns, extending the previous
+// summary when it covers a prefix of them
+func (p *summarizePolicy) summarize(ctx context.Context, messages []Message, turns [][]int) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	start := 0
+	previous := ""
+	if p.summarized > 0 && p.summarized <= len(turns) && p.fingerprint == turnsFingerprint(messages, turns[:p.summarized]) {
+		start = p.summarized
+		previous = p.summary
+	}
+	if start == len(turns) {
+		return previous, nil
+	}
+
+	var transcript strings.Builder
+	if previous != "" {
+		transcript.WriteString("Summary so far:\n")
+		transcript.WriteString(previous)
+		transcript.WriteString("\n\nConversation since then:\n")
+	}
+	for _, turn := range turns[start:] {
+		for _, i := range turn {
+			writeTranscriptLine(&transcript, messages[i])
+		}
+	}
+
+	response, err := p.client.Send(ctx, 0, DefaultMaxTokens, []Message{
+		CreateTextMessage(RoleSystem, "Summarize the conversation below for the assistant that will continue it. Keep decisions, facts, file paths, tool outcomes and open tasks. Be concise and write only the summary."),
+		CreateTextMessage(RoleUser, transcript.String()),
+	}, p.model)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrContextSummary, err)
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("%w: %w", ErrContextSummary, ErrNoResponseChoices)
+	}
+
+	summary := strings.TrimSpace(response.Choices[0].Message.Content.Text())
+	if summary == "" {
+		return "", fmt.Errorf("%w: empty summary", ErrContextSummary)
+	}
+
+	p.summarized = len(turns)
+	p.fingerprint = turnsFingerprint(messages, turns)
+	p.summary = summary
+	return summary, nil
+}
+
+func writeTranscriptLine(b *strings.Builder, msg Message) {
+	text := msg.Content.Text()
+	if len(text) > summaryTranscriptLimit {
+		// cut on a rune boundary so no character is split
+		cut := summaryTranscriptLimit
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "..."
+	}
+	if text != "" {
+		fmt.Fprintf(b, "%s: %s\n", msg.Role, text)
+	}
+	for _, tc := range msg.ToolCalls {
+		fmt.Fprintf(b, "%s called %s(%s)\n", msg.Role, tc.Function.Name, tc.Function.Arguments)
+	}
+}
+
+func turnsFingerprint(messages []Message, turns [][]int) string {
+	h := sha256.New()
+	encoder := json.NewEncoder(h)
+	for _, turn := range turns {
+		for _, i := range turn {
+			encoder.Encode(messages[i])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type chainPolicy struct {
+	policies []ContextPolicy
+}
+
+// ChainPolicies applies policies in order, each to the result of the last
+func ChainPolicies(policies ...ContextPolicy) ContextPolicy {
+	return &chainPolicy{policies: policies}
+}
+
+// Clone copies the stateful policies of the chain
+func (p *chainPolicy) Clone() ContextPolicy {
+	policies := make([]ContextPolicy, len(p.policies))
+	for i, policy := range p.policies {
+		policies[i] = clonePolicy(policy)
+	}
+	return &chainPolicy{policies: policies}
+}
+
+func (p *chainPolicy) Apply(ctx context.Context, messages []Message) ([]Message, error) {
+	var err error
+	for _, policy := range p.policies {
+		if policy == nil {
+			continue
+		}
+		if messages, err = policy.Apply(ctx, messages); err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
@@ -0,0 +1,199 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Helper function to build a history of turns, each a user message, a tool
+// call with its result and an answer, all padded to a known size
+func newLongHistory(turns int) []Message {
+	padding := strings.Repeat("x", 400)
+	messages := []Message{CreateTextMessage(RoleSystem, "system prompt")}
+	for i := 0; i < turns; i++ {
+		id := "call_" + string(rune('a'+i))
+		messages = append(messages,
+			CreateTextMessage(RoleUser, "question "+padding),
+			Message{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: id, Type: "function", Function: ToolFunction{Name: "lookup", Arguments: "{}"}}}},
+			Message{Role: RoleTool, ToolCallID: id, Content: TextContent("result " + padding)},
+			CreateTextMessage(RoleAssistant, "answer "+padding),
+		)
+	}
+	return messages
+}
+
+// Helper function to check the invariants every policy must keep
+func assertContextInvariants(t *testing.T, messages []Message) {
+	t.Helper()
+	if len(messages) == 0 || messages[0].Role != RoleSystem || messages[0].Text() != "system prompt" {
+		t.Fatalf("Expected the system prompt first, got %+v", messages)
+	}
+
+	calls := map[string]bool{}
+	for _, msg := range messages {
+		for _, tc := range msg.ToolCalls {
+			calls[tc.ID] = true
+		}
+		if msg.Role == RoleTool {
+			if !calls[msg.ToolCallID] {
+				t.Errorf("Tool result %s sent without its call", msg.ToolCallID)
+			}
+			delete(calls, msg.ToolCallID)
+		}
+	}
+	for id := range calls {
+		t.Errorf("Tool call %s sent without its result", id)
+	}
+}
+
+func TestContextPolicies(t *testing.T) {
+	history := newLongHistory(6)
+	full := EstimateTokens(history)
+
+	tests := []struct {
+		name      string
+		policy    ContextPolicy
+		maxTokens int
+	}{
+		{name: "Sliding window", policy: NewSlidingWindowPolicy(full / 2), maxTokens: full / 2},
+		{name: "Drop tool results", policy: NewDropToolResultsPolicy(full*4/5, 1), maxTokens: full * 4 / 5},
+		{name: "Chained", policy: ChainPolicies(NewDropToolResultsPolicy(full/3, 1), NewSlidingWindowPolicy(full/3)), maxTokens: full / 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := append([]Message{}, history...)
+
+			sent, err := tt.policy.Apply(context.Background(), history)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			assertContextInvariants(t, sent)
+			if got := EstimateTokens(sent); got > tt.maxTokens {
+				t.Errorf("Expected at most %d tokens, got %d", tt.maxTokens, got)
+			}
+			if sent[len(sent)-1].Text() != history[len(history)-1].Text() {
+				t.Errorf("Expected the latest message to be kept")
+			}
+			for i := range history {
+				if history[i].Text() != original[i].Text() {
+					t.Fatalf("Policy modified the history at %d", i)
+				}
+			}
+		})
+	}
+
+	// a history within budget is sent as is
+	sent, _ := NewSlidingWindowPolicy(full).Apply(context.Background(), history)
+	if len(sent) != len(history) {
+		t.Errorf("Expected %d messages, got %d", len(history), len(sent))
+	}
+}
+
+func TestSummarizePolicy(t *testing.T) {
+	var transcripts []string
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		transcripts = append(transcripts, req.Messages[len(req.Messages)-1].Content.Text())
+		json.NewEncoder(w).Encode(ChatCompletionResponse{
+			Choices: []Choice{{Message: CreateTextMessage(RoleAssistant, "the user asked questions")}},
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	history := newLongHistory(6)
+	budget := EstimateTokens(history) / 2
+	policy := NewSummarizePolicy(client, "mini-model", budget)
+
+	sent, err := policy.Apply(context.Background(), history)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertContextInvariants(t, sent)
+	if sent[1].Role != RoleSystem || !strings.Contains(sent[1].Content.Text(), "the user asked questions") {
+		t.Errorf("Expected the summary after the system prompt, got %+v", sent[1])
+	}
+	if EstimateTokens(sent) > budget {
+		t.Errorf("Expected at most %d tokens, got %d", budget, EstimateTokens(sent))
+	}
+
+	// the next turn extends the existing summary instead of starting over
+	history = append(history, newLongHistory(2)[1:]...)
+	if _, err := policy.Apply(context.Background(), history); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(transcripts) != 2 || !strings.HasPrefix(transcripts[1], "Summary so far:") {
+		t.Errorf("Expected an incremental summary, got %d requests", len(transcripts))
+	}
+
+	// without a summary the oldest turns are dropped
+	fail = true
+	history = append(history, newLongHistory(2)[1:]...)
+	sent, err = policy.Apply(context.Background(), history)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertContextInvariants(t, sent)
+	if EstimateTokens(sent) > budget {
+		t.Errorf("Expected at most %d tokens, got %d", budget, EstimateTokens(sent))
+	}
+}
+
+func TestSummarizePolicyWithoutTurns(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	// a system prompt over budget and no user turn leaves nothing to summarize
+	history := []Message{CreateTextMessage(RoleSystem, strings.Repeat("system prompt ", 200))}
+	policy := NewSummarizePolicy(client, "mini-model", 10)
+
+	sent, err := policy.Apply(context.Background(), history)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sent) != 1 || sent[0].Content.Text() != history[0].Content.Text() {
+		t.Errorf("Expected the history unchanged, got %+v", sent)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no summary request, got %d", requests)
+	}
+}
+
+func TestWriteTranscriptLineKeepsRunesWhole(t *testing.T) {
+	var b strings.Builder
+	writeTranscriptLine(&b, CreateTextMessage(RoleUser, "a"+strings.Repeat("é", summaryTranscriptLimit)))
+
+	line := strings.TrimSuffix(b.String(), "\n")
+	if !utf8.ValidString(line) {
+		t.Errorf("Expected valid UTF-8, got a split rune")
+	}
+	if !strings.HasSuffix(line, "...") || len(line) > len("user: ")+summaryTranscriptLimit+len("...") {
+		t.Errorf("Expected the line truncated to the limit, got %d bytes", len(line))
+	}
+}
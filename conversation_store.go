@@ -0,0 +1,264 @@
+package beau
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+var (
+	ErrConversationStore   = fmt.Errorf("failed to access conversation store")
+	ErrCorruptConversation = fmt.Errorf("conversation data is corrupt")
+)
+
+// conversationSettings is the part of a conversation's request options that
+// can be written down. Stream channels are left out, they belong to a process.
+type conversationSettings struct {
+	Temperature    float64         `json:"temperature,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	ToolChoice     interface{}     `json:"tool_choice,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	SamplingParams
+}
+
+func settingsFromOptions(opts []RequestOption) conversationSettings {
+	var req ChatCompletionRequest
+	for _, opt := range opts {
+		opt(&req)
+	}
+	return conversationSettings{
+		Temperature:    req.Temperature,
+		MaxTokens:      req.MaxTokens,
+		Tools:          req.Tools,
+		ToolChoice:     req.ToolChoice,
+		ResponseFormat: req.ResponseFormat,
+		SamplingParams: req.SamplingParams,
+	}
+}
+
+func (s conversationSettings) options() []RequestOption {
+	var opts []RequestOption
+	if s.Temperature != 0 {
+		opts = append(opts, WithTemperature(s.Temperature))
+	}
+	if s.MaxTokens != 0 {
+		opts = append(opts, WithMaxTokens(s.MaxTokens))
+	}
+	if len(s.Tools) > 0 {
+		opts = append(opts, WithTools(s.Tools))
+	}
+	if s.ToolChoice != nil {
+		opts = append(opts, WithToolChoice(s.ToolChoice))
+	}
+	if s.ResponseFormat != nil {
+		opts = append(opts, WithResponseFormat(*s.ResponseFormat))
+	}
+	if !reflect.DeepEqual(s.SamplingParams, SamplingParams{}) {
+		sampling := s.SamplingParams
+		opts = append(opts, func(req *ChatCompletionRequest) {
+			req.SamplingParams = sampling
+		})
+	}
+	return opts
+}
+
+type conversationJSON struct {
+	Model    string               `json:"model"`
+	Settings conversationSettings `json:"settings"`
+	Messages []Message            `json:"messages"`
+}
+
+// MarshalJSON encodes the model, the serializable request options and every
+// message on the current branch of the conversation
+func (c *Conversation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(conversationJSON{
+		Model:    c.model,
+		Settings: settingsFromOptions(c.options),
+		Messages: c.messages,
+	})
+}
+
+// UnmarshalJSON restores a conversation encoded by MarshalJSON. The client is
+// not part of the encoding, use Client.LoadConversation to get one ready to Send.
+func (c *Conversation) UnmarshalJSON(data []byte) error {
+	var decoded conversationJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptConversation, err)
+	}
+
+	c.model = decoded.Model
+	c.options = decoded.Settings.options()
+	c.messages = decoded.Messages
+	if c.messages == nil {
+		c.messages = []Message{}
+	}
+	c.lastResponse = nil
+	c.initHistory()
+	return nil
+}
+
+// LoadConversation restores a conversation encoded with MarshalJSON. Any opts
+// are applied after the stored options.
+func (x *Client) LoadConversation(data []byte, opts ...RequestOption) (*Conversation, error) {
+	conv := &Conversation{client: x}
+	if err := conv.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	conv.options = append(conv.options, opts...)
+	return conv, nil
+}
+
+// conversationRecord is one line of a conversation log, the first line is the
+// header and every following line holds one message
+type conversationRecord struct {
+	Model    string                `json:"model,omitempty"`
+	Settings *conversationSettings `json:"settings,omitempty"`
+	Message  *Message              `json:"message,omitempty"`
+}
+
+// PersistTo writes the conversation to a JSONL file at path, replacing it, and
+// appends every message added afterwards so the session can be resumed with
+// Client.ResumeConversation after the process exits.
+func (c *Conversation) PersistTo(path string) error {
+	var buf bytes.Buffer
+	settings := settingsFromOptions(c.options)
+	if err := writeRecord(&buf, conversationRecord{Model: c.model, Settings: &settings}); err != nil {
+		return err
+	}
+	for i := range c.messages {
+		if err := writeRecord(&buf, conversationRecord{Message: &c.messages[i]}); err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("%w: %v", ErrConversationStore, err)
+	}
+	c.storePath = path
+	return nil
+}
+
+// ResumeConversation loads a conversation log written by PersistTo and keeps
+// appending to it. A final line cut short by a crash is dropped, a complete one
+// missing only its newline is kept. Any opts are applied after the stored
+// options.
+func (x *Client) ResumeConversation(path string, opts ...RequestOption) (*Conversation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConversationStore, err)
+	}
+
+	conv := &Conversation{client: x, messages: []Message{}}
+	valid := 0
+	header := false
+
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record conversationRecord
+			if jsonErr := json.Unmarshal(line, &record); jsonErr != nil {
+				if err == io.EOF {
+					// an unterminated last line is a write interrupted by a crash
+					x.Logger.Warn("Dropping incomplete conversation record", "path", path)
+					break
+				}
+				return nil, fmt.Errorf("%w: %s: %v", ErrCorruptConversation, path, jsonErr)
+			}
+
+			switch {
+			case !header:
+				if record.Settings == nil {
+					return nil, fmt.Errorf("%w: %s: missing header", ErrCorruptConversation, path)
+				}
+				conv.model = record.Model
+				conv.options = record.Settings.options()
+				header = true
+			case record.Message != nil:
+				conv.messages = append(conv.messages, *record.Message)
+			}
+		}
+		if err != nil {
+			// a last record that parsed is complete, only its newline is missing
+			if len(bytes.TrimSpace(line)) > 0 {
+				valid += len(line)
+			}
+			break
+		}
+		valid += len(line)
+	}
+
+	if !header {
+		return nil, fmt.Errorf("%w: %s: missing header", ErrCorruptConversation, path)
+	}
+
+	// cut off a partial record, or end a complete one, so new lines are
+	// appended to a well formed file
+	if valid < len(data) {
+		if err := os.Truncate(path, int64(valid)); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrConversationStore, err)
+		}
+	} else if len(data) > 0 && data[len(data)-1] != '\n' {
+		if err := appendNewline(path); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrConversationStore, err)
+		}
+	}
+
+	conv.options = append(conv.options, opts...)
+	conv.storePath = path
+	conv.initHistory()
+	return conv, nil
+}
+
+func appendNewline(path string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write([]byte{'\n'}); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// appendToStore writes a message to the conversation log, if there is one
+func (c *Conversation) appendToStore(message Message) error {
+	if c.storePath == "" {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := writeRecord(&buf, conversationRecord{Message: &message}); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(c.storePath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConversationStore, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("%w: %v", ErrConversationStore, err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("%w: %v", ErrConversationStore, err)
+	}
+	return nil
+}
+
+func writeRecord(w io.Writer, record conversationRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConversationStore, err)
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
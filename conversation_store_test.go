@@ -0,0 +1,186 @@
+package beau
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Helper function to build a conversation using every kind of content
+func newRichConversation(t *testing.T) (*Client, *Conversation) {
+	t.Helper()
+	client, err := NewClient("test-key", "http://localhost", nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	tools := []Tool{{Type: "function", Function: ToolSchema{
+		Name:       "lookup",
+		Parameters: map[string]interface{}{"type": "object"},
+	}}}
+	conv := client.NewConversation("test-model", WithTools(tools), WithToolChoice("auto"), WithMaxTokens(64))
+	conv.AddSystemMessage("be brief").
+		AddComplexUserMessage([]ContentItem{
+			CreateTextItem("what is this?"),
+			{Type: ContentTypeImageURL, ImageURL: &ImageURL{URL: "data:image/png;base64,AAAA", Detail: "low"}},
+		}).
+		AddMessage(Message{
+			Role: RoleAssistant,
+			ToolCalls: []ToolCall{{
+				ID:       "call_1",
+				Type:     "function",
+				Function: ToolFunction{Name: "lookup", Arguments: `{"q":"png"}`},
+			}},
+		}).
+		AddToolResult("call_1", "an image").
+		AddMessage(Message{
+			Role: RoleAssistant,
+			ToolCalls: []ToolCall{{
+				ID:       "call_2",
+				Type:     "function",
+				Function: ToolFunction{Name: "lookup", Arguments: `{"q":"jpg"}`},
+			}},
+		}).
+		AddToolError("call_2", "Error: not found").
+		AddAssistantMessage("It is an image.")
+	return client, conv
+}
+
+func TestConversationJSONRoundTrip(t *testing.T) {
+	client, conv := newRichConversation(t)
+
+	data, err := json.Marshal(conv)
+	if err != nil {
+		t.Fatalf("Failed to marshal conversation: %v", err)
+	}
+
+	restored, err := client.LoadConversation(data)
+	if err != nil {
+		t.Fatalf("Failed to load conversation: %v", err)
+	}
+
+	if !reflect.DeepEqual(restored.GetMessages(), conv.GetMessages()) {
+		t.Errorf("Messages differ after round trip:\n%+v\n%+v", restored.GetMessages(), conv.GetMessages())
+	}
+	if restored.model != "test-model" {
+		t.Errorf("Expected model test-model, got %q", restored.model)
+	}
+	if !reflect.DeepEqual(settingsFromOptions(restored.options), settingsFromOptions(conv.options)) {
+		t.Errorf("Options differ after round trip")
+	}
+
+	if _, err := client.LoadConversation([]byte("{not json")); !errors.Is(err, ErrCorruptConversation) {
+		t.Errorf("Expected ErrCorruptConversation, got %v", err)
+	}
+}
+
+func TestConversationPersistAndResume(t *testing.T) {
+	client, conv := newRichConversation(t)
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+
+	if err := conv.PersistTo(path); err != nil {
+		t.Fatalf("Failed to persist conversation: %v", err)
+	}
+	conv.AddUserMessage("and now?")
+
+	// simulate a crash in the middle of writing the next record
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	file.WriteString(`{"message":{"role":"assis`)
+	file.Close()
+
+	resumed, err := client.ResumeConversation(path)
+	if err != nil {
+		t.Fatalf("Failed to resume conversation: %v", err)
+	}
+	if !reflect.DeepEqual(resumed.GetMessages(), conv.GetMessages()) {
+		t.Fatalf("Messages differ after resume:\n%+v\n%+v", resumed.GetMessages(), conv.GetMessages())
+	}
+
+	// appends after the partial record was cut off keep the log readable
+	resumed.AddAssistantMessage("still here")
+	again, err := client.ResumeConversation(path)
+	if err != nil {
+		t.Fatalf("Failed to resume conversation again: %v", err)
+	}
+	messages := again.GetMessages()
+	if len(messages) != len(conv.GetMessages())+1 || messages[len(messages)-1].Text() != "still here" {
+		t.Errorf("Expected the appended message last, got %+v", messages[len(messages)-1])
+	}
+}
+
+func TestResumeConversationWithoutTrailingNewline(t *testing.T) {
+	client, conv := newRichConversation(t)
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+
+	if err := conv.PersistTo(path); err != nil {
+		t.Fatalf("Failed to persist conversation: %v", err)
+	}
+
+	// the last record is complete, only its newline is missing
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if err := os.WriteFile(path, bytes.TrimSuffix(data, []byte("\n")), 0644); err != nil {
+		t.Fatalf("Failed to rewrite log: %v", err)
+	}
+
+	resumed, err := client.ResumeConversation(path)
+	if err != nil {
+		t.Fatalf("Failed to resume conversation: %v", err)
+	}
+	if !reflect.DeepEqual(resumed.GetMessages(), conv.GetMessages()) {
+		t.Fatalf("Messages differ after resume:\n%+v\n%+v", resumed.GetMessages(), conv.GetMessages())
+	}
+	if repaired, _ := os.ReadFile(path); !bytes.Equal(repaired, data) {
+		t.Errorf("Expected the record kept and its newline restored, got %q", repaired)
+	}
+
+	resumed.AddAssistantMessage("still here")
+	again, err := client.ResumeConversation(path)
+	if err != nil {
+		t.Fatalf("Failed to resume conversation again: %v", err)
+	}
+	if messages := again.GetMessages(); len(messages) != len(conv.GetMessages())+1 {
+		t.Errorf("Expected every message after a second resume, got %d", len(messages))
+	}
+}
+
+func TestResumeConversationErrors(t *testing.T) {
+	client, err := NewClient("test-key", "http://localhost", nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		missing  bool
+		contents string
+		expected error
+	}{
+		{name: "Missing file", missing: true, expected: ErrConversationStore},
+		{name: "Empty file", contents: "", expected: ErrCorruptConversation},
+		{name: "Missing header", contents: `{"message":{"role":"user","content":"hi"}}` + "\n", expected: ErrCorruptConversation},
+		{name: "Corrupt middle line", contents: `{"model":"m","settings":{}}` + "\nnot json\n" + `{"message":{"role":"user","content":"hi"}}` + "\n", expected: ErrCorruptConversation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".jsonl")
+			if !tt.missing {
+				os.WriteFile(path, []byte(tt.contents), 0644)
+			}
+			if _, err := client.ResumeConversation(path); !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package beau
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrBudgetExceeded = fmt.Errorf("budget exceeded")
+)
+
+// ModelPrice is what a model costs in USD per million tokens
+type ModelPrice struct {
+	Input       float64 `json:"input"`
+	Output      float64 `json:"output"`
+	CachedInput float64 `json:"cached_input"` // prompt tokens read from the provider's cache, Input if 0
+}
+
+// Cost returns the price of usage in USD
+func (p ModelPrice) Cost(usage Usage) float64 {
+	cached := usage.CachedTokens()
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	return (float64(usage.PromptTokens-cached)*p.Input +
+		float64(cached)*cachedPrice +
+		float64(usage.CompletionTokens)*p.Output) / 1_000_000
+}
+
+// defaultPrices are the list prices of common hosted models. Local models are
+// free and left out.
+var defaultPrices = map[string]ModelPrice{
+	"gpt-4o":                 {Input: 2.50, Output: 10.00, CachedInput: 1.25},
+	"gpt-4o-mini":            {Input: 0.15, Output: 0.60, CachedInput: 0.075},
+	"gpt-4.1":                {Input: 2.00, Output: 8.00, CachedInput: 0.50},
+	"gpt-4.1-mini":           {Input: 0.40, Output: 1.60, CachedInput: 0.10},
+	"gpt-4.1-nano":           {Input: 0.10, Output: 0.40, CachedInput: 0.025},
+	"o3":                     {Input: 2.00, Output: 8.00, CachedInput: 0.50},
+	"o3-mini":                {Input: 1.10, Output: 4.40, CachedInput: 0.55},
+	"o4-mini":                {Input: 1.10, Output: 4.40, CachedInput: 0.275},
+	"text-embedding-3-small": {Input: 0.02},
+	"text-embedding-3-large": {Input: 0.13},
+	"claude-3-5-sonnet":      {Input: 3.00, Output: 15.00, CachedInput: 0.30},
+	"claude-3-5-haiku":       {Input: 0.80, Output: 4.00, CachedInput: 0.08},
+	"claude-3-7-sonnet":      {Input: 3.00, Output: 15.00, CachedInput: 0.30},
+	"claude-sonnet-4":        {Input: 3.00, Output: 15.00, CachedInput: 0.30},
+	"claude-opus-4":          {Input: 15.00, Output: 75.00, CachedInput: 1.50},
+	"grok-2":                 {Input: 2.00, Output: 10.00},
+	"grok-3":                 {Input: 3.00, Output: 15.00, CachedInput: 0.75},
+	"grok-3-mini":            {Input: 0.30, Output: 0.50, CachedInput: 0.075},
+	"grok-4":                 {Input: 3.00, Output: 15.00, CachedInput: 0.75},
+}
+
+// Pricing maps model names to prices. A model without an entry of its own is
+// priced by the longest name it starts with, so dated releases such as
+// gpt-4o-2024-08-06 share the price of gpt-4o.
+type Pricing struct {
+	mu     sync.RWMutex
+	prices map[string]ModelPrice
+}
+
+// DefaultPricing is used by clients that were not given a Pricing of their own
+var DefaultPricing = NewPricing(nil)
+
+// NewPricing creates a registry holding the default prices with overrides
+// applied on top
+func NewPricing(overrides map[string]ModelPrice) *Pricing {
+	p := &Pricing{prices: make(map[string]ModelPrice, len(defaultPrices)+len(overrides))}
+	for model, price := range defaultPrices {
+		p.prices[model] = price
+	}
+	for model, price := range overrides {
+		p.prices[model] = price
+	}
+	return p
+}
+
+// Set adds or replaces the price of a model
+func (p *Pricing) Set(model string, price ModelPrice) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prices[model] = price
+}
+
+// Price looks up the price of a model
+func (p *Pricing) Price(model string) (ModelPrice, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if price, ok := p.prices[model]; ok {
+		return price, true
+	}
+	var best string
+	for name := range p.prices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return p.prices[best], true
+}
+
+// Cost returns the price of usage on model in USD, 0 for unknown models
+func (p *Pricing) Cost(model string, usage Usage) float64 {
+	price, _ := p.Price(model)
+	return price.Cost(usage)
+}
+
+// Spend is the usage and cost of a number of requests
+type Spend struct {
+	Requests int
+	Usage    Usage
+	Cost     float64 // USD
+}
+
+// Sub returns the spend accrued since an earlier reading
+func (s Spend) Sub(earlier Spend) Spend {
+	usage := Usage{
+		PromptTokens:     s.Usage.PromptTokens - earlier.Usage.PromptTokens,
+		CompletionTokens: s.Usage.CompletionTokens - earlier.Usage.CompletionTokens,
+		TotalTokens:      s.Usage.TotalTokens - earlier.Usage.TotalTokens,
+	}
+	if cached := s.Usage.CachedTokens() - earlier.Usage.CachedTokens(); cached > 0 {
+		usage.PromptTokensDetails = &PromptTokensDetails{CachedTokens: cached}
+	}
+	return Spend{
+		Requests: s.Requests - earlier.Requests,
+		Usage:    usage,
+		Cost:     s.Cost - earlier.Cost,
+	}
+}
+
+// CostMeter accumulates the spend of the clients recording to it. Every charge
+// also counts toward the parent, so a meter per mage can roll up into one per
+// session.
+type CostMeter struct {
+	mu     sync.Mutex
+	spend  Spend
+	parent *CostMeter
+}
+
+// NewCostMeter creates a meter, parent may be nil
+func NewCostMeter(parent *CostMeter) *CostMeter {
+	return &CostMeter{parent: parent}
+}
+
+// Record adds one request to the meter and its parents
+func (m *CostMeter) Record(usage Usage, cost float64) {
+	for meter := m; meter != nil; meter = meter.parent {
+		meter.mu.Lock()
+		meter.spend.Requests++
+		meter.spend.Usage = meter.spend.Usage.Add(usage)
+		meter.spend.Cost += cost
+		meter.mu.Unlock()
+	}
+}
+
+// Spend returns everything recorded so far
+func (m *CostMeter) Spend() Spend {
+	if m == nil {
+		return Spend{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.spend
+}
+
+// Budget caps the spend of every client sharing it. Requests are refused with
+// ErrBudgetExceeded once the limit is reached; the request that crosses it is
+// still paid for, since its cost is only known after it returns.
+type Budget struct {
+	mu    sync.Mutex
+	limit float64
+	spent float64
+}
+
+// NewBudget creates a budget of limit USD
+func NewBudget(limit float64) *Budget {
+	return &Budget{limit: limit}
+}
+
+// Check returns ErrBudgetExceeded once the limit has been reached
+func (b *Budget) Check() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.spent >= b.limit {
+		return fmt.Errorf("%w: spent $%.4f of $%.4f", ErrBudgetExceeded, b.spent, b.limit)
+	}
+	return nil
+}
+
+// Spent returns the USD charged to the budget
+func (b *Budget) Spent() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent
+}
+
+// Remaining returns the USD left before requests are refused
+func (b *Budget) Remaining() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return max(0, b.limit-b.spent)
+}
+
+func (b *Budget) charge(cost float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spent += cost
+}
+
+// WithPricing prices requests with a registry other than DefaultPricing
+func (x *Client) WithPricing(pricing *Pricing) *Client {
+	x.Pricing = pricing
+	return x
+}
+
+// WithCostMeter records the client's spend to meter, use NewCostMeter with a
+// shared parent to total several clients
+func (x *Client) WithCostMeter(meter *CostMeter) *Client {
+	if meter != nil {
+		x.Meter = meter
+	}
+	return x
+}
+
+// WithBudget refuses requests once the budget, which may be shared between
+// clients, is spent
+func (x *Client) WithBudget(budget *Budget) *Client {
+	x.Budget = budget
+	return x
+}
+
+// Spend returns the usage and cost of every request the client made
+func (x *Client) Spend() Spend {
+	return x.Meter.Spend()
+}
+
+// checkBudget refuses a request once the client's budget is spent
+func (x *Client) checkBudget() error {
+	if x.Budget == nil {
+		return nil
+	}
+	return x.Budget.Check()
+}
+
+// costOf prices usage on model with the client's pricing
+func (x *Client) costOf(model string, usage Usage) float64 {
+	pricing := x.Pricing
+	if pricing == nil {
+		pricing = DefaultPricing
+	}
+	price, ok := pricing.Price(model)
+	if !ok {
+		x.Logger.Debug("No price for model, counting it as free", "model", model)
+	}
+	return price.Cost(usage)
+}
+
+// recordCost charges the usage of a request on model to the meter and budget
+func (x *Client) recordCost(model string, usage Usage) {
+	cost := x.costOf(model, usage)
+
+	x.Meter.Record(usage, cost)
+	if x.Budget != nil {
+		x.Budget.charge(cost)
+	}
+}
@@ -0,0 +1,103 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPricingLookup(t *testing.T) {
+	pricing := NewPricing(map[string]ModelPrice{"gpt-4o": {Input: 1, Output: 1}})
+
+	tests := []struct {
+		name     string
+		model    string
+		expected ModelPrice
+		found    bool
+	}{
+		{name: "Exact name", model: "grok-4", expected: defaultPrices["grok-4"], found: true},
+		{name: "Dated release", model: "claude-3-5-sonnet-20240620", expected: defaultPrices["claude-3-5-sonnet"], found: true},
+		{name: "Longest prefix wins", model: "gpt-4o-mini-2024-07-18", expected: defaultPrices["gpt-4o-mini"], found: true},
+		{name: "Override", model: "gpt-4o", expected: ModelPrice{Input: 1, Output: 1}, found: true},
+		{name: "Unknown model", model: "llama3.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, found := pricing.Price(tt.model)
+			if found != tt.found || price != tt.expected {
+				t.Errorf("Expected %+v (%v), got %+v (%v)", tt.expected, tt.found, price, found)
+			}
+		})
+	}
+}
+
+func TestModelPriceCost(t *testing.T) {
+	price := ModelPrice{Input: 2, Output: 10, CachedInput: 1}
+	usage := Usage{
+		PromptTokens:        1_000_000,
+		CompletionTokens:    500_000,
+		PromptTokensDetails: &PromptTokensDetails{CachedTokens: 400_000},
+	}
+
+	// 600k uncached at 2, 400k cached at 1, 500k completion at 10
+	if cost := price.Cost(usage); cost != 1.2+0.4+5 {
+		t.Errorf("Expected 6.6, got %v", cost)
+	}
+
+	price.CachedInput = 0
+	if cost := price.Cost(usage); cost != 2+5 {
+		t.Errorf("Expected cached tokens at the input price, got %v", cost)
+	}
+}
+
+func TestClientBudget(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewEncoder(w).Encode(ChatCompletionResponse{
+			Choices: []Choice{{Message: CreateTextMessage(RoleAssistant, "ok")}},
+			Usage:   Usage{PromptTokens: 1000, CompletionTokens: 100, TotalTokens: 1100},
+		})
+	}))
+	defer server.Close()
+
+	session := NewCostMeter(nil)
+	budget := NewBudget(0.015)
+	client, err := NewClient("test-key", server.URL, nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.WithPricing(NewPricing(map[string]ModelPrice{"priced": {Input: 5, Output: 50}})).
+		WithBudget(budget).
+		WithCostMeter(NewCostMeter(session))
+
+	messages := []Message{CreateTextMessage(RoleUser, "hi")}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Send(context.Background(), 0, 100, messages, "priced"); err != nil {
+			t.Fatalf("Request %d: unexpected error: %v", i, err)
+		}
+	}
+
+	// two requests at 1000*5/1M + 100*50/1M = $0.01 each cross the budget
+	if _, err := client.Send(context.Background(), 0, 100, messages, "priced"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected ErrBudgetExceeded, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected the refused request not to be sent, got %d requests", requests)
+	}
+
+	spend := client.Spend()
+	if spend.Requests != 2 || spend.Usage.TotalTokens != 2200 || spend.Cost != 0.02 {
+		t.Errorf("Unexpected client spend: %+v", spend)
+	}
+	if session.Spend() != spend {
+		t.Errorf("Expected the session meter to match, got %+v", session.Spend())
+	}
+	if budget.Spent() != 0.02 || budget.Remaining() != 0 {
+		t.Errorf("Unexpected budget: spent %v, remaining %v", budget.Spent(), budget.Remaining())
+	}
+}
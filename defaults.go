@@ -9,6 +9,8 @@ const (
 	DefaultModel_Claude   = "claude-3-5-sonnet-20240620"
 	DefaultBaseURL_OpenAI = "https://api.openai.com"
 	DefaultModel_OpenAI   = "gpt-4o"
+	DefaultBaseURL_Local  = "http://localhost:11434"
+	DefaultModel_Local    = "llama3.1"
 	DefaultTemperature    = 0.7
 	DefaultMaxTokens      = 8000
 
@@ -17,6 +19,7 @@ const (
 	DefaultInitialDelay  = 1 * time.Second
 	DefaultMaxDelay      = 32 * time.Second
 	DefaultBackoffFactor = 2.0
+	DefaultJitter        = 0.2
 
 	DefaultTimeout = 10 * time.Minute
 
@@ -0,0 +1,278 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+)
+
+var (
+	ErrEmbeddingsUnsupported = fmt.Errorf("provider does not support embeddings")
+	ErrEmbeddingMismatch     = fmt.Errorf("embedding count does not match inputs")
+)
+
+// DefaultEmbeddingBatchSize is the number of inputs sent per embedding request
+const DefaultEmbeddingBatchSize = 256
+
+// EmbeddingProvider is implemented by providers that can embed text. Providers
+// without an embeddings endpoint, such as Anthropic, do not implement it.
+type EmbeddingProvider interface {
+	// NewEmbeddingRequest builds the HTTP request embedding one batch of inputs
+	NewEmbeddingRequest(ctx context.Context, baseURL string, apiKey string, req EmbeddingRequest) (*http.Request, error)
+
+	// ParseEmbeddingResponse decodes the body of a successful embedding response
+	ParseEmbeddingResponse(body []byte) (*EmbeddingResponse, error)
+}
+
+// EmbeddingRequest is one batch of inputs to embed
+type EmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+// Embedding is the vector of the input at Index
+type Embedding struct {
+	Index  int       `json:"index"`
+	Vector []float32 `json:"embedding"`
+}
+
+// EmbeddingResponse holds one embedding per input, in input order
+type EmbeddingResponse struct {
+	Model      string      `json:"model"`
+	Embeddings []Embedding `json:"data"`
+	Usage      Usage       `json:"usage"`
+}
+
+// Vectors returns the embedding vectors in input order
+func (r *EmbeddingResponse) Vectors() [][]float32 {
+	vectors := make([][]float32, len(r.Embeddings))
+	for i, embedding := range r.Embeddings {
+		vectors[i] = embedding.Vector
+	}
+	return vectors
+}
+
+type EmbeddingOption func(*embeddingConfig)
+
+type embeddingConfig struct {
+	batchSize  int
+	dimensions int
+}
+
+// WithEmbeddingBatchSize sets how many inputs are sent per request
+func WithEmbeddingBatchSize(size int) EmbeddingOption {
+	return func(c *embeddingConfig) {
+		c.batchSize = size
+	}
+}
+
+// WithDimensions asks for shortened vectors, for models that support it
+func WithDimensions(dimensions int) EmbeddingOption {
+	return func(c *embeddingConfig) {
+		c.dimensions = dimensions
+	}
+}
+
+// Embed embeds inputs with model, splitting them into batches. Every batch goes
+// through the same retries and rate limiter as chat requests. The usage of all
+// batches is summed.
+func (x *Client) Embed(ctx context.Context, model string, inputs []string, opts ...EmbeddingOption) (*EmbeddingResponse, error) {
+	provider, ok := x.Provider.(EmbeddingProvider)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrEmbeddingsUnsupported, x.Provider.Name())
+	}
+
+	config := embeddingConfig{batchSize: DefaultEmbeddingBatchSize}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.batchSize <= 0 {
+		config.batchSize = DefaultEmbeddingBatchSize
+	}
+
+	x.Logger.Debug("Sending embedding request", "provider", x.Provider.Name(), "model", model, "inputCount", len(inputs))
+
+	result := &EmbeddingResponse{Model: model, Embeddings: make([]Embedding, 0, len(inputs))}
+	for start := 0; start < len(inputs); start += config.batchSize {
+		if err := x.checkBudget(); err != nil {
+			return nil, err
+		}
+		end := min(start+config.batchSize, len(inputs))
+		batch, err := x.embedBatch(ctx, provider, EmbeddingRequest{
+			Model:      model,
+			Input:      inputs[start:end],
+			Dimensions: config.dimensions,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, embedding := range batch.Embeddings {
+			embedding.Index += start
+			result.Embeddings = append(result.Embeddings, embedding)
+		}
+		result.Usage = result.Usage.Add(batch.Usage)
+		x.recordCost(model, batch.Usage)
+		if batch.Model != "" {
+			result.Model = batch.Model
+		}
+	}
+	return result, nil
+}
+
+func (x *Client) embedBatch(ctx context.Context, provider EmbeddingProvider, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	httpReq, err := provider.NewEmbeddingRequest(ctx, x.BaseURL, x.APIKey, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := x.doRequestWithRetry(ctx, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReadResponseBody, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, x.classifyError(resp, body)
+	}
+
+	result, err := provider.ParseEmbeddingResponse(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Embeddings) != len(req.Input) {
+		return nil, fmt.Errorf("%w: sent %d, got %d", ErrEmbeddingMismatch, len(req.Input), len(result.Embeddings))
+	}
+
+	// providers may answer out of order, the index is authoritative
+	sort.Slice(result.Embeddings, func(i, j int) bool {
+		return result.Embeddings[i].Index < result.Embeddings[j].Index
+	})
+	return result, nil
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors, 0 when
+// either is empty or they differ in length
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+var (
+	_ EmbeddingProvider = &OpenAIProvider{}
+	_ EmbeddingProvider = &XAIProvider{}
+	_ EmbeddingProvider = &OllamaProvider{}
+)
+
+func (p *OpenAIProvider) NewEmbeddingRequest(ctx context.Context, baseURL string, apiKey string, req EmbeddingRequest) (*http.Request, error) {
+	if p.RequireAPIKey && apiKey == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMarshalRequest, err)
+	}
+
+	path := p.EmbeddingsPath
+	if path == "" {
+		path = "/v1/embeddings"
+	}
+
+	httpReq, err := newJSONRequest(ctx, baseURL, path, jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	}
+	for k, v := range p.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	return httpReq, nil
+}
+
+func (p *OpenAIProvider) ParseEmbeddingResponse(body []byte) (*EmbeddingResponse, error) {
+	var result EmbeddingResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnmarshalResponse, err)
+	}
+	return &result, nil
+}
+
+type ollamaEmbedRequest struct {
+	Model      string                 `json:"model"`
+	Input      []string               `json:"input"`
+	Dimensions int                    `json:"dimensions,omitempty"`
+	Options    map[string]interface{} `json:"options,omitempty"`
+	KeepAlive  string                 `json:"keep_alive,omitempty"`
+}
+
+type ollamaEmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+}
+
+// NewEmbeddingRequest targets Ollama's /api/embed, which takes a batch of inputs
+func (p *OllamaProvider) NewEmbeddingRequest(ctx context.Context, baseURL string, apiKey string, req EmbeddingRequest) (*http.Request, error) {
+	jsonData, err := json.Marshal(ollamaEmbedRequest{
+		Model:      req.Model,
+		Input:      req.Input,
+		Dimensions: req.Dimensions,
+		Options:    p.Options,
+		KeepAlive:  p.KeepAlive,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMarshalRequest, err)
+	}
+
+	httpReq, err := newJSONRequest(ctx, baseURL, "/api/embed", jsonData)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	}
+	return httpReq, nil
+}
+
+func (p *OllamaProvider) ParseEmbeddingResponse(body []byte) (*EmbeddingResponse, error) {
+	var result ollamaEmbedResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnmarshalResponse, err)
+	}
+
+	response := &EmbeddingResponse{
+		Model: result.Model,
+		Usage: Usage{
+			PromptTokens: result.PromptEvalCount,
+			TotalTokens:  result.PromptEvalCount,
+		},
+	}
+	for i, vector := range result.Embeddings {
+		response.Embeddings = append(response.Embeddings, Embedding{Index: i, Vector: vector})
+	}
+	return response, nil
+}
@@ -0,0 +1,129 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Helper function to create a server that embeds each input as [len(input), batch]
+func newEmbeddingServer(t *testing.T, ollama bool, batches *[][]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req EmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		*batches = append(*batches, req.Input)
+		batch := float32(len(*batches))
+
+		if ollama {
+			if r.URL.Path != "/api/embed" {
+				t.Errorf("Unexpected path %s", r.URL.Path)
+			}
+			var vectors [][]float32
+			for _, input := range req.Input {
+				vectors = append(vectors, []float32{float32(len(input)), batch})
+			}
+			json.NewEncoder(w).Encode(ollamaEmbedResponse{Model: req.Model, Embeddings: vectors, PromptEvalCount: len(req.Input)})
+			return
+		}
+
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		// answer in reverse to check the index is honoured
+		response := EmbeddingResponse{Model: req.Model, Usage: Usage{PromptTokens: len(req.Input), TotalTokens: len(req.Input)}}
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			response.Embeddings = append(response.Embeddings, Embedding{Index: i, Vector: []float32{float32(len(req.Input[i])), batch}})
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestClientEmbed(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		ollama   bool
+	}{
+		{name: "OpenAI", provider: NewOpenAIProvider()},
+		{name: "xAI", provider: NewXAIProvider()},
+		{name: "Ollama", provider: NewOllamaProvider(), ollama: true},
+	}
+
+	inputs := []string{"a", "bb", "ccc", "dddd", "eeeee"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var batches [][]string
+			server := newEmbeddingServer(t, tt.ollama, &batches)
+			defer server.Close()
+
+			client, err := NewClient("test-key", server.URL, nil, nil, RetryConfig{})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			client.WithProvider(tt.provider)
+
+			result, err := client.Embed(context.Background(), "embed-model", inputs, WithEmbeddingBatchSize(2))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(batches) != 3 {
+				t.Errorf("Expected 3 batches, got %d", len(batches))
+			}
+			if result.Usage.PromptTokens != len(inputs) {
+				t.Errorf("Expected summed usage of %d, got %d", len(inputs), result.Usage.PromptTokens)
+			}
+
+			vectors := result.Vectors()
+			if len(vectors) != len(inputs) {
+				t.Fatalf("Expected %d vectors, got %d", len(inputs), len(vectors))
+			}
+			for i, vector := range vectors {
+				if int(vector[0]) != len(inputs[i]) || int(vector[1]) != i/2+1 {
+					t.Errorf("Vector %d out of order: %v", i, vector)
+				}
+				if result.Embeddings[i].Index != i {
+					t.Errorf("Expected index %d, got %d", i, result.Embeddings[i].Index)
+				}
+			}
+		})
+	}
+}
+
+func TestClientEmbedUnsupportedProvider(t *testing.T) {
+	client, err := NewClient("test-key", DefaultBaseURL_Claude, nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if _, err := client.Embed(context.Background(), "model", []string{"text"}); !errors.Is(err, ErrEmbeddingsUnsupported) {
+		t.Errorf("Expected ErrEmbeddingsUnsupported, got %v", err)
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     []float32
+		expected float64
+	}{
+		{name: "Same direction", a: []float32{1, 2}, b: []float32{2, 4}, expected: 1},
+		{name: "Orthogonal", a: []float32{1, 0}, b: []float32{0, 1}, expected: 0},
+		{name: "Opposite", a: []float32{1, 1}, b: []float32{-1, -1}, expected: -1},
+		{name: "Length mismatch", a: []float32{1}, b: []float32{1, 1}, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CosineSimilarity(tt.a, tt.b); math.Abs(got-tt.expected) > 1e-9 {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAuthentication = fmt.Errorf("authentication failed")
+	ErrQuotaExceeded  = fmt.Errorf("quota exceeded")
+	ErrRateLimited    = fmt.Errorf("rate limited")
+	ErrContextLength  = fmt.Errorf("context length exceeded")
+	ErrContentFilter  = fmt.Errorf("content filtered")
+	ErrOverloaded     = fmt.Errorf("provider overloaded")
+)
+
+type APIErrorKind string
+
+const (
+	APIErrorUnknown        APIErrorKind = "unknown"
+	APIErrorAuth           APIErrorKind = "auth"
+	APIErrorQuota          APIErrorKind = "quota"
+	APIErrorRateLimit      APIErrorKind = "rate_limit"
+	APIErrorContextLength  APIErrorKind = "context_length"
+	APIErrorContentFilter  APIErrorKind = "content_filter"
+	APIErrorInvalidRequest APIErrorKind = "invalid_request"
+	APIErrorOverloaded     APIErrorKind = "overloaded"
+	APIErrorServer         APIErrorKind = "server"
+)
+
+// APIError is returned when a provider rejects a request. Branch on it with
+// errors.As and Kind, or with errors.Is and the matching sentinel (ErrAuthentication,
+// ErrContextLength, ...). It also matches ErrUnexpectedStatusCode.
+type APIError struct {
+	StatusCode int
+	Kind       APIErrorKind
+	Provider   string
+	Code       string // provider error code, e.g. context_length_exceeded
+	Type       string // provider error type, e.g. invalid_request_error
+	Message    string
+	RequestID  string
+	RetryAfter time.Duration // from the Retry-After header, zero if absent
+	Body       string        // raw response body
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	if e.Provider != "" {
+		b.WriteString(e.Provider)
+		b.WriteString(": ")
+	}
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, "%d ", e.StatusCode)
+	}
+	b.WriteString(string(e.Kind))
+	if e.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Message)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " (request id %s)", e.RequestID)
+	}
+	return b.String()
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnexpectedStatusCode:
+		return e.StatusCode != 0
+	case ErrAuthentication:
+		return e.Kind == APIErrorAuth
+	case ErrQuotaExceeded:
+		return e.Kind == APIErrorQuota
+	case ErrRateLimited:
+		return e.Kind == APIErrorRateLimit
+	case ErrContextLength:
+		return e.Kind == APIErrorContextLength
+	case ErrContentFilter:
+		return e.Kind == APIErrorContentFilter
+	case ErrOverloaded:
+		return e.Kind == APIErrorOverloaded
+	}
+	return false
+}
+
+// Retriable reports whether sending the same request again may succeed
+func (e *APIError) Retriable() bool {
+	switch e.Kind {
+	case APIErrorRateLimit, APIErrorOverloaded, APIErrorServer:
+		return true
+	}
+	return e.StatusCode == http.StatusRequestTimeout
+}
+
+// newAPIError builds an APIError and works out its kind from the status, the
+// provider code and type, and as a last resort the message
+func newAPIError(statusCode int, body []byte, code string, errType string, message string) *APIError {
+	e := &APIError{
+		StatusCode: statusCode,
+		Code:       code,
+		Type:       errType,
+		Message:    message,
+		Body:       string(body),
+	}
+	if e.Message == "" && len(body) > 0 && !json.Valid(body) {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	e.Kind = classifyAPIError(statusCode, code, errType, e.Message)
+	return e
+}
+
+func classifyAPIError(statusCode int, code string, errType string, message string) APIErrorKind {
+	hint := strings.ToLower(code + " " + errType + " " + message)
+	switch {
+	case strings.Contains(hint, "insufficient_quota"), strings.Contains(hint, "billing"):
+		return APIErrorQuota
+	case strings.Contains(hint, "quota") && statusCode != http.StatusTooManyRequests:
+		// a 429 mentioning a quota is usually a per minute rate limit
+		return APIErrorQuota
+	case strings.Contains(hint, "context_length"), strings.Contains(hint, "maximum context length"),
+		strings.Contains(hint, "prompt is too long"), strings.Contains(hint, "context window"):
+		return APIErrorContextLength
+	case strings.Contains(hint, "content_filter"), strings.Contains(hint, "content_policy"),
+		strings.Contains(hint, "content management policy"):
+		return APIErrorContentFilter
+	case strings.Contains(hint, "overloaded"):
+		return APIErrorOverloaded
+	}
+
+	switch {
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden,
+		strings.Contains(hint, "authentication"), strings.Contains(hint, "permission"):
+		return APIErrorAuth
+	case statusCode == http.StatusTooManyRequests, strings.Contains(hint, "rate_limit"):
+		return APIErrorRateLimit
+	case statusCode == 529:
+		return APIErrorOverloaded
+	case statusCode >= 500:
+		return APIErrorServer
+	case statusCode >= 400:
+		return APIErrorInvalidRequest
+	}
+	return APIErrorUnknown
+}
+
+// RetryClassifier decides whether an attempt is retried. It receives the status
+// code of the response, or the transport error when there was no response.
+type RetryClassifier func(statusCode int, err error) bool
+
+// DefaultRetryClassifier retries transport failures, timeouts, rate limits and
+// server side errors including Anthropic's 529 overloaded
+func DefaultRetryClassifier(statusCode int, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch statusCode {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+		529:
+		return true
+	}
+	return false
+}
+
+// requestIDFromHeader finds the request id providers attach to responses
+func requestIDFromHeader(header http.Header) string {
+	for _, key := range []string{"X-Request-Id", "Request-Id", "Anthropic-Request-Id"} {
+		if id := header.Get(key); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// retryAfterFromHeader reads Retry-After, or OpenAI's retry-after-ms
+func retryAfterFromHeader(header http.Header) time.Duration {
+	if ms := header.Get("Retry-After-Ms"); ms != "" {
+		if d, err := time.ParseDuration(ms + "ms"); err == nil {
+			return d
+		}
+	}
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		return parseRetryAfter(retryAfter)
+	}
+	return 0
+}
@@ -0,0 +1,208 @@
+package beau
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name         string
+		provider     Provider
+		status       int
+		body         string
+		expectedKind APIErrorKind
+		expectedIs   error
+		expectedCode string
+	}{
+		{
+			name:         "OpenAI context length",
+			provider:     NewOpenAIProvider(),
+			status:       400,
+			body:         `{"error":{"message":"This model's maximum context length is 128000 tokens","type":"invalid_request_error","code":"context_length_exceeded"}}`,
+			expectedKind: APIErrorContextLength,
+			expectedIs:   ErrContextLength,
+			expectedCode: "context_length_exceeded",
+		},
+		{
+			name:         "OpenAI quota",
+			provider:     NewOpenAIProvider(),
+			status:       429,
+			body:         `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`,
+			expectedKind: APIErrorQuota,
+			expectedIs:   ErrQuotaExceeded,
+			expectedCode: "insufficient_quota",
+		},
+		{
+			name:         "OpenAI rate limit",
+			provider:     NewOpenAIProvider(),
+			status:       429,
+			body:         `{"error":{"message":"Rate limit reached for requests","type":"requests","code":"rate_limit_exceeded"}}`,
+			expectedKind: APIErrorRateLimit,
+			expectedIs:   ErrRateLimited,
+			expectedCode: "rate_limit_exceeded",
+		},
+		{
+			name:         "Rate limit worded as a quota",
+			provider:     NewXAIProvider(),
+			status:       429,
+			body:         `{"error":{"message":"Rate limit quota exceeded per minute","type":"requests","code":"rate_limit_exceeded"}}`,
+			expectedKind: APIErrorRateLimit,
+			expectedIs:   ErrRateLimited,
+			expectedCode: "rate_limit_exceeded",
+		},
+		{
+			name:         "Rate limit quota without a code",
+			provider:     NewOpenAIProvider(),
+			status:       429,
+			body:         `{"error":{"message":"rate limit quota exceeded per minute"}}`,
+			expectedKind: APIErrorRateLimit,
+			expectedIs:   ErrRateLimited,
+		},
+		{
+			name:         "OpenAI content filter",
+			provider:     NewOpenAIProvider(),
+			status:       400,
+			body:         `{"error":{"message":"The response was filtered","type":"invalid_request_error","code":"content_filter"}}`,
+			expectedKind: APIErrorContentFilter,
+			expectedIs:   ErrContentFilter,
+			expectedCode: "content_filter",
+		},
+		{
+			name:         "OpenAI auth with null code",
+			provider:     NewOpenAIProvider(),
+			status:       401,
+			body:         `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":null}}`,
+			expectedKind: APIErrorAuth,
+			expectedIs:   ErrAuthentication,
+		},
+		{
+			name:         "Anthropic overloaded",
+			provider:     NewAnthropicProvider(),
+			status:       529,
+			body:         `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			expectedKind: APIErrorOverloaded,
+			expectedIs:   ErrOverloaded,
+		},
+		{
+			name:         "Anthropic prompt too long",
+			provider:     NewAnthropicProvider(),
+			status:       400,
+			body:         `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`,
+			expectedKind: APIErrorContextLength,
+			expectedIs:   ErrContextLength,
+		},
+		{
+			name:         "Ollama missing model",
+			provider:     NewOllamaProvider(),
+			status:       404,
+			body:         `{"error":"model \"llama9\" not found, try pulling it first"}`,
+			expectedKind: APIErrorInvalidRequest,
+			expectedIs:   ErrUnexpectedStatusCode,
+		},
+		{
+			name:         "Gateway page",
+			provider:     NewOpenAIProvider(),
+			status:       502,
+			body:         `<html>Bad Gateway</html>`,
+			expectedKind: APIErrorServer,
+			expectedIs:   ErrUnexpectedStatusCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.provider.ClassifyError(tt.status, []byte(tt.body))
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected *APIError, got %T: %v", err, err)
+			}
+			if apiErr.Kind != tt.expectedKind {
+				t.Errorf("Expected kind %s, got %s", tt.expectedKind, apiErr.Kind)
+			}
+			if apiErr.Code != tt.expectedCode {
+				t.Errorf("Expected code %q, got %q", tt.expectedCode, apiErr.Code)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, apiErr.StatusCode)
+			}
+			if !errors.Is(err, tt.expectedIs) {
+				t.Errorf("Expected errors.Is(%v)", tt.expectedIs)
+			}
+		})
+	}
+}
+
+func TestRetryClassification(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         []int
+		body             string
+		expectedRequests int
+		expectError      error
+	}{
+		{name: "Recovers from 503", statuses: []int{503, 200}, expectedRequests: 2},
+		{name: "Recovers from 529", statuses: []int{529, 529, 200}, expectedRequests: 3},
+		{name: "Recovers from 408", statuses: []int{408, 200}, expectedRequests: 2},
+		{name: "Does not retry 400", statuses: []int{400}, expectedRequests: 1, expectError: ErrUnexpectedStatusCode},
+		{
+			name:             "Does not retry spent quota",
+			statuses:         []int{429},
+			body:             `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`,
+			expectedRequests: 1,
+			expectError:      ErrQuotaExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[requests]
+				requests++
+				w.Header().Set("X-Request-Id", "req_123")
+				w.WriteHeader(status)
+				if status == http.StatusOK {
+					w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+					return
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client, _ := NewClient("test-key", server.URL, nil, nil, RetryConfig{
+				MaxRetries:    3,
+				InitialDelay:  time.Millisecond,
+				MaxDelay:      5 * time.Millisecond,
+				BackoffFactor: 2,
+				Jitter:        0.5,
+				Enabled:       true,
+			})
+
+			_, err := client.Send(context.Background(), 0.7, 100,
+				[]Message{CreateTextMessage(RoleUser, "hi")}, "test-model")
+
+			if requests != tt.expectedRequests {
+				t.Errorf("Expected %d requests, got %d", tt.expectedRequests, requests)
+			}
+			if tt.expectError == nil {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.expectError) {
+				t.Errorf("Expected %v, got %v", tt.expectError, err)
+			}
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && (apiErr.RequestID != "req_123" || apiErr.Provider != "openai") {
+				t.Errorf("Expected request id and provider on the error, got %+v", apiErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package beau
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// FallbackTarget is a provider and model a request can fall back to. Empty
+// fields inherit from the client, so a target with only a Model retries the
+// same provider with another model. The client's API key is only inherited by
+// targets on its own base url, it is never sent to another host.
+type FallbackTarget struct {
+	Provider Provider
+	BaseURL  string
+	APIKey   string
+	Model    string
+
+	// RateLimiter for the target's key. Targets on another base url do not share
+	// the client's limiter.
+	RateLimiter *RateLimiter
+}
+
+// FallbackPolicy lists the targets tried in order when the primary target fails
+type FallbackPolicy struct {
+	Targets []FallbackTarget
+
+	// Classifier decides whether an error moves on to the next target,
+	// DefaultFallbackClassifier if nil
+	Classifier func(err error) bool
+}
+
+// ResponseTarget identifies the provider and model that answered a request
+type ResponseTarget struct {
+	Provider string `json:"provider"`
+	BaseURL  string `json:"base_url"`
+	Model    string `json:"model"`
+
+	// Fallback is the position in the fallback chain, 0 for the primary target
+	Fallback int `json:"fallback"`
+
+	// Cached is set when the response came from the cache, its usage is zero
+	Cached bool `json:"cached,omitempty"`
+}
+
+// DefaultFallbackClassifier falls back on failures another target may not
+// share: overloaded and failing servers, rate limits, timeouts and context
+// length overflows. By the time an error gets here retrying is exhausted.
+func DefaultFallbackClassifier(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retriable() || apiErr.Kind == APIErrorContextLength
+	}
+	if errors.Is(err, ErrContextLength) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// WithFallback sets the targets tried when the client's own provider fails
+func (x *Client) WithFallback(policy FallbackPolicy) *Client {
+	x.Fallback = policy
+	return x
+}
+
+// fallbackTarget describes a target in the chain with the client that reaches it
+type fallbackTarget struct {
+	client *Client
+	target ResponseTarget
+}
+
+// fallbackChain returns the client's own target followed by its fallbacks
+func (x *Client) fallbackChain(model string) []fallbackTarget {
+	chain := []fallbackTarget{{
+		client: x,
+		target: ResponseTarget{Provider: x.Provider.Name(), BaseURL: x.BaseURL, Model: model},
+	}}
+
+	for i, target := range x.Fallback.Targets {
+		client := *x
+		client.Fallback = FallbackPolicy{}
+		if target.BaseURL != "" && target.BaseURL != x.BaseURL {
+			client.BaseURL = target.BaseURL
+			client.APIKey = ""
+			client.RateLimiter = target.RateLimiter
+			if target.Provider == nil {
+				client.Provider = DetectProvider(target.BaseURL)
+			}
+		} else if target.RateLimiter != nil {
+			client.RateLimiter = target.RateLimiter
+		}
+		if target.Provider != nil {
+			client.Provider = target.Provider
+		}
+		if target.APIKey != "" {
+			client.APIKey = target.APIKey
+		}
+
+		targetModel := target.Model
+		if targetModel == "" {
+			targetModel = model
+		}
+		chain = append(chain, fallbackTarget{
+			client: &client,
+			target: ResponseTarget{
+				Provider: client.Provider.Name(),
+				BaseURL:  client.BaseURL,
+				Model:    targetModel,
+				Fallback: i + 1,
+			},
+		})
+	}
+	return chain
+}
+
+// shouldFallback reports whether err warrants trying the next target. Nothing
+// is retried once the caller has given up.
+func (x *Client) shouldFallback(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	classifier := x.Fallback.Classifier
+	if classifier == nil {
+		classifier = DefaultFallbackClassifier
+	}
+	return classifier(err)
+}
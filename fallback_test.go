@@ -0,0 +1,341 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Helper function to create a server that fails every request for a model in
+// failures with the given status and body, and answers the rest with the model name
+func newFallbackServer(t *testing.T, failures map[string]int, models *[]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		*models = append(*models, req.Model)
+
+		if status, ok := failures[req.Model]; ok {
+			message := "overloaded"
+			switch status {
+			case http.StatusBadRequest:
+				message = "This model's maximum context length is 8192 tokens"
+			case http.StatusUnauthorized:
+				message = "invalid api key"
+			}
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"error":{"message":%q,"type":"error"}}`, message)
+			return
+		}
+
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", req.Model)
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		json.NewEncoder(w).Encode(ChatCompletionResponse{
+			Model:   req.Model,
+			Choices: []Choice{{Message: CreateTextMessage(RoleAssistant, req.Model)}},
+		})
+	}))
+}
+
+func TestClientFallback(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         map[string]int
+		expectedModel    string
+		expectedFallback int
+		expectedRequests []string
+		expectError      bool
+	}{
+		{
+			name:             "Primary answers",
+			failures:         map[string]int{},
+			expectedModel:    "primary",
+			expectedRequests: []string{"primary"},
+		},
+		{
+			name:             "Overloaded primary",
+			failures:         map[string]int{"primary": 529},
+			expectedModel:    "second",
+			expectedFallback: 1,
+			expectedRequests: []string{"primary", "second"},
+		},
+		{
+			name:             "Context length overflow",
+			failures:         map[string]int{"primary": http.StatusBadRequest, "second": http.StatusServiceUnavailable},
+			expectedModel:    "third",
+			expectedFallback: 2,
+			expectedRequests: []string{"primary", "second", "third"},
+		},
+		{
+			name:             "Authentication does not fall back",
+			failures:         map[string]int{"primary": http.StatusUnauthorized},
+			expectedRequests: []string{"primary"},
+			expectError:      true,
+		},
+		{
+			name:             "Every target fails",
+			failures:         map[string]int{"primary": 529, "second": 529, "third": 529},
+			expectedRequests: []string{"primary", "second", "third"},
+			expectError:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var models []string
+			server := newFallbackServer(t, tt.failures, &models)
+			defer server.Close()
+
+			client, err := NewClient("test-key", server.URL, nil, nil, RetryConfig{})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			client.WithFallback(FallbackPolicy{Targets: []FallbackTarget{{Model: "second"}, {Model: "third"}}})
+
+			result, err := client.Send(context.Background(), 0, 100, []Message{CreateTextMessage(RoleUser, "hi")}, "primary")
+			if fmt.Sprint(models) != fmt.Sprint(tt.expectedRequests) {
+				t.Errorf("Expected requests %v, got %v", tt.expectedRequests, models)
+			}
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result.Target == nil || result.Target.Model != tt.expectedModel || result.Target.Fallback != tt.expectedFallback {
+				t.Errorf("Expected %s at %d to answer, got %+v", tt.expectedModel, tt.expectedFallback, result.Target)
+			}
+			if result.Choices[0].Message.Text() != tt.expectedModel {
+				t.Errorf("Unexpected reply %q", result.Choices[0].Message.Text())
+			}
+		})
+	}
+}
+
+func TestClientFallbackAfterRetries(t *testing.T) {
+	var models []string
+	server := newFallbackServer(t, map[string]int{"primary": http.StatusServiceUnavailable}, &models)
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil, nil, RetryConfig{Enabled: true, MaxRetries: 2, BackoffFactor: 1})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.WithFallback(FallbackPolicy{Targets: []FallbackTarget{{Model: "second"}}})
+
+	result, err := client.Send(context.Background(), 0, 100, []Message{CreateTextMessage(RoleUser, "hi")}, "primary")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fmt.Sprint(models) != "[primary primary primary second]" {
+		t.Errorf("Expected the primary to be retried before falling back, got %v", models)
+	}
+	if result.Target.Model != "second" {
+		t.Errorf("Expected second to answer, got %+v", result.Target)
+	}
+}
+
+func TestClientFallbackAcrossProviders(t *testing.T) {
+	var primaryModels, fallbackModels []string
+	primary := newFallbackServer(t, map[string]int{"grok-4": 529}, &primaryModels)
+	defer primary.Close()
+	fallback := newFallbackServer(t, map[string]int{}, &fallbackModels)
+	defer fallback.Close()
+
+	client, err := NewClient("primary-key", primary.URL, nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.WithFallback(FallbackPolicy{Targets: []FallbackTarget{{
+		Provider: NewOpenAIProvider(),
+		BaseURL:  fallback.URL,
+		APIKey:   "fallback-key",
+		Model:    "gpt-4o",
+	}}})
+
+	stream := make(chan StreamChunk, 10)
+	result, err := client.Send(context.Background(), 0, 100, []Message{CreateTextMessage(RoleUser, "hi")}, "grok-4", WithStream(stream))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.Target.BaseURL != fallback.URL || result.Target.Provider != "openai" {
+		t.Errorf("Expected the fallback server to answer, got %+v", result.Target)
+	}
+
+	var content string
+	var errs []error
+	for chunk := range stream {
+		content += chunk.Content
+		if chunk.Error != nil {
+			errs = append(errs, chunk.Error)
+		}
+	}
+	if content != "gpt-4o" || len(errs) != 0 {
+		t.Errorf("Expected only the fallback's stream, got %q and %v", content, errs)
+	}
+}
+
+func TestClientFallbackAPIKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		apiKey   string
+		expected string
+	}{
+		{name: "Other host without a key", expected: ""},
+		{name: "Other host with its own key", apiKey: "fallback-key", expected: "Bearer fallback-key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var primaryModels, fallbackModels []string
+			primary := newFallbackServer(t, map[string]int{"primary": 529}, &primaryModels)
+			defer primary.Close()
+			fallback := newFallbackServer(t, map[string]int{}, &fallbackModels)
+			defer fallback.Close()
+
+			var authorization string
+			recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+				fallback.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer recorder.Close()
+
+			client, err := NewClient("primary-key", primary.URL, nil, nil, RetryConfig{})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			// a local server that needs no key
+			provider := NewOpenAIProvider()
+			provider.RequireAPIKey = false
+			client.WithFallback(FallbackPolicy{Targets: []FallbackTarget{{
+				Provider: provider,
+				BaseURL:  recorder.URL,
+				APIKey:   tt.apiKey,
+				Model:    "local",
+			}}})
+
+			if _, err := client.Send(context.Background(), 0, 100, []Message{CreateTextMessage(RoleUser, "hi")}, "primary"); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if authorization != tt.expected {
+				t.Errorf("Expected Authorization %q, got %q", tt.expected, authorization)
+			}
+		})
+	}
+}
+
+func TestClientFallbackOnStreamError(t *testing.T) {
+	tests := []struct {
+		name             string
+		partial          string // content the primary streams before failing
+		expectedRequests []string
+		expectedContent  string
+		expectError      bool
+	}{
+		{
+			name:             "Failure on the first event",
+			expectedRequests: []string{"primary", "second"},
+			expectedContent:  "second",
+		},
+		{
+			name:             "Failure after content was streamed",
+			partial:          "Partial",
+			expectedRequests: []string{"primary"},
+			expectedContent:  "Partial",
+			expectError:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var models []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req ChatCompletionRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				models = append(models, req.Model)
+
+				w.Header().Set("Content-Type", "text/event-stream")
+				if req.Model == "primary" {
+					if tt.partial != "" {
+						fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", tt.partial)
+					}
+					fmt.Fprint(w, "data: {\"error\":{\"message\":\"overloaded\",\"type\":\"error\"}}\n\n")
+					return
+				}
+				fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", req.Model)
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			}))
+			defer server.Close()
+
+			client, err := NewClient("test-key", server.URL, nil, nil, RetryConfig{})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			client.WithFallback(FallbackPolicy{Targets: []FallbackTarget{{Model: "second"}}})
+
+			stream := make(chan StreamChunk, 10)
+			_, err = client.Send(context.Background(), 0, 100, []Message{CreateTextMessage(RoleUser, "hi")}, "primary", WithStream(stream))
+			if fmt.Sprint(models) != fmt.Sprint(tt.expectedRequests) {
+				t.Errorf("Expected requests %v, got %v", tt.expectedRequests, models)
+			}
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+
+			var content string
+			var errs []error
+			for chunk := range stream {
+				content += chunk.Content
+				if chunk.Error != nil {
+					errs = append(errs, chunk.Error)
+				}
+			}
+			if content != tt.expectedContent {
+				t.Errorf("Expected streamed %q, got %q", tt.expectedContent, content)
+			}
+			if tt.expectError != (len(errs) == 1) {
+				t.Errorf("Expected an error chunk only on failure, got %v", errs)
+			}
+		})
+	}
+}
+
+func TestDefaultFallbackClassifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "Overloaded", err: newAPIError(529, nil, "", "", "overloaded"), expected: true},
+		{name: "Server error", err: newAPIError(503, nil, "", "", "unavailable"), expected: true},
+		{name: "Rate limited", err: newAPIError(429, nil, "", "", "slow down"), expected: true},
+		{name: "Request timeout", err: newAPIError(408, nil, "", "", "timeout"), expected: true},
+		{name: "Context length", err: newAPIError(400, nil, "context_length_exceeded", "", "too long"), expected: true},
+		{name: "Deadline", err: fmt.Errorf("send: %w", context.DeadlineExceeded), expected: true},
+		{name: "Transport failure", err: errors.New("connection reset"), expected: false},
+		{name: "Quota", err: newAPIError(429, nil, "insufficient_quota", "", "quota"), expected: false},
+		{name: "Invalid request", err: newAPIError(400, nil, "", "", "bad"), expected: false},
+		{name: "Canceled", err: context.Canceled, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultFallbackClassifier(tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
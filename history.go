@@ -0,0 +1,172 @@
+package beau
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrUnknownCheckpoint = fmt.Errorf("unknown checkpoint")
+	ErrInvalidEdit       = fmt.Errorf("invalid message edit")
+)
+
+// HistoryNode is one message in the tree of every message a conversation and
+// its forks have held. The root has ID 0 and no message, the path from the root
+// to a conversation's checkpoint is its current history.
+type HistoryNode struct {
+	ID       int
+	Message  Message
+	Parent   *HistoryNode
+	Children []*HistoryNode
+}
+
+// historyTree is shared by a conversation and its forks
+type historyTree struct {
+	mu     sync.Mutex
+	root   *HistoryNode
+	nodes  map[int]*HistoryNode
+	nextID int
+}
+
+func newHistoryTree() *historyTree {
+	root := &HistoryNode{}
+	return &historyTree{
+		root:   root,
+		nodes:  map[int]*HistoryNode{0: root},
+		nextID: 1,
+	}
+}
+
+func (t *historyTree) add(parent *HistoryNode, message Message) *HistoryNode {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	node := &HistoryNode{ID: t.nextID, Message: message, Parent: parent}
+	t.nextID++
+	t.nodes[node.ID] = node
+	parent.Children = append(parent.Children, node)
+	return node
+}
+
+func (t *historyTree) node(id int) (*HistoryNode, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node, ok := t.nodes[id]
+	return node, ok
+}
+
+// path returns the messages from the root down to node
+func (t *historyTree) path(node *HistoryNode) []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var reversed []Message
+	for n := node; n.Parent != nil; n = n.Parent {
+		reversed = append(reversed, n.Message)
+	}
+	messages := make([]Message, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		messages = append(messages, reversed[i])
+	}
+	return messages
+}
+
+// snapshot deep copies the tree so callers can walk it while it grows
+func (t *historyTree) snapshot() *HistoryNode {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return cloneHistoryNode(t.root, nil)
+}
+
+func cloneHistoryNode(node *HistoryNode, parent *HistoryNode) *HistoryNode {
+	clone := &HistoryNode{ID: node.ID, Message: node.Message, Parent: parent}
+	for _, child := range node.Children {
+		clone.Children = append(clone.Children, cloneHistoryNode(child, clone))
+	}
+	return clone
+}
+
+// initHistory starts a new tree holding the conversation's current messages
+func (c *Conversation) initHistory() {
+	c.tree = newHistoryTree()
+	c.head = c.tree.root
+	for _, message := range c.messages {
+		c.head = c.tree.add(c.head, message)
+	}
+}
+
+// Checkpoint returns a marker for the current point in the conversation to
+// pass to Rollback later
+func (c *Conversation) Checkpoint() int {
+	return c.head.ID
+}
+
+// Rollback returns the conversation to a checkpoint. Nothing is lost, messages
+// added since stay in the history tree and any checkpoint in it, including those
+// of forks, can be returned to.
+func (c *Conversation) Rollback(checkpoint int) error {
+	node, ok := c.tree.node(checkpoint)
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrUnknownCheckpoint, checkpoint)
+	}
+	c.moveTo(node)
+	return nil
+}
+
+// EditMessage replaces the user message at index, as counted in GetMessages,
+// and drops everything after it so the conversation continues from the edit.
+// The original message and its replies stay in the history tree.
+func (c *Conversation) EditMessage(index int, content string) error {
+	if index < 0 || index >= len(c.messages) {
+		return fmt.Errorf("%w: no message at index %d", ErrInvalidEdit, index)
+	}
+	if c.messages[index].Role != RoleUser {
+		return fmt.Errorf("%w: message %d is a %s message", ErrInvalidEdit, index, c.messages[index].Role)
+	}
+
+	node := c.head
+	for i := len(c.messages) - 1; i > index; i-- {
+		node = node.Parent
+	}
+
+	edited := c.messages[index]
+	edited.Content = TextContent(content)
+	c.moveTo(c.tree.add(node.Parent, edited))
+	return nil
+}
+
+// Fork returns a copy of the conversation that continues independently. Both
+// share one history tree, so checkpoints of either work on the other. The fork
+// gets its own copy of a stateful context policy, see ClonablePolicy. Forks are
+// not persisted, see PersistTo.
+func (c *Conversation) Fork() *Conversation {
+	return &Conversation{
+		client:   c.client,
+		messages: append([]Message{}, c.messages...),
+		model:    c.model,
+		options:  append([]RequestOption{}, c.options...),
+		policy:   clonePolicy(c.policy),
+		tree:     c.tree,
+		head:     c.head,
+	}
+}
+
+// History returns a copy of the tree of every message the conversation and its
+// forks have held
+func (c *Conversation) History() *HistoryNode {
+	return c.tree.snapshot()
+}
+
+// moveTo makes node the end of the conversation, rewriting the conversation log
+// to match when there is one
+func (c *Conversation) moveTo(node *HistoryNode) {
+	c.head = node
+	c.messages = c.tree.path(node)
+	c.lastResponse = nil
+
+	if c.storePath != "" {
+		if err := c.PersistTo(c.storePath); err != nil {
+			c.client.Logger.Error("Failed to persist conversation", "path", c.storePath, "error", err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package beau
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// Helper function to collect the text of the messages on the current branch
+func messageTexts(c *Conversation) []string {
+	var texts []string
+	for _, msg := range c.GetMessages() {
+		texts = append(texts, msg.Content.Text())
+	}
+	return texts
+}
+
+// Helper function to count the nodes in a history tree, root excluded
+func countNodes(node *HistoryNode) int {
+	count := len(node.Children)
+	for _, child := range node.Children {
+		count += countNodes(child)
+	}
+	return count
+}
+
+func TestConversationRollbackAndEdit(t *testing.T) {
+	client, err := NewClient("test-key", "http://localhost", nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	conv := client.NewConversation("test-model")
+	conv.AddSystemMessage("system").AddUserMessage("first")
+	checkpoint := conv.Checkpoint()
+	conv.AddAssistantMessage("reply one").AddUserMessage("second").AddAssistantMessage("reply two")
+	end := conv.Checkpoint()
+
+	if err := conv.Rollback(checkpoint); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := messageTexts(conv); len(got) != 2 || got[1] != "first" {
+		t.Fatalf("Unexpected messages after rollback: %v", got)
+	}
+
+	// rolling forward again restores the abandoned branch
+	if err := conv.Rollback(end); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := messageTexts(conv); len(got) != 5 || got[4] != "reply two" {
+		t.Fatalf("Unexpected messages after rolling forward: %v", got)
+	}
+
+	if err := conv.EditMessage(3, "second, edited"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := messageTexts(conv); len(got) != 4 || got[3] != "second, edited" {
+		t.Fatalf("Unexpected messages after edit: %v", got)
+	}
+
+	history := conv.History()
+	if countNodes(history) != 6 {
+		t.Errorf("Expected 6 messages in the history tree, got %d", countNodes(history))
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{name: "Unknown checkpoint", err: conv.Rollback(99), expected: ErrUnknownCheckpoint},
+		{name: "Edit assistant message", err: conv.EditMessage(2, "x"), expected: ErrInvalidEdit},
+		{name: "Edit out of range", err: conv.EditMessage(10, "x"), expected: ErrInvalidEdit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, tt.err)
+			}
+		})
+	}
+}
+
+func TestConversationFork(t *testing.T) {
+	client, err := NewClient("test-key", "http://localhost", nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	conv := client.NewConversation("test-model")
+	conv.AddUserMessage("question")
+
+	fork := conv.Fork()
+	fork.AddAssistantMessage("answer b")
+	conv.AddAssistantMessage("answer a")
+
+	if got := messageTexts(conv); len(got) != 2 || got[1] != "answer a" {
+		t.Errorf("Unexpected original messages: %v", got)
+	}
+	if got := messageTexts(fork); len(got) != 2 || got[1] != "answer b" {
+		t.Errorf("Unexpected fork messages: %v", got)
+	}
+
+	// both continuations hang off the shared question
+	question := conv.History().Children[0]
+	if len(question.Children) != 2 {
+		t.Errorf("Expected 2 branches, got %d", len(question.Children))
+	}
+
+	// checkpoints are shared, so the original can switch to the fork's branch
+	if err := conv.Rollback(fork.Checkpoint()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := messageTexts(conv); got[1] != "answer b" {
+		t.Errorf("Expected to be on the fork's branch, got %v", got)
+	}
+}
+
+func TestRollbackRewritesConversationLog(t *testing.T) {
+	client, err := NewClient("test-key", "http://localhost", nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+
+	conv := client.NewConversation("test-model")
+	conv.AddUserMessage("keep")
+	checkpoint := conv.Checkpoint()
+	if err := conv.PersistTo(path); err != nil {
+		t.Fatalf("Failed to persist conversation: %v", err)
+	}
+	conv.AddAssistantMessage("discard")
+	conv.Rollback(checkpoint)
+
+	resumed, err := client.ResumeConversation(path)
+	if err != nil {
+		t.Fatalf("Failed to resume conversation: %v", err)
+	}
+	if got := messageTexts(resumed); len(got) != 1 || got[0] != "keep" {
+		t.Errorf("Expected the log to match the rolled back conversation, got %v", got)
+	}
+}
+
+func TestConversationForkClonesPolicy(t *testing.T) {
+	client, err := NewClient("test-key", "http://localhost", nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	summarize := NewSummarizePolicy(client, "mini-model", 1000)
+	window := NewSlidingWindowPolicy(1000)
+	conv := client.NewConversation("test-model").WithContextPolicy(ChainPolicies(window, summarize))
+	fork := conv.Fork()
+
+	original, forked := conv.policy.(*chainPolicy), fork.policy.(*chainPolicy)
+	if forked == original {
+		t.Fatal("Expected the fork to get its own chain")
+	}
+	if forked.policies[0] != window {
+		t.Errorf("Expected the stateless window to be shared")
+	}
+	if forked.policies[1] == summarize {
+		t.Errorf("Expected the fork to get its own summary cache")
+	}
+}
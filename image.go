@@ -0,0 +1,439 @@
+package beau
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"strings"
+)
+
+var (
+	ErrUnsupportedImage = fmt.Errorf("unsupported image format")
+	ErrDecodeImage      = fmt.Errorf("failed to decode image")
+	ErrImageTooLarge    = fmt.Errorf("image cannot be reduced to the size limit")
+)
+
+const (
+	MIMETypePNG  = "image/png"
+	MIMETypeJPEG = "image/jpeg"
+	MIMETypeGIF  = "image/gif"
+	MIMETypeWebP = "image/webp"
+
+	DefaultJPEGQuality = 85
+
+	// minJPEGQuality is the lowest quality tried before shrinking further
+	minJPEGQuality = 40
+)
+
+// ImageOptions constrain how an image is prepared before it is sent to a model.
+// Zero values leave that constraint off.
+type ImageOptions struct {
+	MaxDimension int    // longest edge in pixels
+	MaxShortSide int    // shortest edge in pixels, models that tile images ignore detail past it
+	MaxBytes     int    // size of the encoded image, before base64
+	JPEGQuality  int    // quality of re-encoded images, DefaultJPEGQuality if 0
+	Detail       string // low, high or auto
+}
+
+// ImageOptionsForModel returns the constraints past which the model's provider
+// would downscale or reject an image anyway, so the extra pixels only cost
+// bandwidth and tokens
+func ImageOptionsForModel(model string, detail string) ImageOptions {
+	if detail == "" {
+		detail = "auto"
+	}
+	opts := ImageOptions{
+		MaxDimension: 2048,
+		MaxShortSide: 768,
+		MaxBytes:     5 * 1024 * 1024,
+		Detail:       detail,
+	}
+
+	switch {
+	case strings.HasPrefix(model, "claude"):
+		// Anthropic scales anything past 1568 pixels down, and its 5MB limit
+		// applies to the base64 encoding
+		opts.MaxDimension = 1568
+		opts.MaxShortSide = 0
+		opts.MaxBytes = 5 * 1024 * 1024 * 3 / 4
+	case strings.HasPrefix(model, "gpt"), strings.HasPrefix(model, "o1"), strings.HasPrefix(model, "o3"), strings.HasPrefix(model, "o4"):
+		opts.MaxBytes = 20 * 1024 * 1024
+	case strings.HasPrefix(model, "grok"):
+		opts.MaxBytes = 10 * 1024 * 1024
+	}
+
+	// low detail images are seen at 512 pixels whatever their size
+	if detail == "low" {
+		opts.MaxDimension = 512
+		opts.MaxShortSide = 0
+	}
+	return opts
+}
+
+// PreparedImage is an image ready to be sent to a model
+type PreparedImage struct {
+	Data     []byte
+	MIMEType string
+	Width    int
+	Height   int
+	Detail   string
+}
+
+// Base64 returns the image data base64 encoded
+func (p *PreparedImage) Base64() string {
+	return base64.StdEncoding.EncodeToString(p.Data)
+}
+
+// ContentItem returns the image as a message part
+func (p *PreparedImage) ContentItem() ContentItem {
+	return CreateImageBase64Item(p.Base64(), p.MIMEType, p.Detail)
+}
+
+// CreatePreparedImageItem prepares base64 image data, with or without a data
+// url prefix, and returns it as a message part. Unlike CreateImageBase64Item
+// the image is sniffed, oriented and downscaled to meet opts.
+func CreatePreparedImageItem(base64Image string, opts ImageOptions) (ContentItem, error) {
+	data, err := DecodeImageData(base64Image)
+	if err != nil {
+		return ContentItem{}, err
+	}
+	prepared, err := PrepareImage(data, opts)
+	if err != nil {
+		return ContentItem{}, err
+	}
+	return prepared.ContentItem(), nil
+}
+
+// SniffImageType returns the MIME type of image data from its leading bytes
+func SniffImageType(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return MIMETypePNG, nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return MIMETypeJPEG, nil
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return MIMETypeGIF, nil
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return MIMETypeWebP, nil
+	}
+	return "", ErrUnsupportedImage
+}
+
+// DecodeImageData decodes base64 image data, with or without a data url prefix
+func DecodeImageData(encoded string) ([]byte, error) {
+	if strings.HasPrefix(encoded, "data:") {
+		_, payload, found := strings.Cut(encoded, ",")
+		if !found {
+			return nil, fmt.Errorf("%w: malformed data url", ErrDecodeImage)
+		}
+		encoded = payload
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecodeImage, err)
+	}
+	return data, nil
+}
+
+// PrepareImageFile reads an image file and prepares it, see PrepareImage
+func PrepareImageFile(filePath string, opts ImageOptions) (*PreparedImage, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReadImageFile, err)
+	}
+	return PrepareImage(data, opts)
+}
+
+// PrepareImage sniffs the format of image data, applies its EXIF orientation and
+// downscales and re-encodes it until it meets opts. Images that already meet
+// them are passed through untouched. WebP images cannot be decoded with the
+// standard library, so they are passed through or rejected.
+func PrepareImage(data []byte, opts ImageOptions) (*PreparedImage, error) {
+	mimeType, err := SniffImageType(data)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Detail == "" {
+		opts.Detail = "auto"
+	}
+
+	if mimeType == MIMETypeWebP {
+		width, height, ok := webpSize(data)
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed webp", ErrDecodeImage)
+		}
+		targetWidth, targetHeight := fitImage(width, height, opts)
+		if targetWidth != width || targetHeight != height || (opts.MaxBytes > 0 && len(data) > opts.MaxBytes) {
+			return nil, fmt.Errorf("%w: webp images cannot be resized", ErrImageTooLarge)
+		}
+		return &PreparedImage{Data: data, MIMEType: mimeType, Width: width, Height: height, Detail: opts.Detail}, nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecodeImage, err)
+	}
+
+	orientation := 1
+	if mimeType == MIMETypeJPEG {
+		orientation = exifOrientation(data)
+	}
+	width, height := config.Width, config.Height
+	if orientation >= 5 {
+		width, height = height, width
+	}
+
+	targetWidth, targetHeight := fitImage(width, height, opts)
+	if orientation == 1 && targetWidth == width && targetHeight == height && (opts.MaxBytes <= 0 || len(data) <= opts.MaxBytes) {
+		return &PreparedImage{Data: data, MIMEType: mimeType, Width: width, Height: height, Detail: opts.Detail}, nil
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecodeImage, err)
+	}
+	oriented := orientImage(toRGBA(decoded), orientation)
+
+	quality := opts.JPEGQuality
+	if quality <= 0 {
+		quality = DefaultJPEGQuality
+	}
+
+	for {
+		resized := oriented
+		if targetWidth != width || targetHeight != height {
+			resized = resizeImage(oriented, targetWidth, targetHeight)
+		}
+
+		encoded, encodedType, err := encodeImage(resized, quality)
+		if err != nil {
+			return nil, err
+		}
+		if opts.MaxBytes <= 0 || len(encoded) <= opts.MaxBytes {
+			return &PreparedImage{
+				Data:     encoded,
+				MIMEType: encodedType,
+				Width:    targetWidth,
+				Height:   targetHeight,
+				Detail:   opts.Detail,
+			}, nil
+		}
+
+		// trade quality before pixels, then shrink
+		if encodedType == MIMETypeJPEG && quality > minJPEGQuality {
+			quality -= 15
+			continue
+		}
+		targetWidth, targetHeight = targetWidth*3/4, targetHeight*3/4
+		if targetWidth < 16 || targetHeight < 16 {
+			return nil, fmt.Errorf("%w: %d bytes", ErrImageTooLarge, opts.MaxBytes)
+		}
+	}
+}
+
+// fitImage scales width and height down to meet the dimension limits of opts,
+// keeping the aspect ratio
+func fitImage(width, height int, opts ImageOptions) (int, int) {
+	scale := 1.0
+	if longest := max(width, height); opts.MaxDimension > 0 && longest > opts.MaxDimension {
+		scale = float64(opts.MaxDimension) / float64(longest)
+	}
+	if shortest := float64(min(width, height)) * scale; opts.MaxShortSide > 0 && shortest > float64(opts.MaxShortSide) {
+		scale = float64(opts.MaxShortSide) / float64(min(width, height))
+	}
+	if scale == 1.0 {
+		return width, height
+	}
+	return max(1, int(float64(width)*scale+0.5)), max(1, int(float64(height)*scale+0.5))
+}
+
+// encodeImage encodes opaque images as JPEG and the rest as PNG to keep transparency
+func encodeImage(img *image.RGBA, quality int) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if img.Opaque() {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrDecodeImage, err)
+		}
+		return buf.Bytes(), MIMETypeJPEG, nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrDecodeImage, err)
+	}
+	return buf.Bytes(), MIMETypePNG, nil
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// resizeImage downscales with a box filter, averaging every source pixel that
+// falls in a destination pixel
+func resizeImage(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcWidth, srcHeight := src.Rect.Dx(), src.Rect.Dy()
+
+	for dy := 0; dy < height; dy++ {
+		y0 := dy * srcHeight / height
+		y1 := max(y0+1, (dy+1)*srcHeight/height)
+		for dx := 0; dx < width; dx++ {
+			x0 := dx * srcWidth / width
+			x1 := max(x0+1, (dx+1)*srcWidth/width)
+
+			var r, g, b, a, n uint32
+			for y := y0; y < y1; y++ {
+				offset := src.PixOffset(x0, y)
+				for x := x0; x < x1; x++ {
+					r += uint32(src.Pix[offset])
+					g += uint32(src.Pix[offset+1])
+					b += uint32(src.Pix[offset+2])
+					a += uint32(src.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			offset := dst.PixOffset(dx, dy)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// orientImage applies an EXIF orientation so the image displays upright
+func orientImage(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dstWidth, dstHeight := w, h
+	if orientation >= 5 {
+		dstWidth, dstHeight = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var tx, ty int
+			switch orientation {
+			case 2: // mirrored
+				tx, ty = w-1-x, y
+			case 3: // upside down
+				tx, ty = w-1-x, h-1-y
+			case 4: // mirrored upside down
+				tx, ty = x, h-1-y
+			case 5: // mirrored, rotated
+				tx, ty = y, x
+			case 6: // needs a clockwise turn
+				tx, ty = h-1-y, x
+			case 7: // mirrored, rotated the other way
+				tx, ty = h-1-y, w-1-x
+			case 8: // needs a counter clockwise turn
+				tx, ty = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(tx, ty):dst.PixOffset(tx, ty)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
+
+// exifOrientation reads the orientation tag from the EXIF block of a JPEG,
+// returning 1 (upright) when there is none
+func exifOrientation(data []byte) int {
+	pos := 2 // skip the start of image marker
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// the image data starts, metadata comes before it
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	// check the offset before converting it, it may not fit an int
+	offset := order.Uint32(tiff[4:])
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return 1
+	}
+	ifd := int(offset)
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// webpSize reads the canvas size from the header of a WebP image
+func webpSize(data []byte) (int, int, bool) {
+	if len(data) < 30 {
+		return 0, 0, false
+	}
+	chunk := data[12:]
+	switch string(chunk[:4]) {
+	case "VP8X":
+		width := int(chunk[12]) | int(chunk[13])<<8 | int(chunk[14])<<16
+		height := int(chunk[15]) | int(chunk[16])<<8 | int(chunk[17])<<16
+		return width + 1, height + 1, true
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(chunk[9:])
+		return int(bits&0x3FFF) + 1, int(bits>>14&0x3FFF) + 1, true
+	case "VP8 ":
+		width := int(binary.LittleEndian.Uint16(chunk[14:]) & 0x3FFF)
+		height := int(binary.LittleEndian.Uint16(chunk[16:]) & 0x3FFF)
+		return width, height, true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,252 @@
+package beau
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+// Helper function to encode a test image with a red left half and blue right half
+func encodeTestImage(t *testing.T, width, height int, format string, alpha uint8) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.NRGBA{R: 255, A: alpha})
+			} else {
+				img.Set(x, y, color.NRGBA{B: 255, A: alpha})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+	}
+	if err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// Helper function to insert an EXIF block carrying an orientation into a JPEG
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	header := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(segment)+2))
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, header...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestSniffImageType(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{name: "PNG", data: encodeTestImage(t, 4, 4, "png", 255), expected: MIMETypePNG},
+		{name: "JPEG", data: encodeTestImage(t, 4, 4, "jpeg", 255), expected: MIMETypeJPEG},
+		{name: "GIF", data: []byte("GIF89a\x01\x00\x01\x00"), expected: MIMETypeGIF},
+		{name: "WebP", data: []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), expected: MIMETypeWebP},
+		{name: "Text", data: []byte("not an image")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mimeType, err := SniffImageType(tt.data)
+			if tt.expected == "" {
+				if !errors.Is(err, ErrUnsupportedImage) {
+					t.Errorf("Expected ErrUnsupportedImage, got %v", err)
+				}
+				return
+			}
+			if err != nil || mimeType != tt.expected {
+				t.Errorf("Expected %s, got %s (%v)", tt.expected, mimeType, err)
+			}
+		})
+	}
+}
+
+func TestPrepareImage(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           []byte
+		opts           ImageOptions
+		expectedWidth  int
+		expectedHeight int
+		expectedType   string
+		passThrough    bool
+	}{
+		{
+			name:           "Within limits",
+			data:           encodeTestImage(t, 100, 50, "png", 255),
+			opts:           ImageOptions{MaxDimension: 200},
+			expectedWidth:  100,
+			expectedHeight: 50,
+			expectedType:   MIMETypePNG,
+			passThrough:    true,
+		},
+		{
+			name:           "Long edge downscaled",
+			data:           encodeTestImage(t, 400, 200, "png", 255),
+			opts:           ImageOptions{MaxDimension: 100},
+			expectedWidth:  100,
+			expectedHeight: 50,
+			expectedType:   MIMETypeJPEG,
+		},
+		{
+			name:           "Short side downscaled",
+			data:           encodeTestImage(t, 400, 200, "jpeg", 255),
+			opts:           ImageOptions{MaxDimension: 2048, MaxShortSide: 100},
+			expectedWidth:  200,
+			expectedHeight: 100,
+			expectedType:   MIMETypeJPEG,
+		},
+		{
+			name:           "Transparency kept",
+			data:           encodeTestImage(t, 400, 200, "png", 128),
+			opts:           ImageOptions{MaxDimension: 100},
+			expectedWidth:  100,
+			expectedHeight: 50,
+			expectedType:   MIMETypePNG,
+		},
+		{
+			name:           "Rotated by EXIF orientation",
+			data:           withOrientation(encodeTestImage(t, 40, 20, "jpeg", 255), 6),
+			opts:           ImageOptions{MaxDimension: 100},
+			expectedWidth:  20,
+			expectedHeight: 40,
+			expectedType:   MIMETypeJPEG,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prepared, err := PrepareImage(tt.data, tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if prepared.Width != tt.expectedWidth || prepared.Height != tt.expectedHeight {
+				t.Errorf("Expected %dx%d, got %dx%d", tt.expectedWidth, tt.expectedHeight, prepared.Width, prepared.Height)
+			}
+			if prepared.MIMEType != tt.expectedType {
+				t.Errorf("Expected %s, got %s", tt.expectedType, prepared.MIMEType)
+			}
+			if tt.passThrough != bytes.Equal(prepared.Data, tt.data) {
+				t.Errorf("Expected pass through %v", tt.passThrough)
+			}
+
+			decoded, _, err := image.Decode(bytes.NewReader(prepared.Data))
+			if err != nil {
+				t.Fatalf("Prepared image does not decode: %v", err)
+			}
+			if decoded.Bounds().Dx() != tt.expectedWidth || decoded.Bounds().Dy() != tt.expectedHeight {
+				t.Errorf("Encoded image is %v", decoded.Bounds())
+			}
+		})
+	}
+}
+
+func TestPrepareImageOrientationMovesPixels(t *testing.T) {
+	// red is on the left, a clockwise turn puts it on top
+	prepared, err := PrepareImage(withOrientation(encodeTestImage(t, 40, 20, "jpeg", 255), 6), ImageOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(prepared.Data))
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	top, _, _, _ := decoded.At(10, 5).RGBA()
+	_, _, bottom, _ := decoded.At(10, 35).RGBA()
+	if top < 0x8000 || bottom < 0x8000 {
+		t.Errorf("Expected red on top and blue below, got %v and %v", decoded.At(10, 5), decoded.At(10, 35))
+	}
+}
+
+func TestPrepareImageMaxBytes(t *testing.T) {
+	// noise compresses badly, forcing lower quality and then smaller sizes
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	rand.New(rand.NewSource(1)).Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+
+	prepared, err := PrepareImage(buf.Bytes(), ImageOptions{MaxBytes: 20 * 1024})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(prepared.Data) > 20*1024 {
+		t.Errorf("Expected at most 20KB, got %d bytes", len(prepared.Data))
+	}
+
+	if _, err := PrepareImage(buf.Bytes(), ImageOptions{MaxBytes: 10}); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge, got %v", err)
+	}
+}
+
+func TestCreatePreparedImageItem(t *testing.T) {
+	data := encodeTestImage(t, 1200, 1000, "png", 255)
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	// the plain constructor sends the image exactly as given
+	plain := CreateImageBase64Item(encoded, MIMETypePNG, "")
+	if plain.ImageURL.URL != "data:image/png;base64,"+encoded || plain.ImageURL.Detail != "auto" {
+		t.Errorf("Expected the image untouched, got a %d byte url with detail %q", len(plain.ImageURL.URL), plain.ImageURL.Detail)
+	}
+
+	item, err := CreatePreparedImageItem(encoded, ImageOptionsForModel("", "high"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if item.ImageURL.Detail != "high" {
+		t.Errorf("Expected detail high, got %q", item.ImageURL.Detail)
+	}
+	prepared, err := DecodeImageData(item.ImageURL.URL)
+	if err != nil {
+		t.Fatalf("Failed to decode the url: %v", err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(prepared))
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if config.Height != 768 {
+		t.Errorf("Expected the short side downscaled to 768, got %dx%d", config.Width, config.Height)
+	}
+
+	if _, err := CreatePreparedImageItem("not base64!", ImageOptions{}); !errors.Is(err, ErrDecodeImage) {
+		t.Errorf("Expected ErrDecodeImage, got %v", err)
+	}
+}
+
+func TestTIFFOrientationOffsetOutOfRange(t *testing.T) {
+	tiff := []byte("MM\x00\x2a\xff\xff\xff\xff")
+	if orientation := tiffOrientation(tiff); orientation != 1 {
+		t.Errorf("Expected orientation 1, got %d", orientation)
+	}
+}
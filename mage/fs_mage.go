@@ -26,7 +26,7 @@ var _ Mage = &fsMage{}
 func newFSMage(portal *Portal) (*fsMage, error) {
 
 	logger := portal.logger
-	client, err := beau.NewClient(portal.apiKey, portal.baseURL, portal.HTTPClient, logger, portal.RetryConfig)
+	client, err := portal.newClient(logger)
 	if err != nil {
 		return nil, err
 	}
@@ -85,9 +85,9 @@ func (m *fsMage) resetConversationInternals() error {
 		beau.WithMaxTokens(m.portal.maxTokens),
 		beau.WithTools(kit.GetTools()),
 		beau.WithToolChoice("auto"),
-	)
+	).WithContextPolicy(m.portal.NewContextPolicy(m.client))
 	m.conversation = conversation
-	m.kit = kit
+	m.kit = kit.WithRedactor(m.portal.redactor)
 	return nil
 }
 
@@ -146,19 +146,15 @@ func (m *fsMage) Execute(ctx context.Context, command string) (string, error) {
 		// If there are no tool calls, print the response and break
 		if len(response.ToolCalls) == 0 {
 
-			asString, ok := response.Content.(string)
-			if !ok {
-				return "", fmt.Errorf("response content is not a string")
-			}
 			// Append the final response to the result builder
-			m.resultBuilder.WriteString(asString)
+			m.resultBuilder.WriteString(response.Text())
 			break
 		}
 
 		m.portal.logger.Info("Found tool calls", "count", len(response.ToolCalls))
 
 		// Handle the tool calls
-		m.kit.HandleResponseCalls(response)
+		m.kit.HandleResponseCallsContext(ctx, response)
 	}
 
 	return m.resultBuilder.String(), nil
@@ -26,8 +26,8 @@ var _ Mage = &imMage{}
 func newIMMage(portal *Portal) (*imMage, error) {
 	logger := portal.logger.WithGroup("im_mage")
 
-	// Create XAI client for conversation
-	client, err := beau.NewClient(portal.apiKey, portal.baseURL, portal.HTTPClient, logger, portal.RetryConfig)
+	// Create client for conversation
+	client, err := portal.newClient(logger)
 	if err != nil {
 		return nil, err
 	}
@@ -41,8 +41,13 @@ func newIMMage(portal *Portal) (*imMage, error) {
 		resultBuilder:   strings.Builder{},
 	}
 
+	visionClient, err := portal.newClient(logger.WithGroup("image_analysis"))
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize the image kit with portal's API configuration
-	imMage.kit = imkit.GetImageKit(portal.apiKey, portal.baseURL, logger, func(isError bool, id string, result interface{}) {
+	imMage.kit = imkit.GetImageKitForClient(visionClient, func(isError bool, id string, result interface{}) {
 		if isError {
 			logger.Error("Tool execution error", "id", id, "error", result)
 		} else {
@@ -61,7 +66,7 @@ func newIMMage(portal *Portal) (*imMage, error) {
 				imMage.resultBuilder.WriteString(fmt.Sprintf("%v", v))
 			}
 		}
-	}, portal.imageModel, portal.projectBounds) // Using portal's image model and project bounds
+	}, portal.imageModel, portal.projectBounds).WithRedactor(portal.redactor) // Using portal's image model and project bounds
 
 	err = imMage.resetConversationInternals()
 	if err != nil {
@@ -81,7 +86,7 @@ func (m *imMage) resetConversationInternals() error {
 		beau.WithMaxTokens(m.portal.maxTokens),
 		beau.WithTools(m.kit.GetTools()),
 		beau.WithToolChoice("auto"),
-	)
+	).WithContextPolicy(m.portal.NewContextPolicy(m.client))
 
 	return nil
 }
@@ -140,19 +145,15 @@ func (m *imMage) Execute(ctx context.Context, command string) (string, error) {
 
 		// If there are no tool calls, append the response and break
 		if len(response.ToolCalls) == 0 {
-			asString, ok := response.Content.(string)
-			if !ok {
-				return "", fmt.Errorf("response content is not a string")
-			}
 			// Append the final response to the result builder
-			m.resultBuilder.WriteString(asString)
+			m.resultBuilder.WriteString(response.Text())
 			break
 		}
 
 		m.portal.logger.Info("Found tool calls", "count", len(response.ToolCalls))
 
 		// Handle the tool calls
-		m.kit.HandleResponseCalls(response)
+		m.kit.HandleResponseCallsContext(ctx, response)
 	}
 
 	return m.resultBuilder.String(), nil
@@ -2,7 +2,6 @@ package mage
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
@@ -10,7 +9,6 @@ import (
 	"runtime"
 	"time"
 
-	"github.com/bosley/beau"
 	"github.com/bosley/beau/toolkit"
 )
 
@@ -35,6 +33,11 @@ func GetMageKit(config MageKitConfig) *toolkit.LlmToolKit {
 		WithCallback(config.Callback)
 }
 
+type analyzeImageWithMageArgs struct {
+	ImagePath string `json:"image_path" description:"Absolute path to the image file to analyze (must use full path like /home/user/project/image.png)"`
+	Query     string `json:"query" description:"Specific question or instruction about the image. Be descriptive. Examples: 'describe what you see in detail', 'identify all objects in the image', 'what is the dominant color scheme', 'are there any people in this image'"`
+}
+
 func getImageAnalysisTool(config MageKitConfig) toolkit.LlmTool {
 	analyzeWithMage := func(imagePath string, query string) (string, error) {
 		if config.ImageMage == nil {
@@ -57,34 +60,10 @@ func getImageAnalysisTool(config MageKitConfig) toolkit.LlmTool {
 		return result, nil
 	}
 
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "analyze_image_with_mage",
-			Description: "Use the image mage to analyze an image and answer questions about it. The mage will handle image loading and vision model interaction.",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"image_path": map[string]interface{}{
-						"type":        "string",
-						"description": "Absolute path to the image file to analyze (must use full path like /home/user/project/image.png)",
-					},
-					"query": map[string]interface{}{
-						"type":        "string",
-						"description": "Specific question or instruction about the image. Be descriptive. Examples: 'describe what you see in detail', 'identify all objects in the image', 'what is the dominant color scheme', 'are there any people in this image'",
-					},
-				},
-				"required": []string{"image_path", "query"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				ImagePath string `json:"image_path"`
-				Query     string `json:"query"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("failed to parse arguments: %w", err)
-			}
-
+	return toolkit.NewTypedTool(
+		"analyze_image_with_mage",
+		"Use the image mage to analyze an image and answer questions about it. The mage will handle image loading and vision model interaction.",
+		func(args analyzeImageWithMageArgs) (interface{}, error) {
 			if args.ImagePath == "" {
 				return nil, fmt.Errorf("image_path is required")
 			}
@@ -97,6 +76,10 @@ func getImageAnalysisTool(config MageKitConfig) toolkit.LlmTool {
 	)
 }
 
+type executeFilesystemOperationArgs struct {
+	Command string `json:"command" description:"Natural language command for file operation. Examples: 'list all files in /home/user/project', 'read the contents of /home/user/project/main.go', 'create a new file at /home/user/project/test.txt with content Hello World', 'analyze /home/user/project/large.log and summarize its contents', 'search for TODO comments in /home/user/project/src'"`
+}
+
 func getFilesystemTool(config MageKitConfig) toolkit.LlmTool {
 	executeFileOperation := func(command string) (string, error) {
 		if config.FSMage == nil {
@@ -116,29 +99,10 @@ func getFilesystemTool(config MageKitConfig) toolkit.LlmTool {
 		return result, nil
 	}
 
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "execute_filesystem_operation",
-			Description: "Use the filesystem mage to perform file operations. The mage has tools for reading, writing, listing, analyzing files. It automatically handles large files by chunking or summarizing. ALWAYS use absolute paths.",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"command": map[string]interface{}{
-						"type":        "string",
-						"description": "Natural language command for file operation. Examples: 'list all files in /home/user/project', 'read the contents of /home/user/project/main.go', 'create a new file at /home/user/project/test.txt with content Hello World', 'analyze /home/user/project/large.log and summarize its contents', 'search for TODO comments in /home/user/project/src'",
-					},
-				},
-				"required": []string{"command"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				Command string `json:"command"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("failed to parse arguments: %w", err)
-			}
-
+	return toolkit.NewTypedTool(
+		"execute_filesystem_operation",
+		"Use the filesystem mage to perform file operations. The mage has tools for reading, writing, listing, analyzing files. It automatically handles large files by chunking or summarizing. ALWAYS use absolute paths.",
+		func(args executeFilesystemOperationArgs) (interface{}, error) {
 			if args.Command == "" {
 				return nil, fmt.Errorf("command is required")
 			}
@@ -155,8 +119,13 @@ func GetUnifiedMageKit(portal *Portal, logger *slog.Logger, callback toolkit.Kit
 		WithCallback(callback)
 }
 
+type taskMageArgs struct {
+	MageType string `json:"mage_type" description:"Type of mage to use. Must be exactly one of: 'image', 'filesystem', 'web', or 'shell'" enum:"image,filesystem,web,shell"`
+	Command  string `json:"command" description:"Natural language command for the mage. Examples for image: 'analyze /home/user/project/screenshot.png and describe the UI elements'. Examples for filesystem: 'read /home/user/project/config.json', 'list all Python files in /home/user/project/src'. Examples for web: 'navigate to https://example.com and take a fullpage screenshot'. Examples for shell: 'list all running processes', 'execute ls -la in the project directory', 'show me the system information'."`
+}
+
 func getUnifiedMageTool(portal *Portal, logger *slog.Logger) toolkit.LlmTool {
-	executeMageTask := func(mageType string, command string) (string, error) {
+	executeMageTask := func(ctx context.Context, mageType string, command string) (string, error) {
 		var variant MageVariant
 		switch mageType {
 		case "image", "vision":
@@ -176,7 +145,8 @@ func getUnifiedMageTool(portal *Portal, logger *slog.Logger) toolkit.LlmTool {
 			return "", fmt.Errorf("failed to summon %s mage: %w", mageType, err)
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		// the caller's context carries cancellation and the tool call's span
+		ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 		defer cancel()
 
 		switch variant {
@@ -344,36 +314,11 @@ Remember: Commands execute in a subprocess with timeout protection. Long-running
 		return result, nil
 	}
 
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "task_mage",
-			Description: "Task a specialized mage to perform operations. The mage will use its own tools to complete the task. Four types available: 'image' for image/vision analysis, 'filesystem' for file operations (read/write/list/analyze), 'web' for browser automation and screenshots, 'shell' for executing system commands.",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"mage_type": map[string]interface{}{
-						"type":        "string",
-						"description": "Type of mage to use. Must be exactly one of: 'image', 'filesystem', 'web', or 'shell'",
-						"enum":        []string{"image", "filesystem", "web", "shell"},
-					},
-					"command": map[string]interface{}{
-						"type":        "string",
-						"description": "Natural language command for the mage. Examples for image: 'analyze /home/user/project/screenshot.png and describe the UI elements'. Examples for filesystem: 'read /home/user/project/config.json', 'list all Python files in /home/user/project/src'. Examples for web: 'navigate to https://example.com and take a fullpage screenshot'. Examples for shell: 'list all running processes', 'execute ls -la in the project directory', 'show me the system information'.",
-					},
-				},
-				"required": []string{"mage_type", "command"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				MageType string `json:"mage_type"`
-				Command  string `json:"command"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("failed to parse arguments: %w", err)
-			}
-
-			return executeMageTask(args.MageType, args.Command)
+	return toolkit.NewTypedContextTool(
+		"task_mage",
+		"Task a specialized mage to perform operations. The mage will use its own tools to complete the task. Four types available: 'image' for image/vision analysis, 'filesystem' for file operations (read/write/list/analyze), 'web' for browser automation and screenshots, 'shell' for executing system commands.",
+		func(ctx context.Context, args taskMageArgs) (interface{}, error) {
+			return executeMageTask(ctx, args.MageType, args.Command)
 		},
 	)
 }
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
 
 	"github.com/bosley/beau"
+	"github.com/bosley/beau/tracing"
 )
 
 var (
@@ -21,6 +23,25 @@ type PortalConfig struct {
 
 	HTTPClient  *http.Client
 	RetryConfig beau.RetryConfig
+	Provider    beau.Provider // if nil, detected from BaseURL
+
+	// Shared by every client the portal creates, so summoned mages draw on one budget
+	RateLimiter *beau.RateLimiter
+
+	// Targets every mage client falls back to when the provider fails
+	Fallback beau.FallbackPolicy
+
+	// Spend accounting shared by every mage. Meter totals the spend of all mages
+	// and is created when nil; Budget refuses requests once spent.
+	Pricing *beau.Pricing
+	Meter   *beau.CostMeter
+	Budget  *beau.Budget
+
+	// Wraps the requests of every mage client, see beau.Client.Use
+	Middleware []beau.Middleware
+
+	// Removes secrets from the logs, tool results and requests of every mage
+	Redactor *beau.Redactor
 
 	PrimaryModel string // Must be able to do function calling
 	ImageModel   string // For image understanding
@@ -29,6 +50,10 @@ type PortalConfig struct {
 	MaxTokens   int
 	Temperature float64
 
+	// Estimated tokens of history sent with each request, older turns are
+	// summarized with the MiniModel past it. 0 sends the full history.
+	ContextBudget int
+
 	// Project bounds for path validation
 	ProjectBounds []beau.ProjectBounds
 }
@@ -59,13 +84,22 @@ type Portal struct {
 	baseURL     string
 	HTTPClient  *http.Client
 	RetryConfig beau.RetryConfig
+	provider    beau.Provider
+	rateLimiter *beau.RateLimiter
+	fallback    beau.FallbackPolicy
+	pricing     *beau.Pricing
+	meter       *beau.CostMeter
+	budget      *beau.Budget
+	middleware  []beau.Middleware
+	redactor    *beau.Redactor
 
 	primaryModel string
 	imageModel   string
 	miniModel    string
 
-	maxTokens   int
-	temperature float64
+	maxTokens     int
+	temperature   float64
+	contextBudget int
 
 	// Project bounds for path validation
 	projectBounds []beau.ProjectBounds
@@ -78,35 +112,154 @@ func NewPortal(config PortalConfig) *Portal {
 	if config.Temperature == 0 {
 		config.Temperature = DefaultTemperature
 	}
+	if config.Meter == nil {
+		config.Meter = beau.NewCostMeter(nil)
+	}
 
 	return &Portal{
-		logger:        config.Logger,
+		logger:        beau.RedactLogger(config.Logger, config.Redactor),
 		apiKey:        config.APIKey,
 		baseURL:       config.BaseURL,
 		HTTPClient:    config.HTTPClient,
 		RetryConfig:   config.RetryConfig,
+		provider:      config.Provider,
+		rateLimiter:   config.RateLimiter,
+		fallback:      config.Fallback,
+		pricing:       config.Pricing,
+		meter:         config.Meter,
+		budget:        config.Budget,
+		middleware:    config.Middleware,
+		redactor:      config.Redactor,
 		primaryModel:  config.PrimaryModel,
 		imageModel:    config.ImageModel,
 		miniModel:     config.MiniModel,
 		maxTokens:     config.MaxTokens,
 		temperature:   config.Temperature,
+		contextBudget: config.ContextBudget,
 		projectBounds: config.ProjectBounds,
 	}
 }
 
+// newClient creates a client configured from the portal for a mage to use
+func (p *Portal) newClient(logger *slog.Logger) (*beau.Client, error) {
+	client, err := beau.NewClient(p.apiKey, p.baseURL, p.HTTPClient, logger, p.RetryConfig)
+	if err != nil {
+		return nil, err
+	}
+	return client.WithProvider(p.provider).
+		WithRateLimiter(p.rateLimiter).
+		WithFallback(p.fallback).
+		WithPricing(p.pricing).
+		WithBudget(p.budget).
+		WithCostMeter(p.meter).
+		WithRedactor(p.redactor).
+		Use(p.middleware...), nil
+}
+
+// Spend returns what every mage summoned from the portal has spent
+func (p *Portal) Spend() beau.Spend {
+	return p.meter.Spend()
+}
+
+// NewContextPolicy creates the context policy for a conversation on client, nil
+// when the portal has no context budget. Policies hold state, so every
+// conversation needs its own.
+func (p *Portal) NewContextPolicy(client *beau.Client) beau.ContextPolicy {
+	if p.contextBudget <= 0 {
+		return nil
+	}
+	model := p.miniModel
+	if model == "" {
+		model = p.primaryModel
+	}
+	return beau.ChainPolicies(
+		beau.NewSummarizePolicy(client, model, p.contextBudget),
+		beau.NewDropToolResultsPolicy(p.contextBudget, 2),
+		beau.NewSlidingWindowPolicy(p.contextBudget),
+	)
+}
+
 func (p *Portal) Summon(variant MageVariant) (Mage, error) {
+	// every mage gets a meter of its own that rolls up into the portal's
+	scoped := *p
+	scoped.meter = beau.NewCostMeter(p.meter)
+
+	var mage Mage
+	var err error
 	switch variant {
 	case Mage_FS:
-		return newFSMage(p)
+		mage, err = newFSMage(&scoped)
 	case Mage_IM:
-		return newIMMage(p)
+		mage, err = newIMMage(&scoped)
 	case Mage_WB:
-		return newWebMage(p)
+		mage, err = newWebMage(&scoped)
 	case Mage_SH:
-		return newShellMage(p)
+		mage, err = newShellMage(&scoped)
 	default:
 		return nil, fmt.Errorf("unknown variant: %s", variant)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return &meteredMage{Mage: mage, variant: variant, meter: scoped.meter, logger: p.logger}, nil
+}
+
+// Metered is implemented by every summoned mage
+type Metered interface {
+	// LastSpend returns what the most recent Execute spent
+	LastSpend() beau.Spend
+
+	// Spend returns what the mage has spent since it was summoned
+	Spend() beau.Spend
+}
+
+// meteredMage records what each Execute of a mage spent
+type meteredMage struct {
+	Mage
+	variant MageVariant
+	meter   *beau.CostMeter
+	logger  *slog.Logger
+
+	mu   sync.Mutex
+	last beau.Spend
+}
+
+var _ Metered = &meteredMage{}
+
+func (m *meteredMage) Execute(ctx context.Context, command string) (string, error) {
+	ctx, span := tracing.Start(ctx, "mage.execute")
+	defer span.End()
+	span.SetAttribute("mage", string(m.variant))
+
+	before := m.meter.Spend()
+	result, err := m.Mage.Execute(ctx, command)
+	spent := m.meter.Spend().Sub(before)
+
+	span.SetAttribute("requests", spent.Requests)
+	span.SetAttribute("tokens", spent.Usage.TotalTokens)
+	span.SetAttribute("cost_usd", spent.Cost)
+	span.RecordError(err)
+
+	m.mu.Lock()
+	m.last = spent
+	m.mu.Unlock()
+
+	m.logger.Info("Mage execution spend",
+		"mage", m.variant,
+		"requests", spent.Requests,
+		"tokens", spent.Usage.TotalTokens,
+		"cost", spent.Cost)
+	return result, err
+}
+
+func (m *meteredMage) LastSpend() beau.Spend {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+func (m *meteredMage) Spend() beau.Spend {
+	return m.meter.Spend()
 }
 
 // ----------------------------------------
@@ -0,0 +1,183 @@
+package mage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bosley/beau"
+	"github.com/bosley/beau/beautest"
+)
+
+// Helper function to create a portal talking to the fake server
+func newTestPortal(t *testing.T, server *beautest.Server) (*Portal, string) {
+	dir := t.TempDir()
+	return NewPortal(PortalConfig{
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		APIKey:       "test-key",
+		BaseURL:      server.URL,
+		PrimaryModel: "test-model",
+		ImageModel:   "test-model",
+		MiniModel:    "test-model",
+		ProjectBounds: []beau.ProjectBounds{
+			{Name: "test", Description: "Test project", ABSPath: dir},
+		},
+	}), dir
+}
+
+func TestFSMageExecute(t *testing.T) {
+	server := beautest.NewServer()
+	defer server.Close()
+	portal, dir := newTestPortal(t, server)
+
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	server.Enqueue(
+		beautest.ToolCall("list_directory", map[string]string{"directory_path": dir}),
+		beautest.Text("The directory holds notes.txt"),
+	)
+
+	mage, err := portal.Summon(Mage_FS)
+	if err != nil {
+		t.Fatalf("Failed to summon mage: %v", err)
+	}
+
+	result, err := mage.Execute(context.Background(), "what is in the project?")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(result, "notes.txt") || !strings.HasSuffix(result, "The directory holds notes.txt") {
+		t.Errorf("Unexpected result: %s", result)
+	}
+
+	server.AssertRequestCount(t, 2)
+	server.AssertMessage(t, 0, beau.RoleSystem, dir)
+	server.AssertMessage(t, 0, beau.RoleUser, "what is in the project?")
+	server.AssertMessage(t, 1, beau.RoleTool, "notes.txt")
+
+	tools := server.Requests()[0].Tools
+	if len(tools) == 0 {
+		t.Errorf("Expected the filesystem tools to be offered")
+	}
+
+	// both requests are charged to the mage and roll up into the portal
+	if spend := mage.(Metered).LastSpend(); spend.Requests != 2 {
+		t.Errorf("Expected the execution to span 2 requests, got %+v", spend)
+	}
+	if spend := portal.Spend(); spend.Requests != 2 {
+		t.Errorf("Expected the portal to total 2 requests, got %+v", spend)
+	}
+}
+
+func TestShellMageExecute(t *testing.T) {
+	tests := []struct {
+		name           string
+		replies        []beautest.Reply
+		expectedResult string
+		expectedTool   string
+	}{
+		{
+			name: "Command output is fed back",
+			replies: []beautest.Reply{
+				beautest.ToolCall("execute_command", map[string]string{"command": "echo beau-shell-test"}),
+				beautest.Text("ran it"),
+			},
+			expectedResult: "beau-shell-test",
+			expectedTool:   "beau-shell-test",
+		},
+		{
+			name: "Invalid arguments are reported to the model",
+			replies: []beautest.Reply{
+				beautest.ToolCall("execute_command", map[string]string{}),
+				beautest.Text("sorry"),
+			},
+			expectedResult: "sorry",
+			expectedTool:   "Error: invalid arguments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := beautest.NewServer(tt.replies...)
+			defer server.Close()
+			portal, _ := newTestPortal(t, server)
+
+			mage, err := portal.Summon(Mage_SH)
+			if err != nil {
+				t.Fatalf("Failed to summon mage: %v", err)
+			}
+
+			result, err := mage.Execute(context.Background(), "run something")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !strings.Contains(result, tt.expectedResult) {
+				t.Errorf("Expected result to contain %q, got %q", tt.expectedResult, result)
+			}
+
+			server.AssertRequestCount(t, 2)
+			server.AssertMessage(t, 1, beau.RoleTool, tt.expectedTool)
+		})
+	}
+}
+
+func TestMageExecuteCancelled(t *testing.T) {
+	server := beautest.NewServer(beautest.Text("never sent"))
+	defer server.Close()
+	portal, _ := newTestPortal(t, server)
+
+	mage, err := portal.Summon(Mage_FS)
+	if err != nil {
+		t.Fatalf("Failed to summon mage: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := mage.Execute(ctx, "anything"); err == nil {
+		t.Errorf("Expected cancellation error")
+	}
+	server.AssertRequestCount(t, 0)
+}
+
+func TestMagesInheritPortalMiddleware(t *testing.T) {
+	server := beautest.NewServer()
+	defer server.Close()
+
+	var models []string
+	portal := NewPortal(PortalConfig{
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		APIKey:       "test-key",
+		BaseURL:      server.URL,
+		PrimaryModel: "test-model",
+		ProjectBounds: []beau.ProjectBounds{
+			{Name: "test", Description: "Test project", ABSPath: t.TempDir()},
+		},
+		Middleware: []beau.Middleware{func(next beau.Handler) beau.Handler {
+			return func(ctx context.Context, req *beau.ChatCompletionRequest) (*beau.ChatCompletionResponse, error) {
+				models = append(models, req.Model)
+				return next(ctx, req)
+			}
+		}},
+	})
+
+	server.Enqueue(beautest.Text("done"))
+	mage, err := portal.Summon(Mage_FS)
+	if err != nil {
+		t.Fatalf("Failed to summon mage: %v", err)
+	}
+	if _, err := mage.Execute(context.Background(), "say done"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(models) != 1 || models[0] != "test-model" {
+		t.Errorf("Expected the mage request to pass through the portal middleware, got %v", models)
+	}
+}
@@ -28,7 +28,7 @@ var _ Mage = &shellMage{}
 func newShellMage(portal *Portal) (*shellMage, error) {
 	logger := portal.logger.WithGroup("shell_mage")
 
-	client, err := beau.NewClient(portal.apiKey, portal.baseURL, portal.HTTPClient, logger, portal.RetryConfig)
+	client, err := portal.newClient(logger)
 	if err != nil {
 		return nil, err
 	}
@@ -46,7 +46,7 @@ func newShellMage(portal *Portal) (*shellMage, error) {
 	shellMage.kit = shellkit.GetShellKit(logger, func(isError bool, id string, result interface{}) {
 		if isError {
 			logger.Error("Tool execution error", "id", id, "error", result)
-			shellMage.conversation.AddToolResult(id, fmt.Sprintf("Error: %v", result))
+			shellMage.conversation.AddToolError(id, fmt.Sprintf("Error: %v", result))
 			shellMage.resultBuilder.WriteString(fmt.Sprintf("Error: %v\n", result))
 		} else {
 			logger.Info("Tool execution result", "id", id)
@@ -80,7 +80,7 @@ func newShellMage(portal *Portal) (*shellMage, error) {
 				shellMage.resultBuilder.WriteString(result + "\n")
 			}
 		}
-	}, portal.projectBounds)
+	}, portal.projectBounds).WithRedactor(portal.redactor)
 
 	err = shellMage.resetConversationInternals()
 	if err != nil {
@@ -107,7 +107,7 @@ func (m *shellMage) resetConversationInternals() error {
 		beau.WithMaxTokens(m.portal.maxTokens),
 		beau.WithTools(m.kit.GetTools()),
 		beau.WithToolChoice("auto"),
-	)
+	).WithContextPolicy(m.portal.NewContextPolicy(m.client))
 
 	return nil
 }
@@ -158,16 +158,12 @@ Project directory: %s`,
 		}
 
 		if len(response.ToolCalls) == 0 {
-			asString, ok := response.Content.(string)
-			if !ok {
-				return "", fmt.Errorf("response content is not a string")
-			}
-			m.resultBuilder.WriteString(asString)
+			m.resultBuilder.WriteString(response.Text())
 			break
 		}
 
 		m.portal.logger.Info("Found tool calls", "count", len(response.ToolCalls))
-		m.kit.HandleResponseCalls(response)
+		m.kit.HandleResponseCallsContext(ctx, response)
 	}
 
 	return m.resultBuilder.String(), nil
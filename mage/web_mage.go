@@ -25,7 +25,7 @@ var _ Mage = &webMage{}
 func newWebMage(portal *Portal) (*webMage, error) {
 	logger := portal.logger.WithGroup("web_mage")
 
-	client, err := beau.NewClient(portal.apiKey, portal.baseURL, portal.HTTPClient, logger, portal.RetryConfig)
+	client, err := portal.newClient(logger)
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +67,7 @@ func newWebMage(portal *Portal) (*webMage, error) {
 				webMage.resultBuilder.WriteString(fmt.Sprintf("%v\n", v))
 			}
 		}
-	}, portal.projectBounds)
+	}, portal.projectBounds).WithRedactor(portal.redactor)
 
 	err = webMage.resetConversationInternals()
 	if err != nil {
@@ -87,7 +87,7 @@ func (m *webMage) resetConversationInternals() error {
 		beau.WithMaxTokens(m.portal.maxTokens),
 		beau.WithTools(m.kit.GetTools()),
 		beau.WithToolChoice("auto"),
-	)
+	).WithContextPolicy(m.portal.NewContextPolicy(m.client))
 
 	return nil
 }
@@ -142,18 +142,14 @@ func (m *webMage) Execute(ctx context.Context, command string) (string, error) {
 
 		// If there are no tool calls, append the response and break
 		if len(response.ToolCalls) == 0 {
-			asString, ok := response.Content.(string)
-			if !ok {
-				return "", fmt.Errorf("response content is not a string")
-			}
-			m.resultBuilder.WriteString(asString)
+			m.resultBuilder.WriteString(response.Text())
 			break
 		}
 
 		m.portal.logger.Info("Found tool calls", "count", len(response.ToolCalls))
 
 		// Handle the tool calls
-		m.kit.HandleResponseCalls(response)
+		m.kit.HandleResponseCallsContext(ctx, response)
 	}
 
 	return m.resultBuilder.String(), nil
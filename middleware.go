@@ -0,0 +1,113 @@
+package beau
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrHandlerCalledTwice = fmt.Errorf("next handler called more than once")
+)
+
+// Handler answers a chat completion request
+type Handler func(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error)
+
+// Middleware wraps the handler that sends requests. It may inspect or modify
+// the request before calling next, observe or replace the response next
+// returns, or answer without calling next at all. Call next at most once, a
+// request is sent only once and a second call fails with ErrHandlerCalledTwice.
+// Replace req.Messages rather than editing it in place, the slice is shared
+// with the conversation.
+type Middleware func(next Handler) Handler
+
+// Use appends middleware to the client's chain. The first middleware added is
+// the outermost, so it sees the request first and the response last.
+func (x *Client) Use(middleware ...Middleware) *Client {
+	x.Middleware = append(x.Middleware, middleware...)
+	return x
+}
+
+// serve runs req through the middleware chain to the provider
+func (x *Client) serve(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	reached := false
+	var handler Handler = func(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+		// a second send would write to a stream that is already closed
+		if reached {
+			return nil, ErrHandlerCalledTwice
+		}
+		reached = true
+		// redact last, so no middleware can put a secret back
+		req.Messages = x.Redactor.RedactMessages(req.Messages)
+		return x.dispatch(ctx, *req)
+	}
+	for i := len(x.Middleware) - 1; i >= 0; i-- {
+		handler = x.Middleware[i](handler)
+	}
+
+	result, err := handler(ctx, req)
+
+	// a middleware answered without the provider, the stream still expects chunks
+	if stream := req.streamChannel(); !reached && stream != nil {
+		if err != nil {
+			stream <- StreamChunk{Error: err}
+			close(stream)
+		} else if result != nil {
+			replayStream(result, stream)
+		} else {
+			close(stream)
+		}
+	}
+	return result, err
+}
+
+// streamChannel returns the channel a streaming request forwards chunks to,
+// nil when it does not stream
+func (r *ChatCompletionRequest) streamChannel() chan StreamChunk {
+	if !r.Stream || r.streamConfig == nil {
+		return nil
+	}
+	return r.streamConfig.Channel
+}
+
+// ObserveStream calls observe with every chunk streamed for req before the
+// caller receives it. It does nothing when req does not stream.
+func ObserveStream(req *ChatCompletionRequest, observe func(chunk StreamChunk)) {
+	original := req.streamChannel()
+	if original == nil {
+		return
+	}
+
+	proxy := make(chan StreamChunk, cap(original))
+	config := *req.streamConfig
+	config.Channel = proxy
+	req.streamConfig = &config
+
+	go func() {
+		for chunk := range proxy {
+			observe(chunk)
+			original <- chunk
+		}
+		close(original)
+	}()
+}
+
+type requestHeadersKey struct{}
+
+// WithRequestHeaders returns a context whose requests carry headers, letting
+// middleware inject them by passing the context on to next
+func WithRequestHeaders(ctx context.Context, headers http.Header) context.Context {
+	merged := requestHeaders(ctx).Clone()
+	if merged == nil {
+		merged = http.Header{}
+	}
+	for key, values := range headers {
+		merged[http.CanonicalHeaderKey(key)] = values
+	}
+	return context.WithValue(ctx, requestHeadersKey{}, merged)
+}
+
+func requestHeaders(ctx context.Context) http.Header {
+	headers, _ := ctx.Value(requestHeadersKey{}).(http.Header)
+	return headers
+}
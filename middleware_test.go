@@ -0,0 +1,171 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestClientMiddleware(t *testing.T) {
+	var received ChatCompletionRequest
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		header = r.Header.Get("X-Audit-Id")
+		json.NewEncoder(w).Encode(ChatCompletionResponse{
+			Choices: []Choice{{Message: CreateTextMessage(RoleAssistant, "reply")}},
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+				order = append(order, name+" request")
+				resp, err := next(ctx, req)
+				order = append(order, name+" response")
+				return resp, err
+			}
+		}
+	}
+	rewrite := func(next Handler) Handler {
+		return func(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+			req.Model = "rewritten-model"
+			req.Messages = append([]Message{CreateTextMessage(RoleSystem, "injected")}, req.Messages...)
+			resp, err := next(WithRequestHeaders(ctx, http.Header{"X-Audit-Id": {"42"}}), req)
+			if err == nil {
+				resp.Choices[0].Message.Content = TextContent(resp.Choices[0].Message.Text() + " (audited)")
+			}
+			return resp, err
+		}
+	}
+	client.Use(trace("outer"), trace("inner")).Use(rewrite)
+
+	messages := []Message{CreateTextMessage(RoleUser, "hi")}
+	result, err := client.Send(context.Background(), 0, 100, messages, "test-model")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedOrder := "outer request,inner request,inner response,outer response"
+	if strings.Join(order, ",") != expectedOrder {
+		t.Errorf("Expected %s, got %v", expectedOrder, order)
+	}
+	if received.Model != "rewritten-model" || len(received.Messages) != 2 || header != "42" {
+		t.Errorf("Expected the rewritten request, got model %s, %d messages, header %q", received.Model, len(received.Messages), header)
+	}
+	if len(messages) != 1 {
+		t.Errorf("Expected the caller's messages untouched, got %d", len(messages))
+	}
+	if text := result.Choices[0].Message.Text(); text != "reply (audited)" {
+		t.Errorf("Expected the observed response, got %q", text)
+	}
+}
+
+func TestClientMiddlewareShortCircuitsStream(t *testing.T) {
+	client, err := NewClient("test-key", "http://127.0.0.1:0", nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	var mu sync.Mutex
+	var observed []string
+	client.Use(
+		func(next Handler) Handler {
+			return func(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+				ObserveStream(req, func(chunk StreamChunk) {
+					mu.Lock()
+					observed = append(observed, chunk.Content)
+					mu.Unlock()
+				})
+				return next(ctx, req)
+			}
+		},
+		func(next Handler) Handler {
+			return func(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+				return &ChatCompletionResponse{
+					Choices: []Choice{{Message: CreateTextMessage(RoleAssistant, "from middleware")}},
+				}, nil
+			}
+		},
+	)
+
+	stream := make(chan StreamChunk, 10)
+	result, err := client.Send(context.Background(), 0, 100, []Message{CreateTextMessage(RoleUser, "hi")}, "test-model", WithStream(stream))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Choices[0].Message.Text() != "from middleware" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	var content string
+	done := false
+	for chunk := range stream {
+		content += chunk.Content
+		done = done || chunk.Done
+	}
+	if content != "from middleware" || !done {
+		t.Errorf("Expected the synthetic reply streamed to completion, got %q (done %v)", content, done)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(observed, "") != "from middleware" {
+		t.Errorf("Expected the middleware to observe the chunks, got %v", observed)
+	}
+}
+
+func TestClientMiddlewareCallsNextTwice(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"reply\"}}]}\n\ndata: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	var retryErr error
+	client.Use(func(next Handler) Handler {
+		return func(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+			result, err := next(ctx, req)
+			_, retryErr = next(ctx, req)
+			return result, err
+		}
+	})
+
+	stream := make(chan StreamChunk, 10)
+	if _, err := client.Send(context.Background(), 0, 100, []Message{CreateTextMessage(RoleUser, "hi")}, "test-model", WithStream(stream)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !errors.Is(retryErr, ErrHandlerCalledTwice) {
+		t.Errorf("Expected ErrHandlerCalledTwice, got %v", retryErr)
+	}
+	if requests != 1 {
+		t.Errorf("Expected a single request, got %d", requests)
+	}
+
+	var content string
+	for chunk := range stream {
+		content += chunk.Content
+	}
+	if content != "reply" {
+		t.Errorf("Expected the first reply streamed once, got %q", content)
+	}
+}
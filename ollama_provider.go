@@ -0,0 +1,308 @@
+package beau
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OllamaProvider speaks Ollama's native /api/chat endpoint. It does not need an API
+// key, which makes it suitable for local and air-gapped machines. llama.cpp servers
+// expose an OpenAI compatible endpoint instead, use an OpenAIProvider with
+// RequireAPIKey disabled for those.
+type OllamaProvider struct {
+	// Options are merged into the request options (num_ctx, top_k, etc)
+	Options map[string]interface{}
+
+	// KeepAlive controls how long the model stays loaded, e.g. "5m"
+	KeepAlive string
+}
+
+var _ Provider = &OllamaProvider{}
+
+func NewOllamaProvider() *OllamaProvider {
+	return &OllamaProvider{}
+}
+
+type ollamaRequest struct {
+	Model     string                 `json:"model"`
+	Messages  []ollamaMessage        `json:"messages"`
+	Stream    bool                   `json:"stream"`
+	Tools     []Tool                 `json:"tools,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+	KeepAlive string                 `json:"keep_alive,omitempty"`
+	Format    interface{}            `json:"format,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      MessageRole      `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       string        `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+func (p *OllamaProvider) Name() string {
+	return "ollama"
+}
+
+func toOllamaRequest(req ChatCompletionRequest, options map[string]interface{}, keepAlive string) ollamaRequest {
+	out := ollamaRequest{
+		Model:     req.Model,
+		Messages:  []ollamaMessage{},
+		Stream:    req.Stream,
+		Tools:     req.Tools,
+		Options:   map[string]interface{}{},
+		KeepAlive: keepAlive,
+	}
+
+	if req.Temperature != 0 {
+		out.Options["temperature"] = req.Temperature
+	}
+	if req.MaxTokens != 0 {
+		out.Options["num_predict"] = req.MaxTokens
+	}
+	// n, logprobs and parallel tool call control have no Ollama equivalent
+	if req.TopP != nil {
+		out.Options["top_p"] = *req.TopP
+	}
+	if len(req.Stop) > 0 {
+		out.Options["stop"] = req.Stop
+	}
+	if req.Seed != nil {
+		out.Options["seed"] = *req.Seed
+	}
+	if req.PresencePenalty != nil {
+		out.Options["presence_penalty"] = *req.PresencePenalty
+	}
+	if req.FrequencyPenalty != nil {
+		out.Options["frequency_penalty"] = *req.FrequencyPenalty
+	}
+	for k, v := range options {
+		out.Options[k] = v
+	}
+
+	// format takes either "json" or a JSON schema
+	if req.ResponseFormat != nil {
+		switch req.ResponseFormat.Type {
+		case ResponseFormatJSONObject:
+			out.Format = "json"
+		case ResponseFormatJSONSchema:
+			if req.ResponseFormat.JSONSchema != nil {
+				out.Format = req.ResponseFormat.JSONSchema.Schema
+			} else {
+				out.Format = "json"
+			}
+		}
+	}
+
+	for _, msg := range req.Messages {
+		om := ollamaMessage{Role: msg.Role}
+		var text strings.Builder
+		for _, item := range msg.Content {
+			switch item.Type {
+			case ContentTypeText:
+				text.WriteString(item.Text)
+			case ContentTypeImageURL:
+				// Ollama only accepts raw base64 image data, remote urls are dropped
+				if item.ImageURL != nil && strings.HasPrefix(item.ImageURL.URL, "data:") {
+					if _, data, found := strings.Cut(item.ImageURL.URL, ","); found {
+						om.Images = append(om.Images, data)
+					}
+				}
+			}
+		}
+		om.Content = text.String()
+
+		for _, tc := range msg.ToolCalls {
+			var call ollamaToolCall
+			call.Function.Name = tc.Function.Name
+			call.Function.Arguments = json.RawMessage(tc.Function.Arguments)
+			if !json.Valid(call.Function.Arguments) {
+				call.Function.Arguments = json.RawMessage("{}")
+			}
+			om.ToolCalls = append(om.ToolCalls, call)
+		}
+		out.Messages = append(out.Messages, om)
+	}
+
+	return out
+}
+
+// newToolCallPrefix returns a random prefix for the ids of one response's tool
+// calls, so ids never repeat across the turns of a conversation
+func newToolCallPrefix() string {
+	id := make([]byte, 6)
+	rand.Read(id)
+	return "call_" + hex.EncodeToString(id)
+}
+
+// toolCalls converts Ollama tool calls, which carry no ids, into tool calls with
+// generated ids so results can be matched back to them
+func (m ollamaMessage) toolCalls(prefix string, offset int) []ToolCall {
+	var calls []ToolCall
+	for i, tc := range m.ToolCalls {
+		args := string(tc.Function.Arguments)
+		if args == "" || args == "null" {
+			args = "{}"
+		}
+		calls = append(calls, ToolCall{
+			ID:   fmt.Sprintf("%s_%d", prefix, offset+i),
+			Type: "function",
+			Function: ToolFunction{
+				Name:      tc.Function.Name,
+				Arguments: args,
+			},
+		})
+	}
+	return calls
+}
+
+func (r ollamaResponse) finishReason(hasToolCalls bool) string {
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	return r.DoneReason
+}
+
+func (p *OllamaProvider) NewRequest(ctx context.Context, baseURL string, apiKey string, req ChatCompletionRequest) (*http.Request, error) {
+	jsonData, err := json.Marshal(toOllamaRequest(req, p.Options, p.KeepAlive))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMarshalRequest, err)
+	}
+
+	httpReq, err := newJSONRequest(ctx, baseURL, "/api/chat", jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ollama itself ignores auth, but it is often put behind a proxy that does not
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	}
+	return httpReq, nil
+}
+
+func (p *OllamaProvider) ParseResponse(body []byte) (*ChatCompletionResponse, error) {
+	var result ollamaResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnmarshalResponse, err)
+	}
+
+	toolCalls := result.Message.toolCalls(newToolCallPrefix(), 0)
+	return &ChatCompletionResponse{
+		Object: "chat.completion",
+		Model:  result.Model,
+		Choices: []Choice{
+			{
+				Message: Message{
+					Role:      RoleAssistant,
+					Content:   TextContent(result.Message.Content),
+					ToolCalls: toolCalls,
+				},
+				FinishReason: result.finishReason(len(toolCalls) > 0),
+			},
+		},
+		Usage: Usage{
+			PromptTokens:     result.PromptEvalCount,
+			CompletionTokens: result.EvalCount,
+			TotalTokens:      result.PromptEvalCount + result.EvalCount,
+		},
+	}, nil
+}
+
+// DecodeStream reads the newline delimited JSON objects Ollama streams. Tool calls
+// arrive whole rather than as deltas, so they are simply collected.
+func (p *OllamaProvider) DecodeStream(ctx context.Context, body io.Reader, stream chan<- StreamChunk) (*ChatCompletionResponse, error) {
+	var content strings.Builder
+	var toolCalls []ToolCall
+	var last ollamaResponse
+	prefix := newToolCallPrefix()
+
+	reader := bufio.NewReader(body)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var chunk ollamaResponse
+			if jsonErr := json.Unmarshal(line, &chunk); jsonErr == nil {
+				if chunk.Error != "" {
+					return nil, fmt.Errorf("%w: %w", ErrReadStream, newAPIError(0, nil, "", "", chunk.Error))
+				}
+				if chunk.Message.Content != "" {
+					stream <- StreamChunk{Content: chunk.Message.Content}
+					content.WriteString(chunk.Message.Content)
+				}
+				toolCalls = append(toolCalls, chunk.Message.toolCalls(prefix, len(toolCalls))...)
+				last = chunk
+				if chunk.Done {
+					break
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrReadStream, err)
+		}
+	}
+
+	return &ChatCompletionResponse{
+		Object: "chat.completion",
+		Model:  last.Model,
+		Choices: []Choice{
+			{
+				Message: Message{
+					Role:      RoleAssistant,
+					Content:   TextContent(content.String()),
+					ToolCalls: toolCalls,
+				},
+				FinishReason: last.finishReason(len(toolCalls) > 0),
+			},
+		},
+		Usage: Usage{
+			PromptTokens:     last.PromptEvalCount,
+			CompletionTokens: last.EvalCount,
+			TotalTokens:      last.PromptEvalCount + last.EvalCount,
+		},
+	}, nil
+}
+
+func (p *OllamaProvider) ClassifyError(statusCode int, body []byte) error {
+	var result struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil && result.Error != "" {
+		return newAPIError(statusCode, body, "", "", result.Error)
+	}
+	return unexpectedStatusError(statusCode, body)
+}
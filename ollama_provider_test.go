@@ -0,0 +1,211 @@
+package beau
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToOllamaRequest(t *testing.T) {
+	ollamaCall := func(name string, arguments string) ollamaToolCall {
+		var call ollamaToolCall
+		call.Function.Name = name
+		call.Function.Arguments = json.RawMessage(arguments)
+		return call
+	}
+	tools := []Tool{{Type: "function", Function: ToolSchema{Name: "list_directory", Description: "Lists a directory"}}}
+
+	tests := []struct {
+		name           string
+		request        ChatCompletionRequest
+		options        map[string]interface{}
+		expectOptions  map[string]interface{}
+		expectFormat   interface{}
+		expectTools    []Tool
+		expectMessages []ollamaMessage
+	}{
+		{
+			name: "Sampling settings become options",
+			request: ChatCompletionRequest{
+				Temperature: 0.2,
+				MaxTokens:   256,
+				Messages:    []Message{CreateTextMessage(RoleUser, "hi")},
+			},
+			options:       map[string]interface{}{"num_ctx": 8192, "temperature": 0.7},
+			expectOptions: map[string]interface{}{"temperature": 0.7, "num_predict": 256, "num_ctx": 8192},
+			expectMessages: []ollamaMessage{
+				{Role: RoleUser, Content: "hi"},
+			},
+		},
+		{
+			name: "JSON schema becomes the format",
+			request: ChatCompletionRequest{
+				ResponseFormat: &ResponseFormat{
+					Type:       ResponseFormatJSONSchema,
+					JSONSchema: &JSONSchemaFormat{Name: "answer", Schema: map[string]interface{}{"type": "object"}},
+				},
+				Messages: []Message{CreateTextMessage(RoleUser, "hi")},
+			},
+			expectOptions: map[string]interface{}{},
+			expectFormat:  map[string]interface{}{"type": "object"},
+			expectMessages: []ollamaMessage{
+				{Role: RoleUser, Content: "hi"},
+			},
+		},
+		{
+			name: "Only inline images are kept",
+			request: ChatCompletionRequest{
+				Messages: []Message{
+					CreateComplexMessage(RoleUser, []ContentItem{
+						{Type: ContentTypeText, Text: "compare "},
+						{Type: ContentTypeImageURL, ImageURL: &ImageURL{URL: "data:image/png;base64,iVBORw0KGgo="}},
+						{Type: ContentTypeImageURL, ImageURL: &ImageURL{URL: "https://example.com/cat.jpg"}},
+						{Type: ContentTypeText, Text: "these"},
+					}),
+				},
+			},
+			expectOptions: map[string]interface{}{},
+			expectMessages: []ollamaMessage{
+				{Role: RoleUser, Content: "compare these", Images: []string{"iVBORw0KGgo="}},
+			},
+		},
+		{
+			name: "Tools and tool calls are passed through",
+			request: ChatCompletionRequest{
+				Tools: tools,
+				Messages: []Message{
+					CreateTextMessage(RoleUser, "list /tmp"),
+					{Role: RoleAssistant, ToolCalls: []ToolCall{
+						{ID: "call_0", Type: "function", Function: ToolFunction{Name: "list_directory", Arguments: `{"directory_path":"/tmp"}`}},
+						{ID: "call_1", Type: "function", Function: ToolFunction{Name: "list_directory", Arguments: `{"directory_path":`}},
+					}},
+					{Role: RoleTool, ToolCallID: "call_0", Content: TextContent("a.txt")},
+				},
+			},
+			expectOptions: map[string]interface{}{},
+			expectTools:   tools,
+			expectMessages: []ollamaMessage{
+				{Role: RoleUser, Content: "list /tmp"},
+				{Role: RoleAssistant, ToolCalls: []ollamaToolCall{
+					ollamaCall("list_directory", `{"directory_path":"/tmp"}`),
+					ollamaCall("list_directory", `{}`),
+				}},
+				{Role: RoleTool, Content: "a.txt"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.Model = "llama3.2"
+			out := toOllamaRequest(tt.request, tt.options, "5m")
+			if out.Model != "llama3.2" || out.KeepAlive != "5m" {
+				t.Errorf("Expected model and keep alive to be set, got %q %q", out.Model, out.KeepAlive)
+			}
+			if !reflect.DeepEqual(out.Options, tt.expectOptions) {
+				t.Errorf("Expected options %v, got %v", tt.expectOptions, out.Options)
+			}
+			if !reflect.DeepEqual(out.Format, tt.expectFormat) {
+				t.Errorf("Expected format %v, got %v", tt.expectFormat, out.Format)
+			}
+			if !reflect.DeepEqual(out.Tools, tt.expectTools) {
+				t.Errorf("Expected tools %+v, got %+v", tt.expectTools, out.Tools)
+			}
+			if !reflect.DeepEqual(out.Messages, tt.expectMessages) {
+				got, _ := json.Marshal(out.Messages)
+				want, _ := json.Marshal(tt.expectMessages)
+				t.Errorf("Expected messages\n%s\ngot\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestOllamaStreamTranscripts(t *testing.T) {
+	tests := []struct {
+		name          string
+		transcript    string
+		expectError   bool
+		expectChunks  string
+		expectFinish  string
+		expectedCalls []ToolCall
+		expectUsage   Usage
+	}{
+		{
+			name:         "Content then tool calls over several lines",
+			transcript:   "ollama_tool_calls.ndjson",
+			expectChunks: "Checking both.",
+			expectFinish: "tool_calls",
+			expectedCalls: []ToolCall{
+				{Type: "function", Function: ToolFunction{Name: "list_directory", Arguments: `{"directory_path":"/tmp"}`}},
+				{Type: "function", Function: ToolFunction{Name: "read_file", Arguments: `{"path":"a.txt"}`}},
+				{Type: "function", Function: ToolFunction{Name: "get_time", Arguments: `{}`}},
+			},
+			expectUsage: Usage{PromptTokens: 180, CompletionTokens: 42, TotalTokens: 222},
+		},
+		{
+			name:         "Error line mid stream",
+			transcript:   "ollama_error.ndjson",
+			expectError:  true,
+			expectChunks: "Partial",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, chunks, err := decodeProviderTranscript(t, NewOllamaProvider(), tt.transcript)
+
+			var streamed string
+			for _, chunk := range chunks {
+				streamed += chunk.Content
+			}
+			if streamed != tt.expectChunks {
+				t.Errorf("Expected streamed %q, got %q", tt.expectChunks, streamed)
+			}
+
+			if tt.expectError {
+				var apiErr *APIError
+				if !errors.Is(err, ErrReadStream) || !errors.As(err, &apiErr) {
+					t.Fatalf("Expected an API error from the stream, got %v", err)
+				}
+				if apiErr.Message != "model requires more system memory than is available" {
+					t.Errorf("Expected the error line's message, got %q", apiErr.Message)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			choice := response.Choices[0]
+			if response.Model != "llama3.2" {
+				t.Errorf("Expected the model from the stream, got %q", response.Model)
+			}
+			if choice.Message.Text() != tt.expectChunks || choice.FinishReason != tt.expectFinish {
+				t.Errorf("Unexpected choice: %+v", choice)
+			}
+
+			// ids share a random prefix for the response and count up from zero
+			calls := append([]ToolCall{}, choice.Message.ToolCalls...)
+			prefix := calls[0].ID[:strings.LastIndex(calls[0].ID, "_")]
+			for i := range calls {
+				if calls[i].ID != fmt.Sprintf("%s_%d", prefix, i) || !strings.HasPrefix(prefix, "call_") {
+					t.Errorf("Unexpected id %q for call %d", calls[i].ID, i)
+				}
+				calls[i].ID = ""
+			}
+			if !reflect.DeepEqual(calls, tt.expectedCalls) {
+				t.Errorf("Expected tool calls %+v, got %+v", tt.expectedCalls, calls)
+			}
+			again, _, _ := decodeProviderTranscript(t, NewOllamaProvider(), tt.transcript)
+			if again.Choices[0].Message.ToolCalls[0].ID == choice.Message.ToolCalls[0].ID {
+				t.Errorf("Expected ids to differ between responses, both were %q", choice.Message.ToolCalls[0].ID)
+			}
+			if response.Usage != tt.expectUsage {
+				t.Errorf("Expected usage %+v, got %+v", tt.expectUsage, response.Usage)
+			}
+		})
+	}
+}
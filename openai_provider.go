@@ -0,0 +1,276 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// OpenAIProvider speaks the OpenAI chat completions wire format. Most hosted and
+// self-hosted gateways are compatible with it, so Path and Headers can be used to
+// point it at them without writing a new provider.
+type OpenAIProvider struct {
+	// Path is appended to the base url. Defaults to /v1/chat/completions
+	Path string
+
+	// EmbeddingsPath is appended to the base url for embeddings. Defaults to /v1/embeddings
+	EmbeddingsPath string
+
+	// Headers are set on every request after the defaults
+	Headers map[string]string
+
+	// RequireAPIKey rejects requests without a key before they are sent. Disable it
+	// for local OpenAI compatible servers such as llama.cpp
+	RequireAPIKey bool
+}
+
+var _ Provider = &OpenAIProvider{}
+
+func NewOpenAIProvider() *OpenAIProvider {
+	return &OpenAIProvider{
+		Path:           "/v1/chat/completions",
+		EmbeddingsPath: "/v1/embeddings",
+		RequireAPIKey:  true,
+	}
+}
+
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+func (p *OpenAIProvider) NewRequest(ctx context.Context, baseURL string, apiKey string, req ChatCompletionRequest) (*http.Request, error) {
+	if p.RequireAPIKey && apiKey == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	// there is no error flag on OpenAI tool messages
+	req.Messages = withoutErrorFlags(req.Messages)
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMarshalRequest, err)
+	}
+
+	path := p.Path
+	if path == "" {
+		path = "/v1/chat/completions"
+	}
+
+	httpReq, err := newJSONRequest(ctx, baseURL, path, jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	}
+	for k, v := range p.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	return httpReq, nil
+}
+
+// withoutErrorFlags returns messages with IsError cleared, copying them only
+// when a flag is set
+func withoutErrorFlags(messages []Message) []Message {
+	for i, msg := range messages {
+		if msg.IsError {
+			out := append([]Message{}, messages...)
+			for j := i; j < len(out); j++ {
+				out[j].IsError = false
+			}
+			return out
+		}
+	}
+	return messages
+}
+
+func (p *OpenAIProvider) ParseResponse(body []byte) (*ChatCompletionResponse, error) {
+	var result ChatCompletionResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnmarshalResponse, err)
+	}
+	return &result, nil
+}
+
+// streamedChoice accumulates one choice of a streamed response
+type streamedChoice struct {
+	content      strings.Builder
+	finishReason string
+	toolCalls    *ToolCallAssembler
+	logprobs     *Logprobs
+}
+
+// DecodeStream rebuilds the response from OpenAI chunks. When several choices
+// were requested they are all collected, but only the first is streamed.
+func (p *OpenAIProvider) DecodeStream(ctx context.Context, body io.Reader, stream chan<- StreamChunk) (*ChatCompletionResponse, error) {
+	decoder := NewSSEDecoder(body)
+	var id, model, fingerprint string
+	var created int64
+	var usage Usage
+	choices := map[int]*streamedChoice{}
+
+	choiceAt := func(index int) *streamedChoice {
+		choice, ok := choices[index]
+		if !ok {
+			choice = &streamedChoice{toolCalls: NewToolCallAssembler()}
+			choices[index] = choice
+		}
+		return choice
+	}
+
+	result := func() (*ChatCompletionResponse, error) {
+		// a stream without choice deltas still yields one empty choice
+		choiceAt(0)
+
+		indices := make([]int, 0, len(choices))
+		for index := range choices {
+			indices = append(indices, index)
+		}
+		sort.Ints(indices)
+
+		response := &ChatCompletionResponse{
+			ID:                id,
+			Object:            "chat.completion",
+			Created:           created,
+			Model:             model,
+			SystemFingerprint: fingerprint,
+			Usage:             usage,
+		}
+		for _, index := range indices {
+			choice := choices[index]
+			calls, err := choice.toolCalls.ToolCalls()
+			if err != nil {
+				return nil, err
+			}
+			response.Choices = append(response.Choices, Choice{
+				Index: index,
+				Message: Message{
+					Role:      RoleAssistant,
+					Content:   TextContent(choice.content.String()),
+					ToolCalls: calls,
+				},
+				FinishReason: choice.finishReason,
+				Logprobs:     choice.logprobs,
+			})
+		}
+		return response, nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		event, err := decoder.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrReadStream, err)
+		}
+
+		if event.Data == "[DONE]" {
+			return result()
+		}
+
+		var chunk struct {
+			ID                string `json:"id"`
+			Created           int64  `json:"created"`
+			Model             string `json:"model"`
+			SystemFingerprint string `json:"system_fingerprint"`
+			Choices           []struct {
+				Index int `json:"index"`
+				Delta struct {
+					Content   string          `json:"content"`
+					ToolCalls []ToolCallDelta `json:"tool_calls"`
+				} `json:"delta"`
+				FinishReason string    `json:"finish_reason"`
+				Logprobs     *Logprobs `json:"logprobs"`
+			} `json:"choices"`
+			Usage *Usage       `json:"usage"`
+			Error *openAIError `json:"error"`
+		}
+
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil {
+			continue
+		}
+
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("%w: %w", ErrReadStream, chunk.Error.apiError(0, nil))
+		}
+
+		if chunk.ID != "" {
+			id, created, model = chunk.ID, chunk.Created, chunk.Model
+		}
+		if chunk.SystemFingerprint != "" {
+			fingerprint = chunk.SystemFingerprint
+		}
+
+		// Usage arrives on a final chunk with no choices when include_usage is set
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+
+		for _, delta := range chunk.Choices {
+			choice := choiceAt(delta.Index)
+
+			// Handle content streaming
+			if delta.Delta.Content != "" {
+				if delta.Index == 0 {
+					stream <- StreamChunk{Content: delta.Delta.Content}
+				}
+				choice.content.WriteString(delta.Delta.Content)
+			}
+
+			// Tool calls arrive as fragments keyed by index
+			for _, tc := range delta.Delta.ToolCalls {
+				choice.toolCalls.Add(tc)
+			}
+
+			if delta.Logprobs != nil {
+				if choice.logprobs == nil {
+					choice.logprobs = &Logprobs{}
+				}
+				choice.logprobs.Content = append(choice.logprobs.Content, delta.Logprobs.Content...)
+			}
+
+			if delta.FinishReason != "" {
+				choice.finishReason = delta.FinishReason
+			}
+		}
+	}
+
+	return result()
+}
+
+// openAIError is the error object OpenAI compatible APIs return, code may be a
+// string, a number or null
+type openAIError struct {
+	Message string          `json:"message"`
+	Type    string          `json:"type"`
+	Code    json.RawMessage `json:"code"`
+}
+
+func (e *openAIError) apiError(statusCode int, body []byte) *APIError {
+	code := strings.Trim(string(e.Code), `"`)
+	if code == "null" {
+		code = ""
+	}
+	return newAPIError(statusCode, body, code, e.Type, e.Message)
+}
+
+func (p *OpenAIProvider) ClassifyError(statusCode int, body []byte) error {
+	var result struct {
+		Error *openAIError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil && result.Error != nil {
+		return result.Error.apiError(statusCode, body)
+	}
+	return unexpectedStatusError(statusCode, body)
+}
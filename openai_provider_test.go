@@ -0,0 +1,169 @@
+package beau
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIStreamUsage(t *testing.T) {
+	response, _, err := decodeTranscript(t, "openai_text_with_usage.sse")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.Model != "gpt-4o" || response.ID != "chatcmpl-3" {
+		t.Errorf("Expected response metadata from the stream, got id=%q model=%q", response.ID, response.Model)
+	}
+
+	expected := Usage{PromptTokens: 42, CompletionTokens: 2, TotalTokens: 44}
+	if response.Usage != expected {
+		t.Errorf("Expected usage %+v, got %+v", expected, response.Usage)
+	}
+
+	if text := response.Choices[0].Message.Content.Text(); text != "Hello there" {
+		t.Errorf("Expected content %q, got %q", "Hello there", text)
+	}
+}
+
+func TestOpenAIStreamMultipleChoices(t *testing.T) {
+	response, chunks, err := decodeTranscript(t, "openai_multiple_choices_logprobs.sse")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.SystemFingerprint != "fp_abc" {
+		t.Errorf("Expected system fingerprint fp_abc, got %q", response.SystemFingerprint)
+	}
+	if len(response.Choices) != 2 {
+		t.Fatalf("Expected 2 choices, got %d", len(response.Choices))
+	}
+
+	expected := []struct {
+		content  string
+		logprobs int
+	}{
+		{content: "Yes.", logprobs: 2},
+		{content: "No", logprobs: 1},
+	}
+	for i, want := range expected {
+		choice := response.Choices[i]
+		if choice.Index != i || choice.Message.Content.Text() != want.content || choice.FinishReason != "stop" {
+			t.Errorf("Choice %d: unexpected %+v", i, choice)
+		}
+		if choice.Logprobs == nil || len(choice.Logprobs.Content) != want.logprobs {
+			t.Errorf("Choice %d: expected %d logprobs, got %+v", i, want.logprobs, choice.Logprobs)
+		}
+	}
+	if top := response.Choices[0].Logprobs.Content[0].TopLogprobs; len(top) != 2 || top[1].Token != "No" {
+		t.Errorf("Unexpected top logprobs: %+v", top)
+	}
+
+	// only the first choice is streamed
+	var streamed string
+	for _, chunk := range chunks {
+		streamed += chunk.Content
+	}
+	if streamed != "Yes." {
+		t.Errorf("Expected only the first choice streamed, got %q", streamed)
+	}
+}
+
+func TestProvidersDropUnsupportedSamplingParams(t *testing.T) {
+	var req ChatCompletionRequest
+	for _, opt := range []RequestOption{
+		WithTopP(0.9),
+		WithStop("END"),
+		WithSeed(7),
+		WithN(2),
+		WithPresencePenalty(0.5),
+		WithFrequencyPenalty(0.5),
+		WithLogprobs(3),
+		WithParallelToolCalls(false),
+		WithTools([]Tool{{Type: "function", Function: ToolSchema{Name: "lookup"}}}),
+	} {
+		opt(&req)
+	}
+
+	tests := []struct {
+		name     string
+		provider Provider
+		model    string
+		present  []string
+		absent   []string
+	}{
+		{
+			name:     "OpenAI",
+			provider: NewOpenAIProvider(),
+			model:    "gpt-4o",
+			present:  []string{`"top_p":0.9`, `"stop":["END"]`, `"seed":7`, `"n":2`, `"presence_penalty"`, `"frequency_penalty"`, `"logprobs":true`, `"top_logprobs":3`, `"parallel_tool_calls":false`},
+		},
+		{
+			name:     "xAI reasoning model",
+			provider: NewXAIProvider(),
+			model:    "grok-4",
+			present:  []string{`"top_p":0.9`, `"seed":7`, `"logprobs":true`},
+			absent:   []string{`"stop"`, `"presence_penalty"`, `"frequency_penalty"`},
+		},
+		{
+			name:     "xAI non reasoning model",
+			provider: NewXAIProvider(),
+			model:    "grok-3",
+			present:  []string{`"stop":["END"]`, `"presence_penalty"`},
+		},
+		{
+			name:     "Anthropic",
+			provider: NewAnthropicProvider(),
+			model:    "claude",
+			present:  []string{`"top_p":0.9`, `"stop_sequences":["END"]`, `"disable_parallel_tool_use":true`},
+			absent:   []string{`"seed"`, `"n"`, `"presence_penalty"`, `"logprobs"`},
+		},
+		{
+			name:     "Ollama",
+			provider: NewOllamaProvider(),
+			model:    "llama3.1",
+			present:  []string{`"top_p":0.9`, `"stop":["END"]`, `"seed":7`, `"presence_penalty":0.5`},
+			absent:   []string{`"n"`, `"logprobs"`, `"parallel_tool_calls"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req.Model = tt.model
+			httpReq, err := tt.provider.NewRequest(context.Background(), "http://localhost", "test-key", req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			body, _ := io.ReadAll(httpReq.Body)
+			for _, want := range tt.present {
+				if !strings.Contains(string(body), want) {
+					t.Errorf("Expected %s in %s", want, body)
+				}
+			}
+			for _, unwanted := range tt.absent {
+				if strings.Contains(string(body), unwanted) {
+					t.Errorf("Expected no %s in %s", unwanted, body)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAIRequestOmitsToolErrorFlag(t *testing.T) {
+	messages := []Message{
+		CreateTextMessage(RoleUser, "read it"),
+		{Role: RoleTool, ToolCallID: "call_1", Content: TextContent("Error: denied"), IsError: true},
+	}
+	httpReq, err := NewOpenAIProvider().NewRequest(context.Background(), "http://localhost", "test-key", ChatCompletionRequest{Model: "gpt-4o", Messages: messages})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(httpReq.Body)
+	if strings.Contains(string(body), "is_error") {
+		t.Errorf("Expected no error flag on the OpenAI wire, got %s", body)
+	}
+	if !messages[1].IsError {
+		t.Errorf("Expected the caller's messages untouched")
+	}
+}
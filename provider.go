@@ -0,0 +1,66 @@
+package beau
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Provider adapts the client to the wire format of a specific backend. The client
+// owns retries, logging and the stream channel lifecycle, the provider owns
+// everything that differs between APIs.
+type Provider interface {
+	// Name identifies the provider in logs and errors
+	Name() string
+
+	// NewRequest builds the HTTP request for a chat completion against baseURL
+	NewRequest(ctx context.Context, baseURL string, apiKey string, req ChatCompletionRequest) (*http.Request, error)
+
+	// ParseResponse decodes the body of a successful non-streaming response
+	ParseResponse(body []byte) (*ChatCompletionResponse, error)
+
+	// DecodeStream reads a streaming response body, forwarding content chunks to
+	// stream as they arrive, and returns the fully assembled response. It must not
+	// close the channel or send the final Done chunk.
+	DecodeStream(ctx context.Context, body io.Reader, stream chan<- StreamChunk) (*ChatCompletionResponse, error)
+
+	// ClassifyError converts a non-200 response into an error
+	ClassifyError(statusCode int, body []byte) error
+}
+
+// DetectProvider picks a provider based on the base url, falling back to the
+// OpenAI compatible wire format
+func DetectProvider(baseURL string) Provider {
+	switch {
+	case strings.Contains(baseURL, "anthropic.com"):
+		return NewAnthropicProvider()
+	case strings.Contains(baseURL, "x.ai"):
+		return NewXAIProvider()
+	case strings.Contains(baseURL, ":11434"):
+		// default ollama port
+		return NewOllamaProvider()
+	default:
+		return NewOpenAIProvider()
+	}
+}
+
+// newJSONRequest builds a POST request with a JSON body against baseURL + path
+func newJSONRequest(ctx context.Context, baseURL string, path string, body []byte) (*http.Request, error) {
+	cleanBaseURL := strings.TrimSuffix(baseURL, "/")
+	fullURL := fmt.Sprintf("%s%s", cleanBaseURL, path)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", fullURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCreateRequest, err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
+
+// unexpectedStatusError builds an APIError for a body in no known format
+func unexpectedStatusError(statusCode int, body []byte) error {
+	return newAPIError(statusCode, body, "", "", "")
+}
@@ -0,0 +1,200 @@
+package beau
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter keeps requests under requests-per-minute and tokens-per-minute
+// budgets. Share one limiter between every client that draws on the same API key
+// so that together they stay within the provider's limits. Budgets refill
+// continuously, and the remaining counts providers report in response headers
+// take precedence over the local estimate.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests bucket
+	tokens   bucket
+
+	// set when the provider reports a budget as spent
+	blockedUntil time.Time
+}
+
+type bucket struct {
+	capacity float64 // zero means unlimited
+	level    float64
+	updated  time.Time
+}
+
+// NewRateLimiter creates a limiter, a budget of zero is unlimited
+func NewRateLimiter(requestsPerMinute int, tokensPerMinute int) *RateLimiter {
+	now := time.Now()
+	return &RateLimiter{
+		requests: bucket{capacity: float64(requestsPerMinute), level: float64(requestsPerMinute), updated: now},
+		tokens:   bucket{capacity: float64(tokensPerMinute), level: float64(tokensPerMinute), updated: now},
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if b.capacity == 0 {
+		return
+	}
+	elapsed := now.Sub(b.updated)
+	if elapsed > 0 {
+		b.level = math.Min(b.capacity, b.level+b.capacity*elapsed.Minutes())
+		b.updated = now
+	}
+}
+
+// wait returns how long until amount is available
+func (b *bucket) wait(amount float64) time.Duration {
+	if b.capacity == 0 || b.level >= amount {
+		return 0
+	}
+	return time.Duration((amount - b.level) / b.capacity * float64(time.Minute))
+}
+
+// Wait blocks until a request estimated to use tokens fits the budgets, then
+// charges it. It returns early with the context's error if ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) error {
+	for {
+		delay := l.reserve(time.Now(), tokens)
+		if delay == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// reserve charges the budgets if the request fits now, otherwise it returns how
+// long to wait before trying again
+func (l *RateLimiter) reserve(now time.Time, tokens int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(l.blockedUntil) {
+		return l.blockedUntil.Sub(now)
+	}
+
+	l.requests.refill(now)
+	l.tokens.refill(now)
+
+	// a request larger than the whole budget only has to wait for a full bucket
+	needed := float64(tokens)
+	if l.tokens.capacity > 0 && needed > l.tokens.capacity {
+		needed = l.tokens.capacity
+	}
+
+	delay := l.requests.wait(1)
+	if tokenDelay := l.tokens.wait(needed); tokenDelay > delay {
+		delay = tokenDelay
+	}
+	if delay > 0 {
+		return delay
+	}
+
+	if l.requests.capacity > 0 {
+		l.requests.level--
+	}
+	if l.tokens.capacity > 0 {
+		l.tokens.level -= needed
+	}
+	return 0
+}
+
+// Record charges tokens used beyond what was estimated in Wait, such as the
+// completion tokens reported once a response arrives
+func (l *RateLimiter) Record(tokens int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.tokens.capacity > 0 {
+		l.tokens.refill(time.Now())
+		l.tokens.level -= float64(tokens)
+	}
+}
+
+// Observe updates the budgets from the rate limit headers of a response. Both the
+// OpenAI style x-ratelimit-* and the Anthropic style anthropic-ratelimit-*
+// headers are understood.
+func (l *RateLimiter) Observe(header http.Header) {
+	l.observe(time.Now(), header)
+}
+
+func (l *RateLimiter) observe(now time.Time, header http.Header) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.requests.refill(now)
+	l.tokens.refill(now)
+
+	for _, limit := range []struct {
+		bucket    *bucket
+		remaining []string
+		reset     []string
+	}{
+		{
+			bucket:    &l.requests,
+			remaining: []string{"X-Ratelimit-Remaining-Requests", "Anthropic-Ratelimit-Requests-Remaining"},
+			reset:     []string{"X-Ratelimit-Reset-Requests", "Anthropic-Ratelimit-Requests-Reset"},
+		},
+		{
+			bucket:    &l.tokens,
+			remaining: []string{"X-Ratelimit-Remaining-Tokens", "Anthropic-Ratelimit-Tokens-Remaining"},
+			reset:     []string{"X-Ratelimit-Reset-Tokens", "Anthropic-Ratelimit-Tokens-Reset"},
+		},
+	} {
+		remaining, ok := headerInt(header, limit.remaining...)
+		if !ok {
+			continue
+		}
+
+		// the provider's count wins when it has less left than we think
+		if limit.bucket.capacity > 0 && float64(remaining) < limit.bucket.level {
+			limit.bucket.level = float64(remaining)
+		}
+
+		if remaining <= 0 {
+			if reset := headerReset(now, header, limit.reset...); reset.After(l.blockedUntil) {
+				l.blockedUntil = reset
+			}
+		}
+	}
+}
+
+func headerInt(header http.Header, keys ...string) (int, bool) {
+	for _, key := range keys {
+		if value := header.Get(key); value != "" {
+			if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// headerReset reads a reset time given as a duration ("1m30s", "20ms") or as an
+// RFC 3339 timestamp
+func headerReset(now time.Time, header http.Header, keys ...string) time.Time {
+	for _, key := range keys {
+		value := strings.TrimSpace(header.Get(key))
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err == nil {
+			return now.Add(d)
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return now
+}
@@ -0,0 +1,159 @@
+package beau
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	start := time.Now()
+
+	tests := []struct {
+		name     string
+		rpm      int
+		tpm      int
+		header   http.Header
+		requests []int           // token estimate of each request, all made at start
+		expected []time.Duration // wait returned for each request
+	}{
+		{
+			name:     "Unlimited never waits",
+			requests: []int{1000, 1000, 1000},
+			expected: []time.Duration{0, 0, 0},
+		},
+		{
+			name:     "Requests per minute",
+			rpm:      2,
+			requests: []int{0, 0, 0},
+			expected: []time.Duration{0, 0, 30 * time.Second},
+		},
+		{
+			name:     "Tokens per minute",
+			tpm:      1000,
+			requests: []int{600, 600},
+			expected: []time.Duration{0, 12 * time.Second},
+		},
+		{
+			name:     "Oversized request waits for a full bucket",
+			tpm:      1000,
+			requests: []int{5000},
+			expected: []time.Duration{0},
+		},
+		{
+			name:     "Provider remaining count wins",
+			rpm:      100,
+			header:   http.Header{"X-Ratelimit-Remaining-Requests": {"1"}},
+			requests: []int{0, 0},
+			expected: []time.Duration{0, 600 * time.Millisecond},
+		},
+		{
+			name:     "Spent budget blocks until reset",
+			header:   http.Header{"X-Ratelimit-Remaining-Tokens": {"0"}, "X-Ratelimit-Reset-Tokens": {"1m30s"}},
+			requests: []int{10},
+			expected: []time.Duration{90 * time.Second},
+		},
+		{
+			name: "Anthropic reset timestamp",
+			header: http.Header{
+				"Anthropic-Ratelimit-Requests-Remaining": {"0"},
+				"Anthropic-Ratelimit-Requests-Reset":     {start.Add(10 * time.Second).UTC().Format(time.RFC3339)},
+			},
+			requests: []int{10},
+			expected: []time.Duration{10 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter(tt.rpm, tt.tpm)
+			limiter.requests.updated = start
+			limiter.tokens.updated = start
+			if tt.header != nil {
+				limiter.observe(start, tt.header)
+			}
+
+			for i, tokens := range tt.requests {
+				wait := limiter.reserve(start, tokens)
+				// timestamps in headers only carry whole seconds
+				if diff := wait - tt.expected[i]; diff > time.Second || diff < -time.Second {
+					t.Errorf("Request %d: expected wait %v, got %v", i, tt.expected[i], wait)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterSharedByClients(t *testing.T) {
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "0")
+		w.Header().Set("X-Ratelimit-Reset-Requests", "100ms")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(0, 0)
+	messages := []Message{CreateTextMessage(RoleUser, "hi")}
+
+	for i := 0; i < 2; i++ {
+		client, _ := NewClient("test-key", server.URL, nil, nil, RetryConfig{})
+		client.WithRateLimiter(limiter)
+		if _, err := client.Send(context.Background(), 0.7, 100, messages, "test-model"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if gap := times[1].Sub(times[0]); gap < 90*time.Millisecond {
+		t.Errorf("Expected the second client to wait for the reset, waited %v", gap)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter.observe(time.Now(), http.Header{"X-Ratelimit-Remaining-Requests": {"0"}, "X-Ratelimit-Reset-Requests": {"1m"}})
+	if err := limiter.Wait(ctx, 0); err == nil {
+		t.Errorf("Expected a cancelled wait to fail")
+	}
+}
+
+func TestRateLimiterChargesRetriedRequestOnce(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(0, 60000)
+	client, _ := NewClient("test-key", server.URL, nil, nil, RetryConfig{
+		MaxRetries:    3,
+		InitialDelay:  time.Millisecond,
+		BackoffFactor: 1,
+		Enabled:       true,
+	})
+	client.WithRateLimiter(limiter)
+
+	// about 1000 estimated tokens, charged three times if every attempt reserved them
+	messages := []Message{CreateTextMessage(RoleUser, strings.Repeat("x", 4000))}
+	if _, err := client.Send(context.Background(), 0.7, 100, messages, "test-model"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", attempts)
+	}
+
+	limiter.mu.Lock()
+	charged := limiter.tokens.capacity - limiter.tokens.level
+	limiter.mu.Unlock()
+	if charged < 900 || charged > 1500 {
+		t.Errorf("Expected the estimate charged once, got %.0f tokens", charged)
+	}
+}
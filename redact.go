@@ -0,0 +1,384 @@
+package beau
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidRedactionPattern = fmt.Errorf("invalid redaction pattern")
+)
+
+// MinSecretLength is the shortest literal secret a Redactor accepts, shorter
+// values would blank out ordinary words
+const MinSecretLength = 6
+
+// RedactionPattern matches a kind of secret by its shape
+type RedactionPattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// DefaultRedactionPatterns match common API keys, tokens and private keys
+var DefaultRedactionPatterns = []RedactionPattern{
+	{Name: "private_key", Regexp: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{Name: "api_key", Regexp: regexp.MustCompile(`\b(?:sk|xai)-[A-Za-z0-9_\-]{20,}`)},
+	{Name: "github_token", Regexp: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{Name: "aws_access_key", Regexp: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "google_api_key", Regexp: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`)},
+	{Name: "slack_token", Regexp: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9\-]{10,}`)},
+	{Name: "jwt", Regexp: regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`)},
+	{Name: "bearer_token", Regexp: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]{20,}=*`)},
+}
+
+// sensitiveEnvNames are name fragments of environment variables holding secrets
+var sensitiveEnvNames = []string{"KEY", "TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL"}
+
+// IsSensitiveEnvName reports whether an environment variable is named like one
+// that holds a secret, e.g. OPENAI_API_KEY or DB_PASSWORD
+func IsSensitiveEnvName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, fragment := range sensitiveEnvNames {
+		if strings.Contains(upper, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redactor replaces secrets in text with a [REDACTED:name] placeholder and
+// counts the distinct secrets it replaced. Secrets are registered as literal
+// values or matched by pattern. A nil Redactor redacts nothing.
+type Redactor struct {
+	mu       sync.Mutex
+	secrets  []redactionSecret // longest first, so a secret containing another wins
+	patterns []RedactionPattern
+	counts   map[string]int
+	seen     map[[sha256.Size]byte]bool // hashes of redacted values, so none is counted twice
+}
+
+type redactionSecret struct {
+	name  string
+	value string
+}
+
+// NewRedactor creates a redactor matching DefaultRedactionPatterns
+func NewRedactor() *Redactor {
+	return &Redactor{
+		patterns: append([]RedactionPattern{}, DefaultRedactionPatterns...),
+		counts:   map[string]int{},
+		seen:     map[[sha256.Size]byte]bool{},
+	}
+}
+
+// AddSecret registers a literal value to redact, reported under name. Values
+// shorter than MinSecretLength are ignored.
+func (r *Redactor) AddSecret(name string, value string) *Redactor {
+	if len(value) < MinSecretLength {
+		return r
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, secret := range r.secrets {
+		if secret.value == value {
+			return r
+		}
+	}
+	r.secrets = append(r.secrets, redactionSecret{name: name, value: value})
+	sort.SliceStable(r.secrets, func(i, j int) bool {
+		return len(r.secrets[i].value) > len(r.secrets[j].value)
+	})
+	return r
+}
+
+// AddSecretsFromEnv registers the values of environment variables whose names
+// look sensitive, see IsSensitiveEnvName
+func (r *Redactor) AddSecretsFromEnv() *Redactor {
+	for _, env := range os.Environ() {
+		name, value, ok := strings.Cut(env, "=")
+		if ok && IsSensitiveEnvName(name) {
+			r.AddSecret(name, value)
+		}
+	}
+	return r
+}
+
+// AddPattern registers a regular expression matching secrets, reported under name
+func (r *Redactor) AddPattern(name string, expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRedactionPattern, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patterns = append(r.patterns, RedactionPattern{Name: name, Regexp: re})
+	return nil
+}
+
+// Redact returns text with every secret replaced
+func (r *Redactor) Redact(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, secret := range r.secrets {
+		if strings.Contains(text, secret.value) {
+			text = strings.ReplaceAll(text, secret.value, redactedPlaceholder(secret.name))
+			r.count(secret.name, secret.value)
+		}
+	}
+	for _, pattern := range r.patterns {
+		text = pattern.Regexp.ReplaceAllStringFunc(text, func(match string) string {
+			r.count(pattern.Name, match)
+			return redactedPlaceholder(pattern.Name)
+		})
+	}
+	return text
+}
+
+// count records a redacted value. The same value turns up again whenever the
+// history is resent or logged, it is only counted the first time.
+func (r *Redactor) count(name string, value string) {
+	hash := sha256.Sum256([]byte(value))
+	if r.seen[hash] {
+		return
+	}
+	r.seen[hash] = true
+	r.counts[name]++
+}
+
+// RedactError returns err with its message redacted. The result still matches
+// err with errors.Is and errors.As.
+func (r *Redactor) RedactError(err error) error {
+	if r == nil || err == nil {
+		return err
+	}
+	message := err.Error()
+	if redacted := r.Redact(message); redacted != message {
+		return &redactedError{err: err, message: redacted}
+	}
+	return err
+}
+
+type redactedError struct {
+	err     error
+	message string
+}
+
+func (e *redactedError) Error() string { return e.message }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// RedactMessages returns messages with secrets removed from their text, tool
+// results and tool call arguments. Images are left alone. The input is never
+// modified; it is returned as is when there was nothing to redact.
+func (r *Redactor) RedactMessages(messages []Message) []Message {
+	if r == nil {
+		return messages
+	}
+	var out []Message
+	for i, message := range messages {
+		redacted, changed := r.redactMessage(message)
+		if changed && out == nil {
+			out = append(make([]Message, 0, len(messages)), messages[:i]...)
+		}
+		if out != nil {
+			out = append(out, redacted)
+		}
+	}
+	if out == nil {
+		return messages
+	}
+	return out
+}
+
+// RedactMessage returns message with secrets removed, see RedactMessages
+func (r *Redactor) RedactMessage(message Message) Message {
+	redacted, _ := r.redactMessage(message)
+	return redacted
+}
+
+func (r *Redactor) redactMessage(message Message) (Message, bool) {
+	if r == nil {
+		return message, false
+	}
+	changed := false
+
+	var content MessageContent
+	for i, item := range message.Content {
+		text := r.Redact(item.Text)
+		var call *ToolCall
+		if item.ToolCall != nil {
+			if redacted := r.Redact(item.ToolCall.Function.Arguments); redacted != item.ToolCall.Function.Arguments {
+				copied := *item.ToolCall
+				copied.Function.Arguments = redacted
+				call = &copied
+			}
+		}
+		var result *ToolResult
+		if item.ToolResult != nil {
+			if redacted := r.Redact(item.ToolResult.Content); redacted != item.ToolResult.Content {
+				copied := *item.ToolResult
+				copied.Content = redacted
+				result = &copied
+			}
+		}
+		if text == item.Text && call == nil && result == nil {
+			continue
+		}
+		if content == nil {
+			content = append(MessageContent{}, message.Content...)
+		}
+		content[i].Text = text
+		if call != nil {
+			content[i].ToolCall = call
+		}
+		if result != nil {
+			content[i].ToolResult = result
+		}
+	}
+	if content != nil {
+		message.Content = content
+		changed = true
+	}
+
+	var calls []ToolCall
+	for i, call := range message.ToolCalls {
+		arguments := r.Redact(call.Function.Arguments)
+		if arguments == call.Function.Arguments {
+			continue
+		}
+		if calls == nil {
+			calls = append([]ToolCall{}, message.ToolCalls...)
+		}
+		calls[i].Function.Arguments = arguments
+	}
+	if calls != nil {
+		message.ToolCalls = calls
+		changed = true
+	}
+	return message, changed
+}
+
+// Counts returns the number of distinct secrets redacted, by secret or pattern
+// name
+func (r *Redactor) Counts() map[string]int {
+	counts := map[string]int{}
+	if r == nil {
+		return counts
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, count := range r.counts {
+		counts[name] = count
+	}
+	return counts
+}
+
+// Total returns the number of distinct secrets redacted
+func (r *Redactor) Total() int {
+	total := 0
+	for _, count := range r.Counts() {
+		total += count
+	}
+	return total
+}
+
+// WithRedactor removes secrets from the messages of every request just before
+// it is sent, and from the client's current logger
+func (x *Client) WithRedactor(redactor *Redactor) *Client {
+	x.Redactor = redactor
+	x.Logger = RedactLogger(x.Logger, redactor)
+	return x
+}
+
+func redactedPlaceholder(name string) string {
+	return "[REDACTED:" + name + "]"
+}
+
+// redactingHandler is a slog.Handler that redacts messages and attributes
+// before passing records on
+type redactingHandler struct {
+	handler  slog.Handler
+	redactor *Redactor
+}
+
+// NewRedactingHandler wraps handler so that secrets never reach the log
+func NewRedactingHandler(handler slog.Handler, redactor *Redactor) slog.Handler {
+	if existing, ok := handler.(*redactingHandler); ok && existing.redactor == redactor {
+		return handler
+	}
+	return &redactingHandler{handler: handler, redactor: redactor}
+}
+
+// RedactLogger returns logger with its output redacted, logger itself if
+// redactor is nil or already applied
+func RedactLogger(logger *slog.Logger, redactor *Redactor) *slog.Logger {
+	if logger == nil || redactor == nil {
+		return logger
+	}
+	handler := NewRedactingHandler(logger.Handler(), redactor)
+	if handler == logger.Handler() {
+		return logger
+	}
+	return slog.New(handler)
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(attr))
+		return true
+	})
+	return h.handler.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redactAttr(attr)
+	}
+	return &redactingHandler{handler: h.handler.WithAttrs(redacted), redactor: h.redactor}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{handler: h.handler.WithGroup(name), redactor: h.redactor}
+}
+
+func (h *redactingHandler) redactAttr(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(h.redactor.Redact(attr.Value.String()))
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, member := range group {
+			redacted[i] = h.redactAttr(member)
+		}
+		attr.Value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		// errors, byte slices, maps and structs are redacted as they print, and
+		// keep their type when there was nothing to remove
+		text := fmt.Sprint(attr.Value.Any())
+		if data, ok := attr.Value.Any().([]byte); ok {
+			text = string(data)
+		}
+		if redacted := h.redactor.Redact(text); redacted != text {
+			attr.Value = slog.StringValue(redacted)
+		}
+	}
+	return attr
+}
@@ -0,0 +1,285 @@
+// Package replay provides an http.RoundTripper that records model API traffic
+// into cassette files and replays it later, so sessions can be tested offline
+// and deterministically. Hand the recorder's client to beau.Client.WithHTTPClient
+// or mage.PortalConfig.HTTPClient.
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	ErrCassetteNotFound = fmt.Errorf("cassette not found")
+	ErrNoInteraction    = fmt.Errorf("no recorded interaction matches request")
+	ErrReadCassette     = fmt.Errorf("failed to read cassette")
+	ErrWriteCassette    = fmt.Errorf("failed to write cassette")
+)
+
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and never touches the network
+	ModeReplay Mode = iota
+
+	// ModeRecord forwards requests to the real transport and records every exchange
+	ModeRecord
+
+	// ModeAuto replays when the cassette exists and records otherwise
+	ModeAuto
+)
+
+// Cassette is the on-disk list of recorded exchanges
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest holds only what is used for matching. Headers are never stored
+// so API keys do not end up in cassettes.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body"`
+}
+
+// RecordedResponse holds the whole response body, streamed responses included
+type RecordedResponse struct {
+	StatusCode int                 `json:"status_code"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       string              `json:"body"`
+}
+
+// Normalizer rewrites a request body before matching, e.g. to drop fields that
+// change between runs
+type Normalizer func(body []byte) []byte
+
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+	normalize Normalizer
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+var _ http.RoundTripper = &Recorder{}
+
+// New creates a recorder backed by the cassette file at path. In ModeReplay the
+// cassette must already exist.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      mode,
+		transport: http.DefaultTransport,
+		normalize: NormalizeJSON,
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrReadCassette, err)
+		}
+		if mode == ModeAuto {
+			r.mode = ModeReplay
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s", ErrCassetteNotFound, path)
+		}
+		if mode == ModeAuto {
+			r.mode = ModeRecord
+		}
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrReadCassette, err)
+	}
+
+	// recording always starts a fresh cassette
+	if r.mode == ModeRecord {
+		r.cassette = Cassette{}
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// WithTransport sets the transport used while recording
+func (r *Recorder) WithTransport(transport http.RoundTripper) *Recorder {
+	r.transport = transport
+	return r
+}
+
+// WithNormalizer replaces the default request body normalization
+func (r *Recorder) WithNormalizer(normalize Normalizer) *Recorder {
+	r.normalize = normalize
+	return r
+}
+
+// Mode returns the mode in effect, which for ModeAuto is resolved at creation
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Client returns an http.Client that routes through the recorder
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	recorded := RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Body:   string(r.normalize(body)),
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+	return r.record(req, recorded, body)
+}
+
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// identical requests, e.g. retries, are served in the order they were recorded
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || interaction.Request != recorded {
+			continue
+		}
+		r.used[i] = true
+		return interaction.Response.toHTTP(req), nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, recorded.Method, recorded.Path)
+}
+
+func (r *Recorder) record(req *http.Request, recorded RecordedRequest, body []byte) (*http.Response, error) {
+	outgoing := req.Clone(req.Context())
+	outgoing.Body = io.NopCloser(bytes.NewReader(body))
+	outgoing.ContentLength = int64(len(body))
+
+	resp, err := r.transport.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// streams are read to the end so they can be replayed whole
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := resp.Header.Clone()
+	headers.Del("Set-Cookie")
+	headers.Del("Content-Length")
+
+	response := RecordedResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    headers,
+		Body:       string(respBody),
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request:  recorded,
+		Response: response,
+	})
+	r.used = append(r.used, true)
+	r.mu.Unlock()
+
+	return response.toHTTP(req), nil
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing
+// when replaying.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWriteCassette, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("%w: %v", ErrWriteCassette, err)
+	}
+	if err := os.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("%w: %v", ErrWriteCassette, err)
+	}
+	return nil
+}
+
+// Unused returns the recorded interactions that have not been replayed, useful
+// to assert a session made every request it was expected to
+func (r *Recorder) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []Interaction
+	for i, interaction := range r.cassette.Interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+func (rr RecordedResponse) toHTTP(req *http.Request) *http.Response {
+	header := http.Header{}
+	for k, v := range rr.Headers {
+		header[k] = append([]string{}, v...)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rr.StatusCode, http.StatusText(rr.StatusCode)),
+		StatusCode:    rr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(rr.Body))),
+		ContentLength: int64(len(rr.Body)),
+		Request:       req,
+	}
+}
+
+// NormalizeJSON re-encodes JSON bodies with sorted keys and no insignificant
+// whitespace. Bodies that are not JSON are left untouched.
+func NormalizeJSON(body []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return body
+	}
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return normalized
+}
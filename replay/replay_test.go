@@ -0,0 +1,165 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bosley/beau"
+)
+
+const toolCallResponse = `{"id":"1","object":"chat.completion","model":"test-model","choices":[{"index":0,"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"list_directory","arguments":"{\"directory_path\":\"/tmp\"}"}}]},"finish_reason":"tool_calls"}]}`
+
+const streamedResponse = "data: {\"id\":\"2\",\"model\":\"test-model\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"Two \"}}]}\n\n" +
+	"data: {\"id\":\"2\",\"model\":\"test-model\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"files\"},\"finish_reason\":\"stop\"}]}\n\n" +
+	"data: [DONE]\n\n"
+
+// Helper function to run a two turn session with a tool call and a streamed reply
+func runSession(t *testing.T, client *beau.Client) (*beau.ChatCompletionResponse, string) {
+	ctx := context.Background()
+	messages := []beau.Message{beau.CreateTextMessage(beau.RoleUser, "what is in /tmp?")}
+
+	first, err := client.Send(ctx, 0.7, 100, messages, "test-model")
+	if err != nil {
+		t.Fatalf("First turn failed: %v", err)
+	}
+	if len(first.Choices) == 0 || len(first.Choices[0].Message.ToolCalls) != 1 {
+		t.Fatalf("Expected a tool call, got %+v", first)
+	}
+
+	call := first.Choices[0].Message.ToolCalls[0]
+	messages = append(messages,
+		first.Choices[0].Message,
+		beau.Message{Role: beau.RoleTool, Content: beau.TextContent("a.txt b.txt"), ToolCallID: call.ID},
+	)
+
+	stream := make(chan beau.StreamChunk, 16)
+	if _, err := client.Send(ctx, 0.7, 100, messages, "test-model", beau.WithStream(stream)); err != nil {
+		t.Fatalf("Second turn failed: %v", err)
+	}
+
+	var content strings.Builder
+	for chunk := range stream {
+		content.WriteString(chunk.Content)
+	}
+	return first, content.String()
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, toolCallResponse)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, streamedResponse)
+	}))
+
+	cassettePath := filepath.Join(t.TempDir(), "cassettes", "session.json")
+
+	recorder, err := New(cassettePath, ModeAuto)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	if recorder.Mode() != ModeRecord {
+		t.Fatalf("Expected auto mode to record without a cassette")
+	}
+
+	client, err := beau.NewClient("secret-key", server.URL, recorder.Client(), nil, beau.RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	_, recordedContent := runSession(t, client)
+	server.Close()
+
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Failed to save cassette: %v", err)
+	}
+
+	data, err := os.ReadFile(cassettePath)
+	if err != nil {
+		t.Fatalf("Failed to read cassette: %v", err)
+	}
+	if strings.Contains(string(data), "secret-key") {
+		t.Errorf("Cassette must not contain the API key")
+	}
+
+	// the server is gone, everything must come from the cassette
+	player, err := New(cassettePath, ModeReplay)
+	if err != nil {
+		t.Fatalf("Failed to load cassette: %v", err)
+	}
+	client, err = beau.NewClient("other-key", "http://127.0.0.1:1", player.Client(), nil, beau.RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	first, replayedContent := runSession(t, client)
+
+	if first.Choices[0].Message.ToolCalls[0].Function.Name != "list_directory" {
+		t.Errorf("Unexpected replayed tool call: %+v", first.Choices[0].Message.ToolCalls[0])
+	}
+	if replayedContent != recordedContent || replayedContent != "Two files" {
+		t.Errorf("Expected streamed content %q, got %q", recordedContent, replayedContent)
+	}
+	if unused := player.Unused(); len(unused) != 0 {
+		t.Errorf("Expected every interaction to be replayed, %d left", len(unused))
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := New(filepath.Join(dir, "missing.json"), ModeReplay); !errors.Is(err, ErrCassetteNotFound) {
+		t.Errorf("Expected ErrCassetteNotFound, got %v", err)
+	}
+
+	cassettePath := filepath.Join(dir, "one.json")
+	cassette := `{"interactions":[{"request":{"method":"POST","path":"/v1/chat/completions","body":"{\"a\":1,\"b\":2}"},"response":{"status_code":200,"body":"{}"}}]}`
+	if err := os.WriteFile(cassettePath, []byte(cassette), 0644); err != nil {
+		t.Fatalf("Failed to write cassette: %v", err)
+	}
+
+	player, err := New(cassettePath, ModeReplay)
+	if err != nil {
+		t.Fatalf("Failed to load cassette: %v", err)
+	}
+	client := player.Client()
+
+	tests := []struct {
+		name        string
+		body        string
+		expectError bool
+	}{
+		{name: "Key order and whitespace are ignored", body: `{ "b": 2, "a": 1 }`},
+		{name: "Interactions are consumed once", body: `{"a":1,"b":2}`, expectError: true},
+		{name: "Different body does not match", body: `{"a":2}`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Post("http://example.invalid/v1/chat/completions", "application/json", strings.NewReader(tt.body))
+			if tt.expectError {
+				if !errors.Is(err, ErrNoInteraction) {
+					t.Errorf("Expected ErrNoInteraction, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package beau
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSchemaValidation = fmt.Errorf("value does not match schema")
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// SchemaFor derives a JSON schema from the Go type of v. Field names follow the
+// json tags, fields tagged omitempty or held by pointer are optional and every
+// other field is required. A required:"true" or required:"false" tag overrides
+// that. Fields may also carry description, enum (comma separated), minimum and
+// maximum tags.
+func SchemaFor(v interface{}) map[string]interface{} {
+	return SchemaForType(reflect.TypeOf(v))
+}
+
+// SchemaForType derives a JSON schema from a Go type, see SchemaFor
+func SchemaForType(t reflect.Type) map[string]interface{} {
+	return schemaForType(t, map[reflect.Type]bool{})
+}
+
+func schemaForType(t reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is encoded as a base64 string
+			return map[string]interface{}{"type": "string"}
+		}
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaForType(t.Elem(), visiting),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaForType(t.Elem(), visiting),
+		}
+	case reflect.Struct:
+		if visiting[t] {
+			// recursive types are cut off rather than expanded forever
+			return map[string]interface{}{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		properties := map[string]interface{}{}
+		required := []string{}
+		collectStructFields(t, visiting, properties, &required)
+
+		schema := map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		}
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+		return schema
+	}
+
+	// interfaces and anything else accept any value
+	return map[string]interface{}{}
+}
+
+func collectStructFields(t reflect.Type, visiting map[reflect.Type]bool, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// embedded structs without a name are flattened like encoding/json does
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				collectStructFields(ft, visiting, properties, required)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = applyFieldTags(field, schemaForType(field.Type, visiting))
+
+		optional := strings.Contains(opts, "omitempty") || field.Type.Kind() == reflect.Ptr
+		if tag, ok := field.Tag.Lookup("required"); ok {
+			optional = tag != "true"
+		}
+		if !optional {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// applyFieldTags adds the description, enum, minimum and maximum tags of a field
+// to its schema
+func applyFieldTags(field reflect.StructField, schema map[string]interface{}) map[string]interface{} {
+	if description := field.Tag.Get("description"); description != "" {
+		schema["description"] = description
+	}
+
+	// enum and bounds on a slice field constrain its items
+	target := schema
+	if items, ok := schema["items"].(map[string]interface{}); ok && schema["type"] == "array" {
+		target = items
+	}
+	numeric := target["type"] == "integer" || target["type"] == "number"
+
+	if enum := field.Tag.Get("enum"); enum != "" {
+		var values []interface{}
+		for _, value := range strings.Split(enum, ",") {
+			value = strings.TrimSpace(value)
+			if numeric {
+				if n, err := strconv.ParseFloat(value, 64); err == nil {
+					values = append(values, n)
+					continue
+				}
+			}
+			values = append(values, value)
+		}
+		target["enum"] = values
+	}
+
+	if numeric {
+		if n, err := strconv.ParseFloat(field.Tag.Get("minimum"), 64); err == nil {
+			target["minimum"] = n
+		}
+		if n, err := strconv.ParseFloat(field.Tag.Get("maximum"), 64); err == nil {
+			target["maximum"] = n
+		}
+	}
+	return schema
+}
+
+// ValidateJSON checks a JSON document against a schema. It supports the subset of
+// JSON schema that SchemaFor produces plus enum, minimum and maximum.
+func ValidateJSON(schema map[string]interface{}, data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("%w: invalid JSON: %v", ErrSchemaValidation, err)
+	}
+	return validateValue(schema, value, "$")
+}
+
+func validateValue(schema map[string]interface{}, value interface{}, path string) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	if enum, ok := schema["enum"]; ok && !enumContains(enum, value) {
+		return fmt.Errorf("%w: %s: %v is not one of %v", ErrSchemaValidation, path, value, enum)
+	}
+
+	schemaType, _ := schema["type"].(string)
+	switch schemaType {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return typeMismatch(path, schemaType, value)
+		}
+		required := map[string]bool{}
+		for _, name := range stringList(schema["required"]) {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%w: %s: missing required property %q", ErrSchemaValidation, path, name)
+			}
+			required[name] = true
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, v := range obj {
+			childPath := path + "." + name
+			// an explicit null for an optional property decodes to the zero value
+			if v == nil && !required[name] {
+				continue
+			}
+			if propSchema, ok := properties[name].(map[string]interface{}); ok {
+				if err := validateValue(propSchema, v, childPath); err != nil {
+					return err
+				}
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					return fmt.Errorf("%w: %s: unexpected property", ErrSchemaValidation, childPath)
+				}
+			case map[string]interface{}:
+				if err := validateValue(extra, v, childPath); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return typeMismatch(path, schemaType, value)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, v := range arr {
+				if err := validateValue(items, v, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return typeMismatch(path, schemaType, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeMismatch(path, schemaType, value)
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return typeMismatch(path, schemaType, value)
+		}
+		if schemaType == "integer" && n != float64(int64(n)) {
+			return typeMismatch(path, schemaType, value)
+		}
+		if min, ok := toFloat(schema["minimum"]); ok && n < min {
+			return fmt.Errorf("%w: %s: %v is less than minimum %v", ErrSchemaValidation, path, n, min)
+		}
+		if max, ok := toFloat(schema["maximum"]); ok && n > max {
+			return fmt.Errorf("%w: %s: %v is greater than maximum %v", ErrSchemaValidation, path, n, max)
+		}
+	case "null":
+		if value != nil {
+			return typeMismatch(path, schemaType, value)
+		}
+	}
+
+	return nil
+}
+
+func typeMismatch(path string, expected string, value interface{}) error {
+	return fmt.Errorf("%w: %s: expected %s, got %T", ErrSchemaValidation, path, expected, value)
+}
+
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		var out []string
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func enumContains(enum interface{}, value interface{}) bool {
+	rv := reflect.ValueOf(enum)
+	if rv.Kind() != reflect.Slice {
+		return true
+	}
+	for i := 0; i < rv.Len(); i++ {
+		candidate := rv.Index(i).Interface()
+		if f, ok := toFloat(candidate); ok {
+			if n, ok := value.(float64); ok && n == f {
+				return true
+			}
+			continue
+		}
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,142 @@
+package beau
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SSEEvent is a single dispatched server-sent event
+type SSEEvent struct {
+	// Event is the event type, "message" when the stream did not name it
+	Event string
+
+	// Data is the event payload. Multiple data lines are joined with newlines
+	Data string
+
+	// ID is the last event id seen on the stream
+	ID string
+
+	// Retry is the reconnection time requested by the server, zero if not sent
+	Retry time.Duration
+}
+
+// SSEDecoder reads server-sent events as described by the WHATWG spec. Unlike a
+// bufio.Scanner it places no limit on line length, accepts CRLF, LF and CR line
+// endings, skips comments and reports event names so typed events can be
+// dispatched.
+type SSEDecoder struct {
+	reader  *bufio.Reader
+	lastID  string
+	started bool
+}
+
+func NewSSEDecoder(r io.Reader) *SSEDecoder {
+	return &SSEDecoder{
+		reader: bufio.NewReader(r),
+	}
+}
+
+// Next returns the next event on the stream, or io.EOF once it is exhausted. A
+// final event that is not followed by a blank line is still dispatched since many
+// servers close the connection right after the last data line.
+func (d *SSEDecoder) Next() (*SSEEvent, error) {
+	var eventType string
+	var data strings.Builder
+	var hasData bool
+	var retry time.Duration
+
+	dispatch := func() *SSEEvent {
+		if eventType == "" {
+			eventType = "message"
+		}
+		return &SSEEvent{
+			Event: eventType,
+			Data:  strings.TrimSuffix(data.String(), "\n"),
+			ID:    d.lastID,
+			Retry: retry,
+		}
+	}
+
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			if err == io.EOF && hasData {
+				return dispatch(), nil
+			}
+			return nil, err
+		}
+
+		if line == "" {
+			if !hasData {
+				// blank line without data resets the event, nothing is dispatched
+				eventType = ""
+				retry = 0
+				continue
+			}
+			return dispatch(), nil
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, found := strings.Cut(line, ":")
+		if found {
+			value = strings.TrimPrefix(value, " ")
+		}
+
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				d.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// readLine reads a single line of any length without its terminator
+func (d *SSEDecoder) readLine() (string, error) {
+	var line []byte
+	for {
+		b, err := d.reader.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return d.stripBOM(line), nil
+			}
+			return "", err
+		}
+
+		switch b {
+		case '\n':
+			return d.stripBOM(line), nil
+		case '\r':
+			if next, err := d.reader.Peek(1); err == nil && next[0] == '\n' {
+				d.reader.ReadByte()
+			}
+			return d.stripBOM(line), nil
+		}
+		line = append(line, b)
+	}
+}
+
+// stripBOM removes a byte order mark from the very first line of the stream
+func (d *SSEDecoder) stripBOM(line []byte) string {
+	if !d.started {
+		d.started = true
+		return strings.TrimPrefix(string(line), "\ufeff")
+	}
+	return string(line)
+}
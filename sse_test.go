@@ -0,0 +1,109 @@
+package beau
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper function to decode every event in a stream
+func decodeAllEvents(t *testing.T, stream string) []SSEEvent {
+	decoder := NewSSEDecoder(strings.NewReader(stream))
+	var events []SSEEvent
+	for {
+		event, err := decoder.Next()
+		if err == io.EOF {
+			return events
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		events = append(events, *event)
+	}
+}
+
+func TestSSEDecoder(t *testing.T) {
+	longPayload := strings.Repeat("x", 256*1024)
+
+	tests := []struct {
+		name     string
+		stream   string
+		expected []SSEEvent
+	}{
+		{
+			name:   "Plain data events",
+			stream: "data: one\n\ndata: two\n\n",
+			expected: []SSEEvent{
+				{Event: "message", Data: "one"},
+				{Event: "message", Data: "two"},
+			},
+		},
+		{
+			name:   "Named events and comments",
+			stream: ": keep-alive\nevent: content_block_delta\ndata: {\"a\":1}\n\n: ping\n\nevent: message_stop\ndata: {}\n\n",
+			expected: []SSEEvent{
+				{Event: "content_block_delta", Data: `{"a":1}`},
+				{Event: "message_stop", Data: "{}"},
+			},
+		},
+		{
+			name:   "Multi-line data is joined with newlines",
+			stream: "data: first\ndata:second\ndata:  third\n\n",
+			expected: []SSEEvent{
+				{Event: "message", Data: "first\nsecond\n third"},
+			},
+		},
+		{
+			name:   "CRLF and CR line endings",
+			stream: "event: a\r\ndata: crlf\r\n\r\nevent: b\rdata: cr\r\r",
+			expected: []SSEEvent{
+				{Event: "a", Data: "crlf"},
+				{Event: "b", Data: "cr"},
+			},
+		},
+		{
+			name:   "Lines longer than the scanner limit",
+			stream: "data: " + longPayload + "\n\n",
+			expected: []SSEEvent{
+				{Event: "message", Data: longPayload},
+			},
+		},
+		{
+			name:   "Id and retry fields",
+			stream: "id: 7\nretry: 1500\ndata: x\n\ndata: y\n\n",
+			expected: []SSEEvent{
+				{Event: "message", Data: "x", ID: "7", Retry: 1500 * time.Millisecond},
+				{Event: "message", Data: "y", ID: "7"},
+			},
+		},
+		{
+			name:   "Events without data are not dispatched",
+			stream: "event: ignored\n\ndata: kept\n\n",
+			expected: []SSEEvent{
+				{Event: "message", Data: "kept"},
+			},
+		},
+		{
+			name:   "Trailing event without blank line",
+			stream: "data: [DONE]",
+			expected: []SSEEvent{
+				{Event: "message", Data: "[DONE]"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := decodeAllEvents(t, tt.stream)
+			if len(events) != len(tt.expected) {
+				t.Fatalf("Expected %d events, got %d: %+v", len(tt.expected), len(events), events)
+			}
+			for i, expected := range tt.expected {
+				if events[i] != expected {
+					t.Errorf("Event %d: expected %+v, got %+v", i, expected, events[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrStructuredOutput = fmt.Errorf("failed to get structured output")
+)
+
+// WithResponseFormat constrains the reply format, see JSONObjectFormat and
+// JSONSchemaResponseFormat
+func WithResponseFormat(format ResponseFormat) RequestOption {
+	return func(req *ChatCompletionRequest) {
+		req.ResponseFormat = &format
+	}
+}
+
+// JSONObjectFormat asks for any valid JSON object
+func JSONObjectFormat() ResponseFormat {
+	return ResponseFormat{Type: ResponseFormatJSONObject}
+}
+
+// JSONSchemaResponseFormat asks for JSON matching schema
+func JSONSchemaResponseFormat(name string, schema map[string]interface{}) ResponseFormat {
+	return ResponseFormat{
+		Type: ResponseFormatJSONSchema,
+		JSONSchema: &JSONSchemaFormat{
+			Name:   name,
+			Schema: schema,
+		},
+	}
+}
+
+var schemaNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// SendTyped sends messages asking for a reply matching the schema of T, validates
+// the reply and decodes it. Replies that fail validation are fed back to the model
+// with the validation error, up to retries additional times. Requests use
+// DefaultTemperature and DefaultMaxTokens unless opts set them. A T that does not
+// encode to a JSON object, such as a slice or a string, is asked for as the value
+// field of an object, since strict schemas must be objects at the top level.
+func SendTyped[T any](ctx context.Context, x *Client, model string, messages []Message, retries int, opts ...RequestOption) (*T, error) {
+	var zero T
+	schema := SchemaForType(reflect.TypeOf(zero))
+	wrapped := schema["type"] != "object"
+	if wrapped {
+		schema = map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"value": schema},
+			"required":   []string{"value"},
+		}
+	}
+
+	name := schemaNameSanitizer.ReplaceAllString(reflect.TypeOf(zero).Name(), "_")
+	if name == "" {
+		name = "response"
+	}
+
+	format := JSONSchemaResponseFormat(name, schema)
+	finalOptions := append([]RequestOption{WithTemperature(DefaultTemperature), WithMaxTokens(DefaultMaxTokens)}, opts...)
+	finalOptions = append(finalOptions, WithResponseFormat(format))
+
+	// work on a copy so correction turns never leak into the caller's slice
+	history := append([]Message{}, messages...)
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		response, err := x.Send(ctx, 0, 0, history, model, finalOptions...)
+		if err != nil {
+			return nil, err
+		}
+		if len(response.Choices) == 0 {
+			return nil, ErrNoResponseChoices
+		}
+
+		reply := response.Choices[0].Message
+		payload := extractJSON(reply.Content.Text())
+
+		if err := ValidateJSON(schema, []byte(payload)); err != nil {
+			lastErr = err
+			x.Logger.Warn("Structured output failed validation",
+				"attempt", attempt+1,
+				"model", model,
+				"error", err)
+
+			history = append(history,
+				CreateTextMessage(RoleAssistant, reply.Content.Text()),
+				CreateTextMessage(RoleUser, fmt.Sprintf(
+					"Your previous response did not match the required JSON schema: %v\nRespond again with only the corrected JSON.", err)),
+			)
+			continue
+		}
+
+		if wrapped {
+			var envelope struct {
+				Value T `json:"value"`
+			}
+			if err := json.Unmarshal([]byte(payload), &envelope); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrUnmarshalResponse, err)
+			}
+			return &envelope.Value, nil
+		}
+		result := new(T)
+		if err := json.Unmarshal([]byte(payload), result); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnmarshalResponse, err)
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("%w: %v", ErrStructuredOutput, lastErr)
+}
+
+// extractJSON strips markdown code fences models sometimes wrap JSON replies in
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(text, "```")
+	}
+	return strings.TrimSpace(text)
+}
+
+// responseFormatInstruction describes a response format in plain words for
+// providers that have no native support for it
+func responseFormatInstruction(format *ResponseFormat) string {
+	if format == nil {
+		return ""
+	}
+	switch format.Type {
+	case ResponseFormatJSONObject:
+		return "Respond only with a single valid JSON object and no other text."
+	case ResponseFormatJSONSchema:
+		if format.JSONSchema == nil {
+			return "Respond only with a single valid JSON object and no other text."
+		}
+		schema, err := json.Marshal(format.JSONSchema.Schema)
+		if err != nil {
+			return "Respond only with a single valid JSON object and no other text."
+		}
+		return fmt.Sprintf("Respond only with a single valid JSON value matching this JSON schema and no other text:\n%s", schema)
+	}
+	return ""
+}
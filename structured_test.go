@@ -0,0 +1,155 @@
+package beau
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type testReview struct {
+	Title    string   `json:"title"`
+	Score    int      `json:"score"`
+	Tags     []string `json:"tags,omitempty"`
+	Reviewer *struct {
+		Name string `json:"name"`
+	} `json:"reviewer,omitempty"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema := SchemaFor(testReview{})
+
+	if schema["type"] != "object" {
+		t.Fatalf("Expected object schema, got %v", schema["type"])
+	}
+
+	required := schema["required"].([]string)
+	if !reflect.DeepEqual(required, []string{"score", "title"}) {
+		t.Errorf("Expected required [score title], got %v", required)
+	}
+
+	properties := schema["properties"].(map[string]interface{})
+	expectedTypes := map[string]string{
+		"title":    "string",
+		"score":    "integer",
+		"tags":     "array",
+		"reviewer": "object",
+	}
+	for name, expected := range expectedTypes {
+		prop := properties[name].(map[string]interface{})
+		if prop["type"] != expected {
+			t.Errorf("Property %s: expected type %s, got %v", name, expected, prop["type"])
+		}
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	schema := SchemaFor(testReview{})
+
+	tests := []struct {
+		name        string
+		data        string
+		expectError bool
+	}{
+		{name: "Valid document", data: `{"title":"ok","score":3,"tags":["a"]}`},
+		{name: "Missing required property", data: `{"title":"ok"}`, expectError: true},
+		{name: "Wrong type", data: `{"title":"ok","score":"high"}`, expectError: true},
+		{name: "Fractional integer", data: `{"title":"ok","score":1.5}`, expectError: true},
+		{name: "Wrong item type", data: `{"title":"ok","score":1,"tags":[1]}`, expectError: true},
+		{name: "Not JSON", data: `the score is 3`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJSON(schema, []byte(tt.data))
+			if tt.expectError && !errors.Is(err, ErrSchemaValidation) {
+				t.Errorf("Expected validation error, got %v", err)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSendTypedRetriesInvalidReplies(t *testing.T) {
+	replies := []string{
+		`{"title":"missing score"}`,
+		"```json\n{\"title\":\"fixed\",\"score\":4}\n```",
+	}
+	var requests []ChatCompletionRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+
+		reply := replies[len(requests)-1]
+		json.NewEncoder(w).Encode(ChatCompletionResponse{
+			Choices: []Choice{{Message: CreateTextMessage(RoleAssistant, reply)}},
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	review, err := SendTyped[testReview](context.Background(), client, "test-model",
+		[]Message{CreateTextMessage(RoleUser, "review this")}, 1, WithTemperature(0.1), WithMaxTokens(300))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if review.Title != "fixed" || review.Score != 4 {
+		t.Errorf("Unexpected result: %+v", review)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	format := requests[0].ResponseFormat
+	if format == nil || format.Type != ResponseFormatJSONSchema || format.JSONSchema.Name != "testReview" {
+		t.Errorf("Expected json_schema response format, got %+v", format)
+	}
+	if requests[0].Temperature != 0.1 || requests[0].MaxTokens != 300 {
+		t.Errorf("Expected the caller's options, got temperature %v and max tokens %d", requests[0].Temperature, requests[0].MaxTokens)
+	}
+	if len(requests[1].Messages) != 3 {
+		t.Errorf("Expected the invalid reply and a correction in the retry, got %d messages", len(requests[1].Messages))
+	}
+}
+
+func TestSendTypedWrapsNonObjects(t *testing.T) {
+	var request ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&request)
+		json.NewEncoder(w).Encode(ChatCompletionResponse{
+			Choices: []Choice{{Message: CreateTextMessage(RoleAssistant, `{"value":["red","green"]}`)}},
+		})
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-key", server.URL, nil, nil, RetryConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	colors, err := SendTyped[[]string](context.Background(), client, "test-model",
+		[]Message{CreateTextMessage(RoleUser, "name two colors")}, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(*colors) != 2 || (*colors)[1] != "green" {
+		t.Errorf("Unexpected result: %v", *colors)
+	}
+
+	schema := request.ResponseFormat.JSONSchema.Schema
+	if schema["type"] != "object" || request.Temperature != DefaultTemperature || request.MaxTokens != DefaultMaxTokens {
+		t.Errorf("Expected an object schema and the default settings, got %v", request)
+	}
+}
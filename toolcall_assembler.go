@@ -0,0 +1,134 @@
+package beau
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrIncompleteToolCall = fmt.Errorf("streamed tool call arguments are not complete JSON")
+)
+
+// ToolCallDelta is a fragment of a tool call as it appears in a streamed chunk.
+// The first fragment of a call usually carries the id and name, the following ones
+// only carry the index and a piece of the arguments.
+type ToolCallDelta struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+// ToolCallAssembler rebuilds complete tool calls from streamed deltas. Deltas are
+// keyed by their index so any number of parallel calls can be interleaved. Deltas
+// without an index are matched by id, and failing that are attributed to the most
+// recent call, which is how providers that stream one call at a time behave.
+type ToolCallAssembler struct {
+	calls     map[int]*ToolCall
+	arguments map[int]*strings.Builder
+	ids       map[string]int
+	last      int
+}
+
+func NewToolCallAssembler() *ToolCallAssembler {
+	return &ToolCallAssembler{
+		calls:     map[int]*ToolCall{},
+		arguments: map[int]*strings.Builder{},
+		ids:       map[string]int{},
+		last:      -1,
+	}
+}
+
+// Add merges a delta into the call it belongs to
+func (a *ToolCallAssembler) Add(delta ToolCallDelta) {
+	index, ok := a.indexFor(delta)
+	if !ok {
+		return
+	}
+
+	call, exists := a.calls[index]
+	if !exists {
+		call = &ToolCall{Type: "function"}
+		a.calls[index] = call
+		a.arguments[index] = &strings.Builder{}
+	}
+
+	if delta.ID != "" {
+		call.ID = delta.ID
+		a.ids[delta.ID] = index
+	}
+	if delta.Type != "" {
+		call.Type = delta.Type
+	}
+	if delta.Function.Name != "" {
+		call.Function.Name = delta.Function.Name
+	}
+	a.arguments[index].WriteString(delta.Function.Arguments)
+	a.last = index
+}
+
+func (a *ToolCallAssembler) indexFor(delta ToolCallDelta) (int, bool) {
+	if delta.Index != nil {
+		return *delta.Index, true
+	}
+	if delta.ID != "" {
+		if index, ok := a.ids[delta.ID]; ok {
+			return index, true
+		}
+		return a.nextIndex(), true
+	}
+	if a.last >= 0 {
+		return a.last, true
+	}
+	return 0, false
+}
+
+func (a *ToolCallAssembler) nextIndex() int {
+	next := 0
+	for index := range a.calls {
+		if index >= next {
+			next = index + 1
+		}
+	}
+	return next
+}
+
+// Len returns the number of calls seen so far
+func (a *ToolCallAssembler) Len() int {
+	return len(a.calls)
+}
+
+// ToolCalls returns the assembled calls ordered by index. Calls that streamed no
+// arguments get an empty object, calls whose arguments do not form valid JSON
+// cause ErrIncompleteToolCall.
+func (a *ToolCallAssembler) ToolCalls() ([]ToolCall, error) {
+	if len(a.calls) == 0 {
+		return nil, nil
+	}
+
+	indexes := make([]int, 0, len(a.calls))
+	for index := range a.calls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	calls := make([]ToolCall, 0, len(indexes))
+	for _, index := range indexes {
+		call := *a.calls[index]
+		call.Function.Arguments = a.arguments[index].String()
+		if strings.TrimSpace(call.Function.Arguments) == "" {
+			call.Function.Arguments = "{}"
+		}
+		if !json.Valid([]byte(call.Function.Arguments)) {
+			return nil, fmt.Errorf("%w: tool call %d (%s): %s",
+				ErrIncompleteToolCall, index, call.Function.Name, call.Function.Arguments)
+		}
+		calls = append(calls, call)
+	}
+	return calls, nil
+}
@@ -0,0 +1,131 @@
+package beau
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Helper function to replay a recorded SSE transcript through the OpenAI decoder
+func decodeTranscript(t *testing.T, name string) (*ChatCompletionResponse, []StreamChunk, error) {
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to open transcript: %v", err)
+	}
+	defer file.Close()
+
+	stream := make(chan StreamChunk, 100)
+	response, err := NewOpenAIProvider().DecodeStream(context.Background(), file, stream)
+	close(stream)
+
+	var chunks []StreamChunk
+	for chunk := range stream {
+		chunks = append(chunks, chunk)
+	}
+	return response, chunks, err
+}
+
+func TestToolCallAssemblerTranscripts(t *testing.T) {
+	tests := []struct {
+		name          string
+		transcript    string
+		expectError   error
+		expectContent string
+		expectFinish  string
+		expectedCalls []ToolCall
+	}{
+		{
+			name:         "Interleaved parallel tool calls",
+			transcript:   "openai_parallel_tool_calls.sse",
+			expectFinish: "tool_calls",
+			expectedCalls: []ToolCall{
+				{ID: "call_weather", Type: "function", Function: ToolFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+				{ID: "call_time", Type: "function", Function: ToolFunction{Name: "get_time", Arguments: `{"zone":"Europe/Paris"}`}},
+				{ID: "call_news", Type: "function", Function: ToolFunction{Name: "get_news", Arguments: `{}`}},
+			},
+		},
+		{
+			name:          "Content followed by a fragmented tool call",
+			transcript:    "openai_content_and_tool_call.sse",
+			expectContent: "Let me check that.",
+			expectFinish:  "tool_calls",
+			expectedCalls: []ToolCall{
+				{ID: "call_ls", Type: "function", Function: ToolFunction{Name: "list_directory", Arguments: `{"directory_path": "/tmp/project"}`}},
+			},
+		},
+		{
+			name:       "Deltas without index fall back to ids",
+			transcript: "legacy_tool_calls_without_index.sse",
+			expectedCalls: []ToolCall{
+				{ID: "call_a", Type: "function", Function: ToolFunction{Name: "read_file", Arguments: `{"file_path":"/tmp/a.txt"}`}},
+				{ID: "call_b", Type: "function", Function: ToolFunction{Name: "read_file", Arguments: `{"file_path":"/tmp/b.txt"}`}},
+			},
+		},
+		{
+			name:        "Truncated arguments are rejected",
+			transcript:  "openai_truncated_tool_call.sse",
+			expectError: ErrIncompleteToolCall,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, chunks, err := decodeTranscript(t, tt.transcript)
+
+			if tt.expectError != nil {
+				if !errors.Is(err, tt.expectError) {
+					t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			choice := response.Choices[0]
+			if content := choice.Message.Content.Text(); content != tt.expectContent {
+				t.Errorf("Expected content %q, got %q", tt.expectContent, content)
+			}
+
+			var streamed string
+			for _, chunk := range chunks {
+				streamed += chunk.Content
+			}
+			if streamed != tt.expectContent {
+				t.Errorf("Expected streamed content %q, got %q", tt.expectContent, streamed)
+			}
+
+			if choice.FinishReason != tt.expectFinish {
+				t.Errorf("Expected finish reason %q, got %q", tt.expectFinish, choice.FinishReason)
+			}
+
+			if len(choice.Message.ToolCalls) != len(tt.expectedCalls) {
+				t.Fatalf("Expected %d tool calls, got %d: %+v", len(tt.expectedCalls), len(choice.Message.ToolCalls), choice.Message.ToolCalls)
+			}
+			for i, expected := range tt.expectedCalls {
+				if got := choice.Message.ToolCalls[i]; got != expected {
+					t.Errorf("Tool call %d: expected %+v, got %+v", i, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestToolCallAssemblerEmptyArguments(t *testing.T) {
+	index := 0
+	assembler := NewToolCallAssembler()
+
+	delta := ToolCallDelta{Index: &index, ID: "call_1"}
+	delta.Function.Name = "get_system_info"
+	assembler.Add(delta)
+
+	calls, err := assembler.ToolCalls()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(calls) != 1 || calls[0].Function.Arguments != "{}" {
+		t.Errorf("Expected a single call with empty object arguments, got %+v", calls)
+	}
+}
@@ -2,7 +2,6 @@ package fskit
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -109,6 +108,10 @@ func validatePath(projects []beau.ProjectBounds, path string) (string, error) {
 		path, strings.Join(allowedPaths, ", "), helpfulPath)
 }
 
+type readFileArgs struct {
+	FilePath string `json:"file_path" description:"Absolute path to the file to read. Must start with / (e.g., /home/user/project/main.go)"`
+}
+
 func validatedReadFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
 	// Define token limits - rough estimate: 1 token ≈ 4 characters
 	const (
@@ -117,29 +120,10 @@ func validatedReadFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
 		contextLines = 50         // Lines of context to show for large files
 	)
 
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "read_file",
-			Description: "Read the entire content of a file. For files >400KB, automatically provides a summary with beginning/end snippets. Use analyze_file first to check size.",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"file_path": map[string]interface{}{
-						"type":        "string",
-						"description": "Absolute path to the file to read. Must start with / (e.g., /home/user/project/main.go)",
-					},
-				},
-				"required": []string{"file_path"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				FilePath string `json:"file_path"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
-
+	return toolkit.NewTypedTool(
+		"read_file",
+		"Read the entire content of a file. For files >400KB, automatically provides a summary with beginning/end snippets. Use analyze_file first to check size.",
+		func(args readFileArgs) (interface{}, error) {
 			// Validate path
 			validPath, err := validatePath(projects, args.FilePath)
 			if err != nil {
@@ -239,40 +223,17 @@ To read specific sections, consider:
 	)
 }
 
-func validatedReadFileChunkTool(projects []beau.ProjectBounds) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "read_file_chunk",
-			Description: "Read a specific portion of a file by line numbers. Essential for reading large files in manageable chunks. Use after analyze_file shows file is too large.",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"file_path": map[string]interface{}{
-						"type":        "string",
-						"description": "Absolute path to the file. Must start with / (e.g., /home/user/project/large.log)",
-					},
-					"start_line": map[string]interface{}{
-						"type":        "integer",
-						"description": "Starting line number (1-indexed, inclusive). Default is 1.",
-					},
-					"end_line": map[string]interface{}{
-						"type":        "integer",
-						"description": "Ending line number (1-indexed, inclusive). If omitted, reads 1000 lines from start_line.",
-					},
-				},
-				"required": []string{"file_path"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				FilePath  string `json:"file_path"`
-				StartLine int    `json:"start_line"`
-				EndLine   int    `json:"end_line"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type readFileChunkArgs struct {
+	FilePath  string `json:"file_path" description:"Absolute path to the file. Must start with / (e.g., /home/user/project/large.log)"`
+	StartLine int    `json:"start_line,omitempty" description:"Starting line number (1-indexed, inclusive). Default is 1."`
+	EndLine   int    `json:"end_line,omitempty" description:"Ending line number (1-indexed, inclusive). If omitted, reads 1000 lines from start_line."`
+}
 
+func validatedReadFileChunkTool(projects []beau.ProjectBounds) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"read_file_chunk",
+		"Read a specific portion of a file by line numbers. Essential for reading large files in manageable chunks. Use after analyze_file shows file is too large.",
+		func(args readFileChunkArgs) (interface{}, error) {
 			// Default values
 			if args.StartLine <= 0 {
 				args.StartLine = 1
@@ -345,35 +306,16 @@ func validatedReadFileChunkTool(projects []beau.ProjectBounds) toolkit.LlmTool {
 	)
 }
 
-func validatedWriteFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "write_file",
-			Description: "Write content to a file. Creates the file if it doesn't exist, overwrites if it does. Creates parent directories as needed.",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"file_path": map[string]interface{}{
-						"type":        "string",
-						"description": "Absolute path where to write the file. Must start with / (e.g., /home/user/project/new_file.txt)",
-					},
-					"content": map[string]interface{}{
-						"type":        "string",
-						"description": "The exact content to write to the file. Will be written as-is.",
-					},
-				},
-				"required": []string{"file_path", "content"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				FilePath string `json:"file_path"`
-				Content  string `json:"content"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type writeFileArgs struct {
+	FilePath string `json:"file_path" description:"Absolute path where to write the file. Must start with / (e.g., /home/user/project/new_file.txt)"`
+	Content  string `json:"content" description:"The exact content to write to the file. Will be written as-is."`
+}
 
+func validatedWriteFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"write_file",
+		"Write content to a file. Creates the file if it doesn't exist, overwrites if it does. Creates parent directories as needed.",
+		func(args writeFileArgs) (interface{}, error) {
 			// Validate path
 			validPath, err := validatePath(projects, args.FilePath)
 			if err != nil {
@@ -397,6 +339,10 @@ func validatedWriteFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
 	)
 }
 
+type listDirectoryArgs struct {
+	DirectoryPath string `json:"directory_path"`
+}
+
 func validatedListDirectoryTool(projects []beau.ProjectBounds) toolkit.LlmTool {
 	// Build example paths from actual projects
 	examplePath := "/home/user/project"
@@ -415,29 +361,10 @@ func validatedListDirectoryTool(projects []beau.ProjectBounds) toolkit.LlmTool {
 		description = fmt.Sprintf("List contents of a directory within the project. Available directories: %s", strings.Join(projectPaths, ", "))
 	}
 
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "list_directory",
-			Description: description,
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"directory_path": map[string]interface{}{
-						"type":        "string",
-						"description": fmt.Sprintf("Absolute path to directory (e.g., %s or %s)", examplePath, exampleSubPath),
-					},
-				},
-				"required": []string{"directory_path"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				DirectoryPath string `json:"directory_path"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
-
+	return toolkit.NewTypedTool(
+		"list_directory",
+		description,
+		func(args listDirectoryArgs) (interface{}, error) {
 			// Default to project root
 			if args.DirectoryPath == "" {
 				args.DirectoryPath = "."
@@ -484,35 +411,20 @@ func validatedListDirectoryTool(projects []beau.ProjectBounds) toolkit.LlmTool {
 				"total_items": len(entries),
 			}, nil
 		},
-	)
+	).WithPropertyDescription("directory_path", fmt.Sprintf("Absolute path to directory (e.g., %s or %s)", examplePath, exampleSubPath))
+}
+
+type analyzeFileArgs struct {
+	FilePath string `json:"file_path" description:"Absolute path to analyze. Must start with / (e.g., /home/user/project/data.csv)"`
 }
 
 func validatedAnalyzeFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
 	const maxFileSize = 400 * 1024 // Same limit as read_file tool
 
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "analyze_file",
-			Description: "ALWAYS use this FIRST before any file operation. Returns file size, line count, and recommendations for the best way to read the file.",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"file_path": map[string]interface{}{
-						"type":        "string",
-						"description": "Absolute path to analyze. Must start with / (e.g., /home/user/project/data.csv)",
-					},
-				},
-				"required": []string{"file_path"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				FilePath string `json:"file_path"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
-
+	return toolkit.NewTypedTool(
+		"analyze_file",
+		"ALWAYS use this FIRST before any file operation. Returns file size, line count, and recommendations for the best way to read the file.",
+		func(args analyzeFileArgs) (interface{}, error) {
 			// Validate path
 			validPath, err := validatePath(projects, args.FilePath)
 			if err != nil {
@@ -583,35 +495,16 @@ func formatFileSize(size int64) string {
 	}
 }
 
-func validatedRenameFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "rename_file",
-			Description: "Rename or move a file within the project directories",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"old_path": map[string]interface{}{
-						"type":        "string",
-						"description": "Current absolute path of the file (e.g., /home/user/project/old.txt)",
-					},
-					"new_path": map[string]interface{}{
-						"type":        "string",
-						"description": "New absolute path for the file (e.g., /home/user/project/new.txt)",
-					},
-				},
-				"required": []string{"old_path", "new_path"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				OldPath string `json:"old_path"`
-				NewPath string `json:"new_path"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type renameFileArgs struct {
+	OldPath string `json:"old_path" description:"Current absolute path of the file (e.g., /home/user/project/old.txt)"`
+	NewPath string `json:"new_path" description:"New absolute path for the file (e.g., /home/user/project/new.txt)"`
+}
 
+func validatedRenameFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"rename_file",
+		"Rename or move a file within the project directories",
+		func(args renameFileArgs) (interface{}, error) {
 			// Validate old path
 			validOldPath, err := validatePath(projects, args.OldPath)
 			if err != nil {
@@ -653,55 +546,20 @@ func validatedRenameFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
 	)
 }
 
-func validatedGrepFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "grep_file",
-			Description: "Search for text patterns in a file. Returns matching lines with line numbers. Supports both simple text and regex patterns.",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"file_path": map[string]interface{}{
-						"type":        "string",
-						"description": "Absolute path to search in. Must start with / (e.g., /home/user/project/src/main.py)",
-					},
-					"pattern": map[string]interface{}{
-						"type":        "string",
-						"description": "Text or regex pattern to search for (e.g., 'TODO', 'function.*test', 'error|warning')",
-					},
-					"use_regex": map[string]interface{}{
-						"type":        "boolean",
-						"description": "If true, treats pattern as regex. If false, does simple text matching. Default: false",
-					},
-					"ignore_case": map[string]interface{}{
-						"type":        "boolean",
-						"description": "If true, ignores case when matching. Default: false",
-					},
-					"context_lines": map[string]interface{}{
-						"type":        "integer",
-						"description": "Number of lines to show before/after each match for context. Default: 0",
-					},
-					"max_matches": map[string]interface{}{
-						"type":        "integer",
-						"description": "Maximum matches to return. Default: 100",
-					},
-				},
-				"required": []string{"file_path", "pattern"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				FilePath     string `json:"file_path"`
-				Pattern      string `json:"pattern"`
-				UseRegex     bool   `json:"use_regex"`
-				IgnoreCase   bool   `json:"ignore_case"`
-				ContextLines int    `json:"context_lines"`
-				MaxMatches   int    `json:"max_matches"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type grepFileArgs struct {
+	FilePath     string `json:"file_path" description:"Absolute path to search in. Must start with / (e.g., /home/user/project/src/main.py)"`
+	Pattern      string `json:"pattern" description:"Text or regex pattern to search for (e.g., 'TODO', 'function.*test', 'error|warning')"`
+	UseRegex     bool   `json:"use_regex,omitempty" description:"If true, treats pattern as regex. If false, does simple text matching. Default: false"`
+	IgnoreCase   bool   `json:"ignore_case,omitempty" description:"If true, ignores case when matching. Default: false"`
+	ContextLines int    `json:"context_lines,omitempty" description:"Number of lines to show before/after each match for context. Default: 0"`
+	MaxMatches   int    `json:"max_matches,omitempty" description:"Maximum matches to return. Default: 100"`
+}
 
+func validatedGrepFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"grep_file",
+		"Search for text patterns in a file. Returns matching lines with line numbers. Supports both simple text and regex patterns.",
+		func(args grepFileArgs) (interface{}, error) {
 			// Set defaults
 			if args.MaxMatches <= 0 {
 				args.MaxMatches = 100
@@ -828,60 +686,21 @@ func validatedGrepFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
 	)
 }
 
-func validatedReplaceInFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "replace_in_file",
-			Description: "Replace all occurrences of a pattern in a file. Creates a backup before making changes.",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"file_path": map[string]interface{}{
-						"type":        "string",
-						"description": "Absolute path to the file to modify (e.g., /home/user/project/file.txt)",
-					},
-					"pattern": map[string]interface{}{
-						"type":        "string",
-						"description": "Pattern to search for (supports simple string matching or regex)",
-					},
-					"replacement": map[string]interface{}{
-						"type":        "string",
-						"description": "String to replace matches with",
-					},
-					"use_regex": map[string]interface{}{
-						"type":        "boolean",
-						"description": "Whether to use regex matching (default: false)",
-					},
-					"ignore_case": map[string]interface{}{
-						"type":        "boolean",
-						"description": "Whether to ignore case when matching (default: false)",
-					},
-					"create_backup": map[string]interface{}{
-						"type":        "boolean",
-						"description": "Whether to create a backup file before replacing (default: true)",
-					},
-					"dry_run": map[string]interface{}{
-						"type":        "boolean",
-						"description": "If true, only show what would be replaced without making changes (default: false)",
-					},
-				},
-				"required": []string{"file_path", "pattern", "replacement"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				FilePath     string `json:"file_path"`
-				Pattern      string `json:"pattern"`
-				Replacement  string `json:"replacement"`
-				UseRegex     bool   `json:"use_regex"`
-				IgnoreCase   bool   `json:"ignore_case"`
-				CreateBackup *bool  `json:"create_backup"`
-				DryRun       bool   `json:"dry_run"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type replaceInFileArgs struct {
+	FilePath     string `json:"file_path" description:"Absolute path to the file to modify (e.g., /home/user/project/file.txt)"`
+	Pattern      string `json:"pattern" description:"Pattern to search for (supports simple string matching or regex)"`
+	Replacement  string `json:"replacement" description:"String to replace matches with"`
+	UseRegex     bool   `json:"use_regex,omitempty" description:"Whether to use regex matching (default: false)"`
+	IgnoreCase   bool   `json:"ignore_case,omitempty" description:"Whether to ignore case when matching (default: false)"`
+	CreateBackup *bool  `json:"create_backup,omitempty" description:"Whether to create a backup file before replacing (default: true)"`
+	DryRun       bool   `json:"dry_run,omitempty" description:"If true, only show what would be replaced without making changes (default: false)"`
+}
 
+func validatedReplaceInFileTool(projects []beau.ProjectBounds) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"replace_in_file",
+		"Replace all occurrences of a pattern in a file. Creates a backup before making changes.",
+		func(args replaceInFileArgs) (interface{}, error) {
 			// Default create_backup to true if not specified
 			if args.CreateBackup == nil {
 				createBackup := true
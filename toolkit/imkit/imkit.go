@@ -2,7 +2,6 @@ package imkit
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"time"
@@ -23,18 +22,8 @@ const (
 
 // GetImageKit returns a toolkit with image analysis capabilities
 func GetImageKit(apiKey string, baseURL string, logger *slog.Logger, callback toolkit.KitCallback, model string, projectBounds []beau.ProjectBounds) *toolkit.LlmToolKit {
-	return toolkit.NewKit("Image Kit").
-		WithTool(getImageAnalysisTool(apiKey, baseURL, logger, model, projectBounds)).
-		WithCallback(callback)
-}
-
-// getImageAnalysisTool creates a tool for analyzing images with vision models
-func getImageAnalysisTool(apiKey string, baseURL string, logger *slog.Logger, model string, projectBounds []beau.ProjectBounds) toolkit.LlmTool {
-	analyzeImage := func(variant TargetVariant, target, query string, temperature float64, maxTokens int) (string, error) {
-		// Create context for the vision API request
-		ctx := context.Background()
-
-		// Configure XAI client with the provided API key
+	newClient := func() (*beau.Client, error) {
+		// Configure client with the provided API key
 		client, err := beau.NewClient(apiKey, baseURL, nil, logger, beau.RetryConfig{
 			MaxRetries:    5,
 			InitialDelay:  2 * time.Second,
@@ -42,17 +31,50 @@ func getImageAnalysisTool(apiKey string, baseURL string, logger *slog.Logger, mo
 			BackoffFactor: 2.0,
 			Enabled:       true,
 		})
-
 		if err != nil {
-			return "", fmt.Errorf("failed to create client: %w", err)
-		}
-
-		if baseURL != "" {
-			client.BaseURL = baseURL
+			return nil, err
 		}
 		if logger != nil {
 			client = client.WithLogger(logger.WithGroup("image_analysis"))
 		}
+		return client, nil
+	}
+
+	return toolkit.NewKit("Image Kit").
+		WithTool(getImageAnalysisTool(newClient, model, projectBounds)).
+		WithCallback(callback)
+}
+
+// GetImageKitForClient returns a toolkit with image analysis capabilities that sends
+// its vision requests through an already configured client
+func GetImageKitForClient(client *beau.Client, callback toolkit.KitCallback, model string, projectBounds []beau.ProjectBounds) *toolkit.LlmToolKit {
+	newClient := func() (*beau.Client, error) {
+		return client, nil
+	}
+
+	return toolkit.NewKit("Image Kit").
+		WithTool(getImageAnalysisTool(newClient, model, projectBounds)).
+		WithCallback(callback)
+}
+
+type analyzeImageArgs struct {
+	Variant     string  `json:"variant" description:"The type of image input - 'file' for file path or 'raw' for base64 encoded image data" enum:"file,raw"`
+	Target      string  `json:"target" description:"Either a file path (when variant='file') or base64 encoded image data (when variant='raw')"`
+	Query       string  `json:"query" description:"Specific question or instruction about the image (e.g., 'What's in this image?', 'Describe the objects in detail')"`
+	Temperature float64 `json:"temperature,omitempty" description:"Temperature for the vision model (between 0.0 and 1.0). Higher values make output more random, lower values more deterministic. Default is 0.7 if not specified." minimum:"0" maximum:"1"`
+	MaxTokens   int     `json:"max_tokens,omitempty" description:"Maximum number of tokens to generate. Default is 2000 if not specified."`
+}
+
+// getImageAnalysisTool creates a tool for analyzing images with vision models
+func getImageAnalysisTool(newClient func() (*beau.Client, error), model string, projectBounds []beau.ProjectBounds) toolkit.LlmTool {
+	analyzeImage := func(variant TargetVariant, target, query string, temperature float64, maxTokens int) (string, error) {
+		// Create context for the vision API request
+		ctx := context.Background()
+
+		client, err := newClient()
+		if err != nil {
+			return "", fmt.Errorf("failed to create client: %w", err)
+		}
 
 		// Use default values if not provided
 		if temperature <= 0 {
@@ -84,7 +106,9 @@ Focus on:
 Structure your response clearly with sections if needed. Be thorough but concise.
 If asked a specific question, address it directly while providing relevant context.`)
 
-		var imgBase64, imgType string
+		// Size the image for the vision model so it is not downscaled again remotely
+		imageOptions := beau.ImageOptionsForModel(model, "high")
+		var image *beau.PreparedImage
 		var err2 error
 
 		// Handle different target variants
@@ -99,21 +123,19 @@ If asked a specific question, address it directly while providing relevant conte
 				target = validPath
 			}
 
-			imgBase64, imgType, err2 = beau.ReadImageFile(target)
+			image, err2 = beau.PrepareImageFile(target, imageOptions)
 			if err2 != nil {
 				return "", fmt.Errorf("failed to read image file: %w", err2)
 			}
 		case Raw:
-			imgBase64 = target
-			// For raw data, we assume it's already base64 encoded and try to detect type
-			imgType = "image/jpeg" // Default to JPEG if can't determine
-			if len(target) > 11 {
-				switch target[0:11] {
-				case "data:image/p":
-					imgType = "image/png"
-				case "data:image/j":
-					imgType = "image/jpeg"
-				}
+			// Raw data is base64 encoded, with or without a data url prefix
+			data, err := beau.DecodeImageData(target)
+			if err != nil {
+				return "", fmt.Errorf("failed to decode image data: %w", err)
+			}
+			image, err2 = beau.PrepareImage(data, imageOptions)
+			if err2 != nil {
+				return "", fmt.Errorf("failed to prepare image: %w", err2)
 			}
 		default:
 			return "", fmt.Errorf("unsupported target variant: %s", variant)
@@ -122,7 +144,7 @@ If asked a specific question, address it directly while providing relevant conte
 		// Create complex message with both the query and image
 		complexMessage := []beau.ContentItem{
 			beau.CreateTextItem(query),
-			beau.CreateImageBase64Item(imgBase64, imgType, "high"),
+			image.ContentItem(),
 		}
 		visionConversation.AddComplexUserMessage(complexMessage)
 
@@ -132,57 +154,17 @@ If asked a specific question, address it directly while providing relevant conte
 			return "", fmt.Errorf("vision model error: %w", err)
 		}
 
-		color.HiGreen("Response: %s", response.Content)
+		color.HiGreen("Response: %s", response.Text())
 
 		// Return the response from the vision model
-		return response.Content.(string), nil
+		return response.Text(), nil
 	}
 
 	// Create and return the LLM tool definition
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "analyze_image",
-			Description: "Analyze an image using a vision model and get a detailed description",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"variant": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"file", "raw"},
-						"description": "The type of image input - 'file' for file path or 'raw' for base64 encoded image data",
-					},
-					"target": map[string]interface{}{
-						"type":        "string",
-						"description": "Either a file path (when variant='file') or base64 encoded image data (when variant='raw')",
-					},
-					"query": map[string]interface{}{
-						"type":        "string",
-						"description": "Specific question or instruction about the image (e.g., 'What's in this image?', 'Describe the objects in detail')",
-					},
-					"temperature": map[string]interface{}{
-						"type":        "number",
-						"description": "Temperature for the vision model (between 0.0 and 1.0). Higher values make output more random, lower values more deterministic. Default is 0.7 if not specified.",
-					},
-					"max_tokens": map[string]interface{}{
-						"type":        "integer",
-						"description": "Maximum number of tokens to generate. Default is 2000 if not specified.",
-					},
-				},
-				"required": []string{"variant", "target", "query"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				Variant     string  `json:"variant"`
-				Target      string  `json:"target"`
-				Query       string  `json:"query"`
-				Temperature float64 `json:"temperature,omitempty"`
-				MaxTokens   int     `json:"max_tokens,omitempty"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("failed to parse arguments: %w", err)
-			}
-
+	return toolkit.NewTypedTool(
+		"analyze_image",
+		"Analyze an image using a vision model and get a detailed description",
+		func(args analyzeImageArgs) (interface{}, error) {
 			// Convert variant string to TargetVariant type
 			variant := TargetVariant(args.Variant)
 			if variant != File && variant != Raw {
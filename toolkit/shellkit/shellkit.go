@@ -3,7 +3,6 @@ package shellkit
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
@@ -107,46 +106,19 @@ func GetShellKit(logger *slog.Logger, callback toolkit.KitCallback, projectBound
 		WithCallback(callback)
 }
 
+type executeCommandArgs struct {
+	Command        string            `json:"command"`
+	WorkingDir     string            `json:"working_dir,omitempty" description:"Working directory for the command (optional, must be within project bounds)"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty" description:"Command timeout in seconds. Default: 30, max: 300"`
+	EnvVars        map[string]string `json:"env_vars,omitempty" description:"Additional environment variables as key-value pairs"`
+}
+
 // getExecuteCommandTool creates a tool for executing shell commands
 func getExecuteCommandTool(logger *slog.Logger, platform PlatformInfo, projectBounds []beau.ProjectBounds) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "execute_command",
-			Description: fmt.Sprintf("Execute a shell command on %s using %s. Commands run with a timeout for safety.", platform.OS, platform.Shell),
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"command": map[string]interface{}{
-						"type":        "string",
-						"description": fmt.Sprintf("The command to execute. Use %s syntax.", platform.ShellType),
-					},
-					"working_dir": map[string]interface{}{
-						"type":        "string",
-						"description": "Working directory for the command (optional, must be within project bounds)",
-					},
-					"timeout_seconds": map[string]interface{}{
-						"type":        "integer",
-						"description": "Command timeout in seconds. Default: 30, max: 300",
-					},
-					"env_vars": map[string]interface{}{
-						"type":        "object",
-						"description": "Additional environment variables as key-value pairs",
-					},
-				},
-				"required": []string{"command"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				Command        string            `json:"command"`
-				WorkingDir     string            `json:"working_dir"`
-				TimeoutSeconds int               `json:"timeout_seconds"`
-				EnvVars        map[string]string `json:"env_vars"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
-
+	return toolkit.NewTypedTool(
+		"execute_command",
+		fmt.Sprintf("Execute a shell command on %s using %s. Commands run with a timeout for safety.", platform.OS, platform.Shell),
+		func(args executeCommandArgs) (interface{}, error) {
 			// Set defaults
 			if args.TimeoutSeconds <= 0 {
 				args.TimeoutSeconds = 30
@@ -225,33 +197,19 @@ func getExecuteCommandTool(logger *slog.Logger, platform PlatformInfo, projectBo
 
 			return result, nil
 		},
-	)
+	).WithPropertyDescription("command", fmt.Sprintf("The command to execute. Use %s syntax.", platform.ShellType))
+}
+
+type listProcessesArgs struct {
+	Filter string `json:"filter,omitempty" description:"Filter processes by name (case-insensitive)"`
 }
 
 // getListProcessesTool creates a tool for listing running processes
 func getListProcessesTool(logger *slog.Logger, platform PlatformInfo) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "list_processes",
-			Description: "List running processes with their PIDs and names",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"filter": map[string]interface{}{
-						"type":        "string",
-						"description": "Filter processes by name (case-insensitive)",
-					},
-				},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				Filter string `json:"filter"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
-
+	return toolkit.NewTypedTool(
+		"list_processes",
+		"List running processes with their PIDs and names",
+		func(args listProcessesArgs) (interface{}, error) {
 			var cmd *exec.Cmd
 			if platform.IsWindows {
 				// Windows: use tasklist
@@ -288,34 +246,16 @@ func getListProcessesTool(logger *slog.Logger, platform PlatformInfo) toolkit.Ll
 	)
 }
 
-func getEnvironmentTool(logger *slog.Logger, platform PlatformInfo) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "get_environment",
-			Description: "Get environment variables, optionally filtered by pattern",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"pattern": map[string]interface{}{
-						"type":        "string",
-						"description": "Filter environment variables by pattern (e.g., 'PATH', 'HOME')",
-					},
-					"show_all": map[string]interface{}{
-						"type":        "boolean",
-						"description": "Show all environment variables. Default: false (shows common ones)",
-					},
-				},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				Pattern string `json:"pattern"`
-				ShowAll bool   `json:"show_all"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type environmentArgs struct {
+	Pattern string `json:"pattern,omitempty" description:"Filter environment variables by pattern (e.g., 'PATH', 'HOME')"`
+	ShowAll bool   `json:"show_all,omitempty" description:"Show all environment variables. Default: false (shows common ones)"`
+}
 
+func getEnvironmentTool(logger *slog.Logger, platform PlatformInfo) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"get_environment",
+		"Get environment variables, optionally filtered by pattern",
+		func(args environmentArgs) (interface{}, error) {
 			envVars := make(map[string]string)
 
 			// Common environment variables to show by default
@@ -357,6 +297,10 @@ func getEnvironmentTool(logger *slog.Logger, platform PlatformInfo) toolkit.LlmT
 					}
 				}
 
+				// the model never needs the value of a credential, only that it is set
+				if beau.IsSensitiveEnvName(key) {
+					value = "[REDACTED]"
+				}
 				envVars[key] = value
 			}
 
@@ -365,29 +309,15 @@ func getEnvironmentTool(logger *slog.Logger, platform PlatformInfo) toolkit.LlmT
 	)
 }
 
-func getWorkingDirectoryTool(logger *slog.Logger, platform PlatformInfo) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "get_working_directory",
-			Description: "Get the current working directory and list its contents",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"list_contents": map[string]interface{}{
-						"type":        "boolean",
-						"description": "List directory contents. Default: true",
-					},
-				},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				ListContents *bool `json:"list_contents"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type workingDirectoryArgs struct {
+	ListContents *bool `json:"list_contents,omitempty" description:"List directory contents. Default: true"`
+}
 
+func getWorkingDirectoryTool(logger *slog.Logger, platform PlatformInfo) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"get_working_directory",
+		"Get the current working directory and list its contents",
+		func(args workingDirectoryArgs) (interface{}, error) {
 			listContents := true
 			if args.ListContents != nil {
 				listContents = *args.ListContents
@@ -425,16 +355,10 @@ func getWorkingDirectoryTool(logger *slog.Logger, platform PlatformInfo) toolkit
 }
 
 func getSystemInfoTool(logger *slog.Logger, platform PlatformInfo) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "get_system_info",
-			Description: "Get detailed system and platform information",
-			Parameters: map[string]interface{}{
-				"type":       "object",
-				"properties": map[string]interface{}{},
-			},
-		},
-		func(input []byte) (interface{}, error) {
+	return toolkit.NewTypedTool(
+		"get_system_info",
+		"Get detailed system and platform information",
+		func(args struct{}) (interface{}, error) {
 			// Get additional runtime info
 			info := map[string]interface{}{
 				"platform":      platform,
@@ -456,40 +380,17 @@ func getSystemInfoTool(logger *slog.Logger, platform PlatformInfo) toolkit.LlmTo
 	)
 }
 
-func getScriptTool(logger *slog.Logger, platform PlatformInfo, projectBounds []beau.ProjectBounds) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "create_script",
-			Description: fmt.Sprintf("Create an executable shell script for %s", platform.OS),
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"file_path": map[string]interface{}{
-						"type":        "string",
-						"description": "Absolute path where to save the script (must be within project bounds)",
-					},
-					"content": map[string]interface{}{
-						"type":        "string",
-						"description": fmt.Sprintf("Script content in %s syntax", platform.ShellType),
-					},
-					"description": map[string]interface{}{
-						"type":        "string",
-						"description": "Description comment to add at the top of the script",
-					},
-				},
-				"required": []string{"file_path", "content"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				FilePath    string `json:"file_path"`
-				Content     string `json:"content"`
-				Description string `json:"description"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type scriptArgs struct {
+	FilePath    string `json:"file_path" description:"Absolute path where to save the script (must be within project bounds)"`
+	Content     string `json:"content"`
+	Description string `json:"description,omitempty" description:"Description comment to add at the top of the script"`
+}
 
+func getScriptTool(logger *slog.Logger, platform PlatformInfo, projectBounds []beau.ProjectBounds) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"create_script",
+		fmt.Sprintf("Create an executable shell script for %s", platform.OS),
+		func(args scriptArgs) (interface{}, error) {
 			// Validate path is within bounds
 			if len(projectBounds) > 0 {
 				validPath := false
@@ -561,5 +462,5 @@ func getScriptTool(logger *slog.Logger, platform PlatformInfo, projectBounds []b
 				"message":  fmt.Sprintf("Script created successfully at %s", args.FilePath),
 			}, nil
 		},
-	)
+	).WithPropertyDescription("content", fmt.Sprintf("Script content in %s syntax", platform.ShellType))
 }
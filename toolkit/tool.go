@@ -1,17 +1,33 @@
 package toolkit
 
-import "github.com/bosley/beau"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/bosley/beau"
+)
 
 type tooling struct {
 	toolDefinition beau.Tool
-	executor       func(input []byte) (interface{}, error)
+	executor       func(ctx context.Context, input []byte) (interface{}, error)
 }
 
-var _ LlmTool = &tooling{}
+var _ ContextTool = &tooling{}
 
 func NewTool(
 	schema beau.ToolSchema,
 	executor func(input []byte) (interface{}, error)) *tooling {
+	return NewContextTool(schema, func(_ context.Context, input []byte) (interface{}, error) {
+		return executor(input)
+	})
+}
+
+// NewContextTool builds a tool whose executor receives the context of the call
+func NewContextTool(
+	schema beau.ToolSchema,
+	executor func(ctx context.Context, input []byte) (interface{}, error)) *tooling {
 	return &tooling{
 		toolDefinition: beau.Tool{
 			Type:     "function",
@@ -21,10 +37,67 @@ func NewTool(
 	}
 }
 
+// NewTypedTool builds a tool whose parameter schema is derived from Args with
+// beau.SchemaFor. Arguments are validated against that schema and decoded into
+// Args before the executor is called, so the schema and the struct cannot drift.
+func NewTypedTool[Args any](
+	name string,
+	description string,
+	executor func(args Args) (interface{}, error)) *tooling {
+	return NewTypedContextTool(name, description, func(_ context.Context, args Args) (interface{}, error) {
+		return executor(args)
+	})
+}
+
+// NewTypedContextTool is NewTypedTool for executors that take the context of
+// the call
+func NewTypedContextTool[Args any](
+	name string,
+	description string,
+	executor func(ctx context.Context, args Args) (interface{}, error)) *tooling {
+	parameters := beau.SchemaForType(reflect.TypeOf((*Args)(nil)).Elem())
+
+	return NewContextTool(
+		beau.ToolSchema{
+			Name:        name,
+			Description: description,
+			Parameters:  parameters,
+		},
+		func(ctx context.Context, input []byte) (interface{}, error) {
+			// some models send no arguments at all for tools without parameters
+			if len(input) == 0 {
+				input = []byte("{}")
+			}
+			if err := beau.ValidateJSON(parameters, input); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			var args Args
+			if err := json.Unmarshal(input, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			return executor(ctx, args)
+		},
+	)
+}
+
+// WithPropertyDescription sets the description of a parameter, for descriptions
+// that are only known at runtime and so cannot live in a struct tag
+func (t *tooling) WithPropertyDescription(property string, description string) *tooling {
+	properties, _ := t.toolDefinition.Function.Parameters["properties"].(map[string]interface{})
+	if prop, ok := properties[property].(map[string]interface{}); ok {
+		prop["description"] = description
+	}
+	return t
+}
+
 func (t *tooling) GetDefinition() beau.Tool {
 	return t.toolDefinition
 }
 
 func (t *tooling) Call(input []byte) (interface{}, error) {
-	return t.executor(input)
+	return t.executor(context.Background(), input)
+}
+
+func (t *tooling) CallContext(ctx context.Context, input []byte) (interface{}, error) {
+	return t.executor(ctx, input)
 }
@@ -0,0 +1,172 @@
+package toolkit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bosley/beau"
+	"github.com/bosley/beau/tracing"
+)
+
+type testToolArgs struct {
+	Path  string   `json:"path" description:"Path to operate on"`
+	Mode  string   `json:"mode,omitempty" enum:"fast,slow"`
+	Depth int      `json:"depth,omitempty" minimum:"1" maximum:"5"`
+	Tags  []string `json:"tags,omitempty" enum:"a,b"`
+	Force *bool    `json:"force" required:"true"`
+}
+
+func TestNewTypedToolSchema(t *testing.T) {
+	tool := NewTypedTool("test_tool", "A test tool", func(args testToolArgs) (interface{}, error) {
+		return args, nil
+	}).WithPropertyDescription("mode", "How to operate")
+
+	definition := tool.GetDefinition()
+	if definition.Type != "function" || definition.Function.Name != "test_tool" {
+		t.Fatalf("Unexpected definition: %+v", definition)
+	}
+
+	parameters := definition.Function.Parameters
+	required := parameters["required"].([]string)
+	if !reflect.DeepEqual(required, []string{"force", "path"}) {
+		t.Errorf("Expected required [force path], got %v", required)
+	}
+
+	properties := parameters["properties"].(map[string]interface{})
+	path := properties["path"].(map[string]interface{})
+	if path["description"] != "Path to operate on" {
+		t.Errorf("Expected description from tag, got %v", path["description"])
+	}
+	mode := properties["mode"].(map[string]interface{})
+	if mode["description"] != "How to operate" {
+		t.Errorf("Expected overridden description, got %v", mode["description"])
+	}
+	if !reflect.DeepEqual(mode["enum"], []interface{}{"fast", "slow"}) {
+		t.Errorf("Expected enum [fast slow], got %v", mode["enum"])
+	}
+	depth := properties["depth"].(map[string]interface{})
+	if depth["minimum"] != 1.0 || depth["maximum"] != 5.0 {
+		t.Errorf("Expected bounds 1..5, got %v..%v", depth["minimum"], depth["maximum"])
+	}
+	items := properties["tags"].(map[string]interface{})["items"].(map[string]interface{})
+	if !reflect.DeepEqual(items["enum"], []interface{}{"a", "b"}) {
+		t.Errorf("Expected item enum [a b], got %v", items["enum"])
+	}
+}
+
+func TestNewTypedToolCall(t *testing.T) {
+	tool := NewTypedTool("test_tool", "A test tool", func(args testToolArgs) (interface{}, error) {
+		return args, nil
+	})
+
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+		expected    string
+	}{
+		{name: "Valid arguments", input: `{"path":"/tmp","mode":"fast","depth":2,"force":true}`, expected: "/tmp"},
+		{name: "Null optional argument", input: `{"path":"/tmp","mode":null,"force":false}`, expected: "/tmp"},
+		{name: "Missing required argument", input: `{"force":true}`, expectError: true},
+		{name: "Value outside enum", input: `{"path":"/tmp","mode":"medium","force":true}`, expectError: true},
+		{name: "Value above maximum", input: `{"path":"/tmp","depth":9,"force":true}`, expectError: true},
+		{name: "Wrong type", input: `{"path":3,"force":true}`, expectError: true},
+		{name: "Malformed JSON", input: `{"path":`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tool.Call([]byte(tt.input))
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if args := result.(testToolArgs); args.Path != tt.expected {
+				t.Errorf("Expected path %s, got %s", tt.expected, args.Path)
+			}
+		})
+	}
+}
+
+func TestHandleResponseCallsTracesTools(t *testing.T) {
+	exporter := tracing.NewMemoryExporter()
+	ctx, parent := tracing.NewTracer(exporter).Start(context.Background(), "agent.turn")
+
+	var seen *tracing.Span
+	kit := NewKit("test").
+		WithTool(NewTypedContextTool("inspect", "Records the calling span", func(ctx context.Context, args struct{}) (interface{}, error) {
+			seen = tracing.SpanFromContext(ctx)
+			return "ok", nil
+		})).
+		WithTool(NewTool(beau.ToolSchema{Name: "fail"}, func(input []byte) (interface{}, error) {
+			return nil, fmt.Errorf("boom")
+		})).
+		WithCallback(func(isError bool, id string, result interface{}) {})
+
+	err := kit.HandleResponseCallsContext(ctx, &beau.Message{ToolCalls: []beau.ToolCall{
+		{ID: "call-1", Function: beau.ToolFunction{Name: "inspect", Arguments: "{}"}},
+		{ID: "call-2", Function: beau.ToolFunction{Name: "fail", Arguments: "{}"}},
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	parent.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	inspect, fail := spans[0], spans[1]
+	if inspect.ParentID != spans[2].SpanID || fail.ParentID != spans[2].SpanID {
+		t.Errorf("Expected both calls under the parent span, got %+v", spans)
+	}
+	if inspect.Attributes["tool"] != "inspect" || inspect.Attributes["call_id"] != "call-1" || inspect.Error != "" {
+		t.Errorf("Unexpected span: %+v", inspect)
+	}
+	if fail.Error != "boom" {
+		t.Errorf("Expected the failure recorded, got %+v", fail)
+	}
+	if seen == nil || seen.TraceID() != parent.TraceID() {
+		t.Errorf("Expected the tool to run under its call span")
+	}
+}
+
+func TestHandleResponseCallsRedactsResults(t *testing.T) {
+	redactor := beau.NewRedactor().AddSecret("API_KEY", "key-from-env")
+
+	results := map[string]interface{}{}
+	kit := NewKit("test").
+		WithTool(NewTool(beau.ToolSchema{Name: "env"}, func(input []byte) (interface{}, error) {
+			return map[string]interface{}{"stdout": "API_KEY=key-from-env", "exit_code": 0}, nil
+		})).
+		WithTool(NewTool(beau.ToolSchema{Name: "fail"}, func(input []byte) (interface{}, error) {
+			return nil, fmt.Errorf("rejected key-from-env")
+		})).
+		WithCallback(func(isError bool, id string, result interface{}) {
+			results[id] = result
+		}).
+		WithRedactor(redactor)
+
+	kit.HandleResponseCalls(&beau.Message{ToolCalls: []beau.ToolCall{
+		{ID: "env", Function: beau.ToolFunction{Name: "env"}},
+		{ID: "fail", Function: beau.ToolFunction{Name: "fail"}},
+	}})
+
+	output, ok := results["env"].(map[string]interface{})
+	if !ok || output["stdout"] != "API_KEY=[REDACTED:API_KEY]" || output["exit_code"] != 0 {
+		t.Errorf("Expected the map redacted in place, got %#v", results["env"])
+	}
+	if err, ok := results["fail"].(error); !ok || err.Error() != "rejected [REDACTED:API_KEY]" {
+		t.Errorf("Expected the error redacted, got %v", results["fail"])
+	}
+	if redactor.Total() != 1 {
+		t.Errorf("Expected the secret counted once, got %v", redactor.Counts())
+	}
+}
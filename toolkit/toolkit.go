@@ -1,9 +1,11 @@
 package toolkit
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/bosley/beau"
+	"github.com/bosley/beau/tracing"
 
 	"github.com/fatih/color"
 )
@@ -16,12 +18,26 @@ type LlmTool interface {
 	// the decoding/ etc as required.
 	// Should usually return a string but can return anything that can be encoded
 	// to json.
-	// LIMITATION: This interface doesn't support context cancellation. Tools
-	// cannot be cancelled when the user clicks stop. This needs architectural
-	// change to add context.Context parameter.
+	// Tools that need cancellation or tracing implement ContextTool as well.
 	Call(input []byte) (interface{}, error)
 }
 
+// ContextTool is a tool that takes the context of the tool call, so it can be
+// cancelled and its work shows up under the call's span
+type ContextTool interface {
+	LlmTool
+
+	CallContext(ctx context.Context, input []byte) (interface{}, error)
+}
+
+// CallTool calls tool with ctx when it accepts one
+func CallTool(ctx context.Context, tool LlmTool, input []byte) (interface{}, error) {
+	if contextTool, ok := tool.(ContextTool); ok {
+		return contextTool.CallContext(ctx, input)
+	}
+	return tool.Call(input)
+}
+
 // Called if a tool is executed. It will execute each tool present in the given
 // hjands back the id that was called with result
 // if isError defined, then the result should be considered an error type
@@ -35,6 +51,9 @@ type LlmToolKit struct {
 	ironedTools []beau.Tool
 
 	callback KitCallback
+
+	// scrubs tool results before the callback sees them, may be nil
+	redactor *beau.Redactor
 }
 
 func NewKit(name string) *LlmToolKit {
@@ -56,6 +75,12 @@ func (x *LlmToolKit) WithCallback(callback KitCallback) *LlmToolKit {
 	return x
 }
 
+// WithRedactor removes secrets from tool results before they reach the callback
+func (x *LlmToolKit) WithRedactor(redactor *beau.Redactor) *LlmToolKit {
+	x.redactor = redactor
+	return x
+}
+
 func (x *LlmToolKit) GetTools() []beau.Tool {
 	if len(x.ironedTools) > 0 {
 		return x.ironedTools
@@ -67,6 +92,12 @@ func (x *LlmToolKit) GetTools() []beau.Tool {
 }
 
 func (x *LlmToolKit) HandleResponseCalls(response *beau.Message) error {
+	return x.HandleResponseCallsContext(context.Background(), response)
+}
+
+// HandleResponseCallsContext executes the tool calls in response, each in a
+// "tool.call" span under the span in ctx
+func (x *LlmToolKit) HandleResponseCallsContext(ctx context.Context, response *beau.Message) error {
 	if x.callback == nil {
 		return fmt.Errorf("no callback set")
 	}
@@ -76,8 +107,8 @@ func (x *LlmToolKit) HandleResponseCalls(response *beau.Message) error {
 			if tool.GetDefinition().Function.Name == toolCall.Function.Name {
 				toolFound = true
 				color.HiYellow("Executing tool: %s", toolCall.Function.Name)
-				color.HiCyan("Args: %s", toolCall.Function.Arguments)
-				result, err := tool.Call([]byte(toolCall.Function.Arguments))
+				color.HiCyan("Args: %s", x.redactor.Redact(toolCall.Function.Arguments))
+				result, err := x.callTool(ctx, tool, toolCall)
 				if err != nil {
 					color.HiRed("Error executing tool %s: %s", toolCall.Function.Name, err)
 					// Pass the error to the callback as an error
@@ -97,3 +128,56 @@ func (x *LlmToolKit) HandleResponseCalls(response *beau.Message) error {
 	}
 	return nil
 }
+
+// callTool runs a single tool call in its own span, redacting what it returns
+func (x *LlmToolKit) callTool(ctx context.Context, tool LlmTool, toolCall beau.ToolCall) (interface{}, error) {
+	ctx, span := tracing.Start(ctx, "tool.call")
+	defer span.End()
+	span.SetAttribute("kit", x.name)
+	span.SetAttribute("tool", toolCall.Function.Name)
+	span.SetAttribute("call_id", toolCall.ID)
+	span.SetAttribute("args_bytes", len(toolCall.Function.Arguments))
+
+	result, err := CallTool(ctx, tool, []byte(toolCall.Function.Arguments))
+	if err != nil {
+		err = x.redactor.RedactError(err)
+		span.RecordError(err)
+		return nil, err
+	}
+	return x.redact(result), nil
+}
+
+// redact removes secrets from a tool result. Maps keep their shape, other
+// results that carry a secret are handed on as the redacted text they print as.
+func (x *LlmToolKit) redact(result interface{}) interface{} {
+	if x.redactor == nil {
+		return result
+	}
+	switch v := result.(type) {
+	case string:
+		return x.redactor.Redact(v)
+	case []byte:
+		return x.redactor.Redact(string(v))
+	case error:
+		return x.redactor.RedactError(v)
+	case map[string]string:
+		redacted := make(map[string]string, len(v))
+		for key, value := range v {
+			redacted[key] = x.redactor.Redact(value)
+		}
+		return redacted
+	case map[string]interface{}:
+		// keep the structure, callers format command results by their fields
+		redacted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			redacted[key] = x.redact(value)
+		}
+		return redacted
+	default:
+		text := fmt.Sprintf("%v", v)
+		if redacted := x.redactor.Redact(text); redacted != text {
+			return redacted
+		}
+		return result
+	}
+}
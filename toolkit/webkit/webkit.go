@@ -114,52 +114,20 @@ func generateScreenshotFilename(url string) string {
 	return fmt.Sprintf("%s_%s.png", cleanURL, timestamp)
 }
 
+type navigateAndScreenshotArgs struct {
+	URL            string `json:"url" description:"The URL to navigate to (e.g., https://example.com)"`
+	ScreenshotType string `json:"screenshot_type,omitempty" description:"Type of screenshot: 'fullpage' captures entire page, 'viewport' captures visible area only. Default: fullpage" enum:"fullpage,viewport"`
+	WaitSeconds    int    `json:"wait_seconds,omitempty" description:"Seconds to wait after page loads before taking screenshot. Default: 2"`
+	ViewportWidth  int    `json:"viewport_width,omitempty" description:"Browser viewport width in pixels. Default: 1920"`
+	ViewportHeight int    `json:"viewport_height,omitempty" description:"Browser viewport height in pixels. Default: 1080"`
+}
+
 // getNavigateAndScreenshotTool provides a combined navigation and screenshot tool
 func getNavigateAndScreenshotTool(logger *slog.Logger, projectBounds []beau.ProjectBounds) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "navigate_and_screenshot",
-			Description: "Navigate to a URL and capture a screenshot. Saves to .web/screenshots/ with timestamp.",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"url": map[string]interface{}{
-						"type":        "string",
-						"description": "The URL to navigate to (e.g., https://example.com)",
-					},
-					"screenshot_type": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"fullpage", "viewport"},
-						"description": "Type of screenshot: 'fullpage' captures entire page, 'viewport' captures visible area only. Default: fullpage",
-					},
-					"wait_seconds": map[string]interface{}{
-						"type":        "integer",
-						"description": "Seconds to wait after page loads before taking screenshot. Default: 2",
-					},
-					"viewport_width": map[string]interface{}{
-						"type":        "integer",
-						"description": "Browser viewport width in pixels. Default: 1920",
-					},
-					"viewport_height": map[string]interface{}{
-						"type":        "integer",
-						"description": "Browser viewport height in pixels. Default: 1080",
-					},
-				},
-				"required": []string{"url"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				URL            string `json:"url"`
-				ScreenshotType string `json:"screenshot_type"`
-				WaitSeconds    int    `json:"wait_seconds"`
-				ViewportWidth  int    `json:"viewport_width"`
-				ViewportHeight int    `json:"viewport_height"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
-
+	return toolkit.NewTypedTool(
+		"navigate_and_screenshot",
+		"Navigate to a URL and capture a screenshot. Saves to .web/screenshots/ with timestamp.",
+		func(args navigateAndScreenshotArgs) (interface{}, error) {
 			// Set defaults
 			if args.ScreenshotType == "" {
 				args.ScreenshotType = string(ScreenshotFullPage)
@@ -259,30 +227,15 @@ func getNavigateAndScreenshotTool(logger *slog.Logger, projectBounds []beau.Proj
 // Additional tools would be implemented here...
 // For brevity, I'll add just the navigate tool as an example
 
-func getNavigateTool(logger *slog.Logger) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "navigate_to_url",
-			Description: "Navigate browser to a specific URL without taking a screenshot",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"url": map[string]interface{}{
-						"type":        "string",
-						"description": "The URL to navigate to",
-					},
-				},
-				"required": []string{"url"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				URL string `json:"url"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type navigateToUrlArgs struct {
+	URL string `json:"url" description:"The URL to navigate to"`
+}
 
+func getNavigateTool(logger *slog.Logger) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"navigate_to_url",
+		"Navigate browser to a specific URL without taking a screenshot",
+		func(args navigateToUrlArgs) (interface{}, error) {
 			// This is a simplified implementation
 			// In a real scenario, you might want to maintain a browser session
 			return map[string]interface{}{
@@ -293,36 +246,17 @@ func getNavigateTool(logger *slog.Logger) toolkit.LlmTool {
 	)
 }
 
+type takeScreenshotArgs struct {
+	ScreenshotType string `json:"screenshot_type,omitempty" description:"Type of screenshot to capture. Default: viewport" enum:"fullpage,viewport,element"`
+	Selector       string `json:"selector,omitempty" description:"CSS selector for element screenshot (only used if screenshot_type is 'element')"`
+}
+
 // getScreenshotTool captures a screenshot of the current page (requires browser session management)
 func getScreenshotTool(logger *slog.Logger, projectBounds []beau.ProjectBounds) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "take_screenshot",
-			Description: "Take a screenshot of the current page in an existing browser session",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"screenshot_type": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"fullpage", "viewport", "element"},
-						"description": "Type of screenshot to capture. Default: viewport",
-					},
-					"selector": map[string]interface{}{
-						"type":        "string",
-						"description": "CSS selector for element screenshot (only used if screenshot_type is 'element')",
-					},
-				},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				ScreenshotType string `json:"screenshot_type"`
-				Selector       string `json:"selector"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
-
+	return toolkit.NewTypedTool(
+		"take_screenshot",
+		"Take a screenshot of the current page in an existing browser session",
+		func(args takeScreenshotArgs) (interface{}, error) {
 			// Note: This would require maintaining browser session state
 			// For now, return a message indicating the limitation
 			return map[string]interface{}{
@@ -333,35 +267,16 @@ func getScreenshotTool(logger *slog.Logger, projectBounds []beau.ProjectBounds)
 	)
 }
 
-func getClickElementTool(logger *slog.Logger) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "click_element",
-			Description: "Click an element on the page by CSS selector",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"selector": map[string]interface{}{
-						"type":        "string",
-						"description": "CSS selector of the element to click (e.g., 'button.submit', '#login-btn', 'a[href=\"/about\"]')",
-					},
-					"wait_visible": map[string]interface{}{
-						"type":        "boolean",
-						"description": "Wait for element to be visible before clicking. Default: true",
-					},
-				},
-				"required": []string{"selector"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				Selector    string `json:"selector"`
-				WaitVisible *bool  `json:"wait_visible"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type clickElementArgs struct {
+	Selector    string `json:"selector" description:"CSS selector of the element to click (e.g., 'button.submit', '#login-btn', 'a[href=\"/about\"]')"`
+	WaitVisible *bool  `json:"wait_visible,omitempty" description:"Wait for element to be visible before clicking. Default: true"`
+}
 
+func getClickElementTool(logger *slog.Logger) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"click_element",
+		"Click an element on the page by CSS selector",
+		func(args clickElementArgs) (interface{}, error) {
 			waitVisible := true
 			if args.WaitVisible != nil {
 				waitVisible = *args.WaitVisible
@@ -375,40 +290,17 @@ func getClickElementTool(logger *slog.Logger) toolkit.LlmTool {
 	)
 }
 
-func getFillFormTool(logger *slog.Logger) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "fill_form",
-			Description: "Fill a form field with text by CSS selector",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"selector": map[string]interface{}{
-						"type":        "string",
-						"description": "CSS selector of the input field (e.g., 'input[name=\"username\"]', '#email', '.search-box')",
-					},
-					"text": map[string]interface{}{
-						"type":        "string",
-						"description": "Text to enter into the field",
-					},
-					"clear_first": map[string]interface{}{
-						"type":        "boolean",
-						"description": "Clear existing text before typing. Default: true",
-					},
-				},
-				"required": []string{"selector", "text"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				Selector   string `json:"selector"`
-				Text       string `json:"text"`
-				ClearFirst *bool  `json:"clear_first"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type fillFormArgs struct {
+	Selector   string `json:"selector" description:"CSS selector of the input field (e.g., 'input[name=\"username\"]', '#email', '.search-box')"`
+	Text       string `json:"text" description:"Text to enter into the field"`
+	ClearFirst *bool  `json:"clear_first,omitempty" description:"Clear existing text before typing. Default: true"`
+}
 
+func getFillFormTool(logger *slog.Logger) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"fill_form",
+		"Fill a form field with text by CSS selector",
+		func(args fillFormArgs) (interface{}, error) {
 			clearFirst := true
 			if args.ClearFirst != nil {
 				clearFirst = *args.ClearFirst
@@ -423,30 +315,15 @@ func getFillFormTool(logger *slog.Logger) toolkit.LlmTool {
 	)
 }
 
-func getExecuteJSTool(logger *slog.Logger) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "execute_javascript",
-			Description: "Execute JavaScript code on the current page",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"script": map[string]interface{}{
-						"type":        "string",
-						"description": "JavaScript code to execute. Can return a value.",
-					},
-				},
-				"required": []string{"script"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				Script string `json:"script"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type executeJavascriptArgs struct {
+	Script string `json:"script" description:"JavaScript code to execute. Can return a value."`
+}
 
+func getExecuteJSTool(logger *slog.Logger) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"execute_javascript",
+		"Execute JavaScript code on the current page",
+		func(args executeJavascriptArgs) (interface{}, error) {
 			return map[string]interface{}{
 				"success": false,
 				"message": "JavaScript execution requires browser session management.",
@@ -456,35 +333,16 @@ func getExecuteJSTool(logger *slog.Logger) toolkit.LlmTool {
 	)
 }
 
-func getWaitForElementTool(logger *slog.Logger) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "wait_for_element",
-			Description: "Wait for an element to appear on the page",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"selector": map[string]interface{}{
-						"type":        "string",
-						"description": "CSS selector of the element to wait for",
-					},
-					"timeout_seconds": map[string]interface{}{
-						"type":        "integer",
-						"description": "Maximum time to wait in seconds. Default: 10",
-					},
-				},
-				"required": []string{"selector"},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				Selector       string `json:"selector"`
-				TimeoutSeconds int    `json:"timeout_seconds"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type waitForElementArgs struct {
+	Selector       string `json:"selector" description:"CSS selector of the element to wait for"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty" description:"Maximum time to wait in seconds. Default: 10"`
+}
 
+func getWaitForElementTool(logger *slog.Logger) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"wait_for_element",
+		"Wait for an element to appear on the page",
+		func(args waitForElementArgs) (interface{}, error) {
 			if args.TimeoutSeconds <= 0 {
 				args.TimeoutSeconds = 10
 			}
@@ -498,29 +356,15 @@ func getWaitForElementTool(logger *slog.Logger) toolkit.LlmTool {
 	)
 }
 
-func getGetPageInfoTool(logger *slog.Logger) toolkit.LlmTool {
-	return toolkit.NewTool(
-		beau.ToolSchema{
-			Name:        "get_page_info",
-			Description: "Get information about the current page (title, URL, meta tags)",
-			Parameters: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"include_meta": map[string]interface{}{
-						"type":        "boolean",
-						"description": "Include meta tags in the response. Default: false",
-					},
-				},
-			},
-		},
-		func(input []byte) (interface{}, error) {
-			var args struct {
-				IncludeMeta bool `json:"include_meta"`
-			}
-			if err := json.Unmarshal(input, &args); err != nil {
-				return nil, fmt.Errorf("invalid arguments: %w", err)
-			}
+type getPageInfoArgs struct {
+	IncludeMeta bool `json:"include_meta,omitempty" description:"Include meta tags in the response. Default: false"`
+}
 
+func getGetPageInfoTool(logger *slog.Logger) toolkit.LlmTool {
+	return toolkit.NewTypedTool(
+		"get_page_info",
+		"Get information about the current page (title, URL, meta tags)",
+		func(args getPageInfoArgs) (interface{}, error) {
 			return map[string]interface{}{
 				"success": false,
 				"message": "Page info requires browser session management. Use navigate_and_screenshot to capture pages.",
@@ -0,0 +1,250 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrExport = fmt.Errorf("failed to export spans")
+)
+
+// JSONLExporter appends every span to a file as one JSON object per line. It
+// works offline, and a session's spans can be turned into a timeline from the
+// start, end and parent of each line.
+type JSONLExporter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+var _ Exporter = &JSONLExporter{}
+
+// NewJSONLExporter opens path for appending, creating it if needed
+func NewJSONLExporter(path string) (*JSONLExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExport, err)
+	}
+	return &JSONLExporter{file: file}, nil
+}
+
+func (e *JSONLExporter) ExportSpan(span SpanData) error {
+	line, err := json.Marshal(span)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrExport, err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", ErrExport, err)
+	}
+	return nil
+}
+
+func (e *JSONLExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
+
+// MemoryExporter keeps spans in memory, for tests and in-process inspection
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+var _ Exporter = &MemoryExporter{}
+
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+func (e *MemoryExporter) ExportSpan(span SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+func (e *MemoryExporter) Close() error {
+	return nil
+}
+
+// Spans returns the exported spans in the order they ended
+func (e *MemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData{}, e.spans...)
+}
+
+// DefaultOTLPBatchSize is the number of spans an OTLPExporter sends at once
+const DefaultOTLPBatchSize = 64
+
+// OTLPExporter sends spans to an OpenTelemetry collector over OTLP/HTTP with
+// JSON encoding, batching them until BatchSize spans are buffered or Close is
+// called
+type OTLPExporter struct {
+	Endpoint    string // collector url, e.g. http://localhost:4318/v1/traces
+	ServiceName string
+	Headers     map[string]string
+	HTTPClient  *http.Client
+	BatchSize   int
+
+	mu      sync.Mutex
+	pending []SpanData
+}
+
+var _ Exporter = &OTLPExporter{}
+
+func NewOTLPExporter(endpoint string, serviceName string) *OTLPExporter {
+	return &OTLPExporter{
+		Endpoint:    endpoint,
+		ServiceName: serviceName,
+		HTTPClient:  &http.Client{Timeout: 10 * time.Second},
+		BatchSize:   DefaultOTLPBatchSize,
+	}
+}
+
+func (e *OTLPExporter) ExportSpan(span SpanData) error {
+	e.mu.Lock()
+	e.pending = append(e.pending, span)
+	if len(e.pending) < max(1, e.BatchSize) {
+		e.mu.Unlock()
+		return nil
+	}
+	batch := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	return e.send(context.Background(), batch)
+}
+
+// Flush sends the buffered spans
+func (e *OTLPExporter) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	batch := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return e.send(ctx, batch)
+}
+
+func (e *OTLPExporter) Close() error {
+	return e.Flush(context.Background())
+}
+
+func (e *OTLPExporter) send(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(OTLPRequest(e.ServiceName, spans))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrExport, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrExport, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := e.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrExport, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%w: collector returned %d: %s", ErrExport, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// OTLPRequest converts spans to the OTLP/JSON ExportTraceServiceRequest
+// document, for exporters that deliver it some other way
+func OTLPRequest(serviceName string, spans []SpanData) map[string]interface{} {
+	otlpSpans := make([]map[string]interface{}, 0, len(spans))
+	for _, span := range spans {
+		otlpSpan := map[string]interface{}{
+			"traceId":           span.TraceID,
+			"spanId":            span.SpanID,
+			"name":              span.Name,
+			"kind":              1, // internal
+			"startTimeUnixNano": strconv.FormatInt(span.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.End.UnixNano(), 10),
+			"attributes":        otlpAttributes(span.Attributes),
+			"status":            map[string]interface{}{"code": 1}, // ok
+		}
+		if span.ParentID != "" {
+			otlpSpan["parentSpanId"] = span.ParentID
+		}
+		if span.Error != "" {
+			otlpSpan["status"] = map[string]interface{}{"code": 2, "message": span.Error}
+		}
+		otlpSpans = append(otlpSpans, otlpSpan)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]interface{}{"service.name": serviceName}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "github.com/bosley/beau/tracing"},
+						"spans": otlpSpans,
+					},
+				},
+			},
+		},
+	}
+}
+
+// otlpAttributes converts attributes to OTLP key values, sorted by key
+func otlpAttributes(attributes map[string]interface{}) []interface{} {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		var value map[string]interface{}
+		switch v := attributes[key].(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		out = append(out, map[string]interface{}{"key": key, "value": value})
+	}
+	return out
+}
@@ -0,0 +1,192 @@
+// Package tracing records spans for agent turns, conversation requests, mage
+// executions and tool calls. Spans find their parent through the context, so a
+// trace follows the work as it is handed from the agent to mages and tools.
+// Finished spans go to an Exporter, such as a JSONL file or an OTLP collector.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SpanData is a finished span as handed to exporters
+type SpanData struct {
+	TraceID    string                 `json:"trace_id"`
+	SpanID     string                 `json:"span_id"`
+	ParentID   string                 `json:"parent_id,omitempty"`
+	Name       string                 `json:"name"`
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	DurationMS float64                `json:"duration_ms"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// Exporter receives spans as they end
+type Exporter interface {
+	// ExportSpan hands over a finished span
+	ExportSpan(span SpanData) error
+
+	// Close flushes anything buffered and releases the exporter
+	Close() error
+}
+
+// Tracer starts spans and sends them to its exporter when they end
+type Tracer struct {
+	exporter Exporter
+
+	// OnError is called when the exporter fails, spans are dropped otherwise
+	OnError func(err error)
+}
+
+// NewTracer creates a tracer exporting to exporter
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Close closes the exporter
+func (t *Tracer) Close() error {
+	return t.exporter.Close()
+}
+
+// Start begins a span as a child of the span in ctx, or as the root of a new
+// trace when there is none, and returns a context carrying it
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			SpanID: newID(8),
+			Name:   name,
+			Start:  time.Now(),
+		},
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.data.TraceID = parent.data.TraceID
+		span.data.ParentID = parent.data.SpanID
+	} else {
+		span.data.TraceID = newID(16)
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+type tracerKey struct{}
+type spanKey struct{}
+
+// ContextWithTracer returns a context whose spans are recorded by t
+func ContextWithTracer(ctx context.Context, t *Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, t)
+}
+
+// ContextWithSpan returns a context carrying span, so spans started from it
+// become its children. Use it to continue a trace on another goroutine.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the current span, nil when there is none
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// TracerFromContext returns the tracer of the current span or the one set with
+// ContextWithTracer, nil when tracing is off
+func TracerFromContext(ctx context.Context) *Tracer {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.tracer
+	}
+	tracer, _ := ctx.Value(tracerKey{}).(*Tracer)
+	return tracer
+}
+
+// Start begins a span with the tracer found in ctx. Without one, tracing is
+// off: ctx is returned as is along with a nil span, which is safe to use.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	tracer := TracerFromContext(ctx)
+	if tracer == nil {
+		return ctx, nil
+	}
+	return tracer.Start(ctx, name)
+}
+
+// Span is an operation in progress. All methods do nothing on a nil span.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SetAttribute records a key value pair on the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = map[string]interface{}{}
+	}
+	s.data.Attributes[key] = value
+}
+
+// RecordError marks the span as failed, nil errors are ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = err.Error()
+}
+
+// TraceID identifies the trace the span belongs to
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.data.TraceID
+}
+
+// End finishes the span and exports it. Only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	s.data.DurationMS = float64(s.data.End.Sub(s.data.Start)) / float64(time.Millisecond)
+	data := s.data
+	// exporters may hold on to the data, so it must not share the live map
+	if s.data.Attributes != nil {
+		data.Attributes = make(map[string]interface{}, len(s.data.Attributes))
+		for key, value := range s.data.Attributes {
+			data.Attributes[key] = value
+		}
+	}
+	s.mu.Unlock()
+
+	if err := s.tracer.exporter.ExportSpan(data); err != nil && s.tracer.OnError != nil {
+		s.tracer.OnError(fmt.Errorf("failed to export span %s: %w", data.Name, err))
+	}
+}
+
+// newID returns n random bytes hex encoded, the id format OTLP uses
+func newID(n int) string {
+	id := make([]byte, n)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
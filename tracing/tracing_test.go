@@ -0,0 +1,175 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSpanParentage(t *testing.T) {
+	exporter := NewMemoryExporter()
+	ctx := ContextWithTracer(context.Background(), NewTracer(exporter))
+
+	ctx, root := Start(ctx, "root")
+	childCtx, child := Start(ctx, "child")
+	_, grandchild := Start(childCtx, "grandchild")
+	grandchild.SetAttribute("tool", "read_file")
+	grandchild.RecordError(fmt.Errorf("no such file"))
+	grandchild.End()
+	child.End()
+	child.End()
+	root.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected each span exported once, got %d", len(spans))
+	}
+	g, c, r := spans[0], spans[1], spans[2]
+
+	if r.ParentID != "" || c.ParentID != r.SpanID || g.ParentID != c.SpanID {
+		t.Errorf("Unexpected parentage: root %q, child %q of %q, grandchild %q of %q", r.SpanID, c.SpanID, c.ParentID, g.SpanID, g.ParentID)
+	}
+	if len(r.TraceID) != 32 || len(r.SpanID) != 16 {
+		t.Errorf("Expected OTLP sized ids, got trace %q span %q", r.TraceID, r.SpanID)
+	}
+	for _, span := range spans {
+		if span.TraceID != r.TraceID {
+			t.Errorf("Expected %s in trace %s, got %s", span.Name, r.TraceID, span.TraceID)
+		}
+		if span.End.Before(span.Start) {
+			t.Errorf("Expected %s to end after it started", span.Name)
+		}
+	}
+	if g.Attributes["tool"] != "read_file" || g.Error != "no such file" {
+		t.Errorf("Unexpected grandchild: %+v", g)
+	}
+
+	// exported data does not change with the span
+	grandchild.SetAttribute("tool", "write_file")
+	if g.Attributes["tool"] != "read_file" {
+		t.Errorf("Expected exported attributes to be a copy, got %v", g.Attributes)
+	}
+}
+
+func TestStartWithoutTracer(t *testing.T) {
+	ctx := context.Background()
+	spanCtx, span := Start(ctx, "untraced")
+	if span != nil || spanCtx != ctx {
+		t.Fatalf("Expected tracing to be off without a tracer")
+	}
+
+	// a nil span is safe to use
+	span.SetAttribute("key", "value")
+	span.RecordError(fmt.Errorf("ignored"))
+	span.End()
+}
+
+func TestJSONLExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	exporter, err := NewJSONLExporter(path)
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+
+	tracer := NewTracer(exporter)
+	ctx, root := tracer.Start(context.Background(), "agent.turn")
+	_, child := Start(ctx, "conversation.send")
+	child.SetAttribute("model", "grok-4")
+	child.End()
+	root.End()
+	if err := tracer.Close(); err != nil {
+		t.Fatalf("Failed to close exporter: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open trace: %v", err)
+	}
+	defer file.Close()
+
+	var spans []SpanData
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var span SpanData
+		if err := json.Unmarshal(scanner.Bytes(), &span); err != nil {
+			t.Fatalf("Failed to decode line %q: %v", scanner.Text(), err)
+		}
+		spans = append(spans, span)
+	}
+
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(spans))
+	}
+	if spans[0].Name != "conversation.send" || spans[0].ParentID != spans[1].SpanID || spans[0].Attributes["model"] != "grok-4" {
+		t.Errorf("Unexpected spans: %+v", spans)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var requests []map[string]interface{}
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(server.URL+"/v1/traces", "beau-test")
+	exporter.Headers = map[string]string{"Authorization": "Bearer token"}
+	exporter.BatchSize = 2
+
+	tracer := NewTracer(exporter)
+	ctx, root := tracer.Start(context.Background(), "mage.execute")
+	_, child := Start(ctx, "tool.call")
+	child.SetAttribute("tool", "list_directory")
+	child.SetAttribute("args_bytes", 12)
+	child.RecordError(fmt.Errorf("denied"))
+	child.End()
+	if len(requests) != 0 {
+		t.Fatalf("Expected spans to be batched, got %d requests", len(requests))
+	}
+	root.End()
+	_, extra := tracer.Start(context.Background(), "agent.turn")
+	extra.End()
+	if err := tracer.Close(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	if len(requests) != 2 || auth != "Bearer token" {
+		t.Fatalf("Expected a full batch and a flush, got %d requests (auth %q)", len(requests), auth)
+	}
+
+	resource := requests[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	service := resource["resource"].(map[string]interface{})["attributes"].([]interface{})[0].(map[string]interface{})
+	if service["key"] != "service.name" || service["value"].(map[string]interface{})["stringValue"] != "beau-test" {
+		t.Errorf("Unexpected resource: %v", service)
+	}
+
+	spans := resource["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans in the batch, got %d", len(spans))
+	}
+	toolSpan, rootSpan := spans[0].(map[string]interface{}), spans[1].(map[string]interface{})
+	if toolSpan["parentSpanId"] != rootSpan["spanId"] || toolSpan["traceId"] != rootSpan["traceId"] {
+		t.Errorf("Expected the tool call under the mage, got %v", toolSpan)
+	}
+	if _, ok := rootSpan["parentSpanId"]; ok {
+		t.Errorf("Expected no parent on the root span")
+	}
+	if status := toolSpan["status"].(map[string]interface{}); status["code"] != 2.0 || status["message"] != "denied" {
+		t.Errorf("Expected an error status, got %v", status)
+	}
+	attributes := toolSpan["attributes"].([]interface{})
+	args := attributes[0].(map[string]interface{})
+	if args["key"] != "args_bytes" || args["value"].(map[string]interface{})["intValue"] != "12" {
+		t.Errorf("Unexpected attribute: %v", args)
+	}
+}
@@ -8,6 +8,8 @@ import (
 )
 
 // RateLimitError represents a 429 rate limit error with retry information
+//
+// Deprecated: rate limits are reported as an *APIError with Kind APIErrorRateLimit
 type RateLimitError struct {
 	StatusCode   int
 	RetryAfter   time.Duration
@@ -18,13 +20,16 @@ func (e *RateLimitError) Error() string {
 	return fmt.Sprintf("rate limit exceeded (429): retry after %v", e.RetryAfter)
 }
 
-// RetryConfig defines the retry behavior for rate-limited requests
+// RetryConfig defines the retry behavior for failed requests
 type RetryConfig struct {
 	MaxRetries    int
 	InitialDelay  time.Duration
 	MaxDelay      time.Duration
 	BackoffFactor float64
-	Enabled       bool
+	Enabled       bool // retry on retriable status codes, transport errors are always retried
+
+	Jitter     float64         // fraction of each delay that is randomized, 0 disables
+	Classifier RetryClassifier // if nil, DefaultRetryClassifier
 }
 
 // DefaultRetryConfig returns the default retry configuration
@@ -35,6 +40,7 @@ func DefaultRetryConfig() RetryConfig {
 		MaxDelay:      DefaultMaxDelay,
 		BackoffFactor: DefaultBackoffFactor,
 		Enabled:       true,
+		Jitter:        DefaultJitter,
 	}
 }
 
@@ -44,6 +50,15 @@ type Client struct {
 	HTTPClient  *http.Client
 	Logger      *slog.Logger
 	RetryConfig RetryConfig
+	Provider    Provider
+	RateLimiter *RateLimiter   // optional, shared between clients on the same key
+	Cache       Cache          // optional, serves repeated requests without calling the provider
+	Fallback    FallbackPolicy // optional, targets tried in turn when the provider fails
+	Pricing     *Pricing       // prices requests for the meter and budget, DefaultPricing if nil
+	Meter       *CostMeter     // accumulates the usage and cost of every request
+	Budget      *Budget        // optional, refuses requests once spent
+	Middleware  []Middleware   // wraps every request, see Use
+	Redactor    *Redactor      // optional, removes secrets from outbound messages and logs
 }
 
 // MessageRole defines the role of a message in a conversation
@@ -75,6 +90,10 @@ type ContentItem struct {
 	ToolResult *ToolResult `json:"tool_result,omitempty"`
 }
 
+// MessageContent is the content of a message as a list of parts. It encodes to a
+// plain string when it is a single text part and always decodes back to parts.
+type MessageContent []ContentItem
+
 // ImageURL represents an image URL content item
 type ImageURL struct {
 	URL    string `json:"url"`
@@ -83,11 +102,15 @@ type ImageURL struct {
 
 // Message represents a message in a conversation
 type Message struct {
-	Role       MessageRole `json:"role"`
-	Content    interface{} `json:"content"`
-	Name       string      `json:"name,omitempty"`
-	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
-	ToolCallID string      `json:"tool_call_id,omitempty"`
+	Role       MessageRole    `json:"role"`
+	Content    MessageContent `json:"content"`
+	Name       string         `json:"name,omitempty"`
+	ToolCalls  []ToolCall     `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+
+	// IsError marks the result of a failed tool call. Only providers with a
+	// native error flag send it, the others see the text alone.
+	IsError bool `json:"is_error,omitempty"`
 }
 
 // ToolCall represents a tool call from the model
@@ -107,6 +130,7 @@ type ToolFunction struct {
 type ToolResult struct {
 	ToolCallID string `json:"tool_call_id"`
 	Content    string `json:"content"`
+	IsError    bool   `json:"is_error,omitempty"`
 }
 
 // StreamConfig represents streaming configuration
@@ -124,16 +148,62 @@ type StreamChunk struct {
 
 // ChatCompletionRequest represents a request to the chat completions API
 type ChatCompletionRequest struct {
-	Model        string        `json:"model"`
-	Messages     []Message     `json:"messages"`
-	MaxTokens    int           `json:"max_tokens,omitempty"`
-	Temperature  float64       `json:"temperature,omitempty"`
-	Stream       bool          `json:"stream,omitempty"`
-	Tools        []Tool        `json:"tools,omitempty"`
-	ToolChoice   interface{}   `json:"tool_choice,omitempty"`
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	Temperature    float64         `json:"temperature,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+	StreamOptions  *StreamOptions  `json:"stream_options,omitempty"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	ToolChoice     interface{}     `json:"tool_choice,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	SamplingParams
 	streamConfig *StreamConfig `json:"-"` // Internal use only
 }
 
+// SamplingParams are the optional controls over how replies are generated.
+// Providers drop the ones their backend does not support.
+type SamplingParams struct {
+	TopP              *float64 `json:"top_p,omitempty"`
+	Stop              []string `json:"stop,omitempty"`
+	Seed              *int64   `json:"seed,omitempty"`
+	N                 int      `json:"n,omitempty"` // number of choices to generate
+	PresencePenalty   *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty  *float64 `json:"frequency_penalty,omitempty"`
+	Logprobs          bool     `json:"logprobs,omitempty"`
+	TopLogprobs       int      `json:"top_logprobs,omitempty"`
+	ParallelToolCalls *bool    `json:"parallel_tool_calls,omitempty"`
+}
+
+// StreamOptions controls what is sent on a streamed response
+type StreamOptions struct {
+	// IncludeUsage asks for a final chunk carrying the token usage of the request
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ResponseFormatType selects how the model formats its reply
+type ResponseFormatType string
+
+const (
+	ResponseFormatText       ResponseFormatType = "text"
+	ResponseFormatJSONObject ResponseFormatType = "json_object"
+	ResponseFormatJSONSchema ResponseFormatType = "json_schema"
+)
+
+// ResponseFormat constrains the reply to JSON, optionally matching a schema
+type ResponseFormat struct {
+	Type       ResponseFormatType `json:"type"`
+	JSONSchema *JSONSchemaFormat  `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaFormat is the schema a json_schema response must match
+type JSONSchemaFormat struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+	Strict      bool                   `json:"strict,omitempty"`
+}
+
 // Tool represents a tool that the model can use
 type Tool struct {
 	Type     string     `json:"type"`
@@ -155,26 +225,80 @@ type Usage struct {
 	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
 }
 
+// Add returns the sum of two usages, used to total a turn that spans several requests
+func (u Usage) Add(other Usage) Usage {
+	sum := Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+	if u.PromptTokensDetails != nil || other.PromptTokensDetails != nil {
+		sum.PromptTokensDetails = &PromptTokensDetails{}
+		for _, details := range []*PromptTokensDetails{u.PromptTokensDetails, other.PromptTokensDetails} {
+			if details != nil {
+				sum.PromptTokensDetails.ImageTokens += details.ImageTokens
+				sum.PromptTokensDetails.CachedTokens += details.CachedTokens
+			}
+		}
+	}
+	return sum
+}
+
 // PromptTokensDetails contains detailed token usage information
 type PromptTokensDetails struct {
-	ImageTokens int `json:"image_tokens"`
+	ImageTokens  int `json:"image_tokens"`
+	CachedTokens int `json:"cached_tokens,omitempty"` // prompt tokens served from the provider's prompt cache
+}
+
+// CachedTokens returns the prompt tokens served from the provider's prompt cache
+func (u Usage) CachedTokens() int {
+	if u.PromptTokensDetails == nil {
+		return 0
+	}
+	return u.PromptTokensDetails.CachedTokens
 }
 
 // ChatCompletionResponse represents a response from the chat completions API
 type ChatCompletionResponse struct {
-	ID      string   `json:"id"`
-	Object  string   `json:"object"`
-	Created int64    `json:"created"`
-	Model   string   `json:"model"`
-	Choices []Choice `json:"choices"`
-	Usage   Usage    `json:"usage"`
+	ID                string   `json:"id"`
+	Object            string   `json:"object"`
+	Created           int64    `json:"created"`
+	Model             string   `json:"model"`
+	SystemFingerprint string   `json:"system_fingerprint,omitempty"` // identifies the backend configuration, for reproducibility
+	Choices           []Choice `json:"choices"`
+	Usage             Usage    `json:"usage"`
+
+	// Target is the provider and model that answered, see FallbackPolicy
+	Target *ResponseTarget `json:"-"`
 }
 
 // Choice represents a completion choice
 type Choice struct {
-	Index        int     `json:"index"`
-	Message      Message `json:"message"`
-	FinishReason string  `json:"finish_reason"`
+	Index        int       `json:"index"`
+	Message      Message   `json:"message"`
+	FinishReason string    `json:"finish_reason"`
+	Logprobs     *Logprobs `json:"logprobs,omitempty"`
+}
+
+// Logprobs holds the log probabilities of the tokens of a choice
+type Logprobs struct {
+	Content []TokenLogprob `json:"content"`
+}
+
+// TokenLogprob is the log probability of one generated token and, when
+// requested, of the most likely alternatives
+type TokenLogprob struct {
+	Token       string       `json:"token"`
+	Logprob     float64      `json:"logprob"`
+	Bytes       []int        `json:"bytes,omitempty"`
+	TopLogprobs []TopLogprob `json:"top_logprobs,omitempty"`
+}
+
+// TopLogprob is one alternative token at a position
+type TopLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes,omitempty"`
 }
 
 // ProjectBounds defines the allowed directories for file operations
@@ -0,0 +1,43 @@
+package beau
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// XAIProvider speaks to the xAI API, which follows the OpenAI chat completions format
+type XAIProvider struct {
+	OpenAIProvider
+}
+
+var _ Provider = &XAIProvider{}
+
+func NewXAIProvider() *XAIProvider {
+	return &XAIProvider{
+		OpenAIProvider: *NewOpenAIProvider(),
+	}
+}
+
+func (p *XAIProvider) Name() string {
+	return "xai"
+}
+
+// NewRequest drops the parameters xAI reasoning models reject
+func (p *XAIProvider) NewRequest(ctx context.Context, baseURL string, apiKey string, req ChatCompletionRequest) (*http.Request, error) {
+	if xaiReasoningModel(req.Model) {
+		req.PresencePenalty = nil
+		req.FrequencyPenalty = nil
+		req.Stop = nil
+	}
+	return p.OpenAIProvider.NewRequest(ctx, baseURL, apiKey, req)
+}
+
+// xaiReasoningModel reports whether a model reasons before answering. Only the
+// grok-2 and full grok-3 models do not.
+func xaiReasoningModel(model string) bool {
+	if strings.HasPrefix(model, "grok-2") {
+		return false
+	}
+	return !strings.HasPrefix(model, "grok-3") || strings.HasPrefix(model, "grok-3-mini")
+}